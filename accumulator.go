@@ -0,0 +1,166 @@
+package ar
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// LSARXAccumulator fits an LSARX model on data streamed in chunks, keeping the normal equations
+// phi' phi th = phi' Y of the least-squares fit instead of the phi matrix: its memory grows with
+// the square of the number of coefficients, not with the data.
+//
+// Accumulators of consecutive chunks of a series can be filled independently, on several
+// goroutines or machines, and merged in series order; the exported fields can be encoded, e.g.
+// with encoding/json, to send them. The merged accumulator fits the same model as Fit on the
+// whole series, up to rounding.
+type LSARXAccumulator struct {
+	Params          LSARXModelParameters // Model parameters, with the recursive strategy.
+	NumObservations int                  // Number of data rows added.
+	NumRows         int                  // Number of regression rows accumulated, after the initial lags.
+	PhiTPhi         []float64            // Normal matrix phi' phi, row-major.
+	PhiTY           []float64            // Moment vector phi' Y.
+	YTY             float64              // Sum of the squared regression targets.
+	SumY            float64              // Sum of the regression targets.
+	Head            [][]float64          // First data rows, up to the largest lag: the rows whose lags precede the chunk.
+	Tail            [][]float64          // Last data rows, up to the largest lag: the lags of the next chunk.
+
+	scratch []float64 // Lagged data values, time values and regression row of accumulate.
+}
+
+// NewLSARXAccumulator creates an empty accumulator for the LSARX model with the given parameters.
+// Only the recursive strategy is supported, and the latest regime cannot be selected on a stream.
+func NewLSARXAccumulator(params LSARXModelParameters) (*LSARXAccumulator, error) {
+	if _, err := NewLSARXPredictor(nil, params); err != nil {
+		return nil, err
+	}
+	if params.Strategy != RecursiveStrategy {
+		return nil, fmt.Errorf("accumulator supports the recursive strategy only, strategy: %s", params.Strategy)
+	}
+	if params.LatestRegime != nil {
+		return nil, fmt.Errorf("accumulator does not support latest regime selection")
+	}
+	k := params.structure().numParams()
+	return &LSARXAccumulator{Params: params, PhiTPhi: make([]float64, k*k), PhiTY: make([]float64, k)}, nil
+}
+
+// Add accumulates the data rows of [data_value, time_value], which continue the rows added before.
+func (a *LSARXAccumulator) Add(data [][]float64) error {
+	if err := checkColumns(data, 2, "data"); err != nil {
+		return err
+	}
+	for _, row := range data {
+		a.push(row)
+	}
+	return nil
+}
+
+// push accumulates one data row: its regression row, if the accumulator holds all of its lags,
+// and the row itself as a lag of the next rows.
+func (a *LSARXAccumulator) push(row []float64) {
+	s := a.Params.structure()
+	m := s.maxLag()
+	if a.NumObservations >= m {
+		a.accumulate(s, row)
+	}
+	row = []float64{row[0], row[1]}
+	if len(a.Head) < m {
+		a.Head = append(a.Head, row)
+	}
+	switch {
+	case len(a.Tail) < m:
+		a.Tail = append(a.Tail, row)
+	case m > 0:
+		copy(a.Tail, a.Tail[1:])
+		a.Tail[m-1] = row
+	}
+	a.NumObservations++
+}
+
+// accumulate adds the regression row of the data row, with the lags held in the tail, to the normal equations.
+func (a *LSARXAccumulator) accumulate(s arxStructure, row []float64) {
+	m, k := s.maxLag(), s.numParams()
+	a.scratch = growFloats(a.scratch, 2*(m+1)+k)
+	dataValues, timeValues, phi := a.scratch[:m+1], a.scratch[m+1:2*(m+1)], a.scratch[2*(m+1):]
+	for i, r := range a.Tail {
+		dataValues[i], timeValues[i] = r[0], r[1]
+	}
+	dataValues[m], timeValues[m] = row[0], row[1]
+
+	fillPhiMatrix(phi, dataValues, timeValues, s, 1, m)
+	y := row[0]
+	for i, xi := range phi {
+		for j, xj := range phi {
+			a.PhiTPhi[i*k+j] += xi * xj
+		}
+		a.PhiTY[i] += xi * y
+	}
+	a.YTY += y * y
+	a.SumY += y
+	a.NumRows++
+}
+
+// Merge adds the accumulator b of the data rows that continue the rows of a, as if they had been
+// added to a. The rows of b whose lags precede b are accumulated from the tail of a.
+func (a *LSARXAccumulator) Merge(b *LSARXAccumulator) error {
+	if !a.Params.structure().sameColumns(b.Params.structure()) {
+		return fmt.Errorf("cannot merge accumulators of different models, %+v and %+v", a.Params, b.Params)
+	}
+	k := a.Params.structure().numParams()
+	if len(b.PhiTPhi) != k*k || len(b.PhiTY) != k || len(b.Head) > b.NumObservations || len(b.Tail) > b.NumObservations {
+		return fmt.Errorf("accumulator is inconsistent with its model")
+	}
+
+	count := a.NumObservations + b.NumObservations
+	for _, row := range b.Head {
+		a.push(row)
+	}
+	if b.NumObservations > len(b.Head) {
+		for i := range a.PhiTPhi {
+			a.PhiTPhi[i] += b.PhiTPhi[i]
+		}
+		for i := range a.PhiTY {
+			a.PhiTY[i] += b.PhiTY[i]
+		}
+		a.YTY += b.YTY
+		a.SumY += b.SumY
+		a.NumRows += b.NumRows
+		a.Tail = copyRows(b.Tail)
+	}
+	a.NumObservations = count
+	return nil
+}
+
+// Fit solves the accumulated normal equations and returns the fitted model, like LSARXPredictor.Fit
+// on the accumulated data.
+func (a *LSARXAccumulator) Fit() (*LSARXModel, error) {
+	s := a.Params.structure()
+	k := s.numParams()
+	if a.NumObservations <= s.maxLag() {
+		return nil, fmt.Errorf("not enough data points for prediction, need at least %d points", s.maxLag()+1)
+	}
+
+	phiTphi := mat.NewDense(k, k, a.PhiTPhi)
+	var inv mat.Dense
+	if err := inv.Inverse(phiTphi); err != nil {
+		return nil, fmt.Errorf("failed to calculate theta: %v", err)
+	}
+	var th mat.Dense
+	th.Mul(&inv, mat.NewDense(k, 1, a.PhiTY))
+	theta := mat.Col(nil, 0, &th)
+
+	// The sum of squared residuals |Y - phi th|^2 expands to Y'Y - 2 th' phi'Y + th' phi'phi th.
+	n := a.NumRows
+	md := TrainingMetadata{NumObservations: a.NumObservations, TrainedAt: time.Now().UTC(), NumResiduals: n}
+	thv := mat.NewVecDense(k, theta)
+	md.SSE = math.Max(0, a.YTY-2*mat.Dot(thv, mat.NewVecDense(k, a.PhiTY))+mat.Inner(thv, phiTphi, thv))
+	md.TSS = math.Max(0, a.YTY-a.SumY*a.SumY/float64(n))
+	if n-k > 0 {
+		md.Sigma2 = md.SSE / float64(n-k)
+		md.setStdErrors(phiTphi)
+	}
+
+	return &LSARXModel{Params: a.Params, Theta: theta, History: copyRows(a.Tail), Metadata: md}, nil
+}
@@ -0,0 +1,160 @@
+package ar
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// checkAccumulatedModel compares a model fitted by an accumulator with the model fitted on the whole data.
+func checkAccumulatedModel(t *testing.T, got *LSARXModel, want *LSARXModel) {
+	t.Helper()
+	for i := range want.Theta {
+		if !approxEqual(got.Theta[i], want.Theta[i], 1e-9) {
+			t.Errorf("Theta = %v, want %v", got.Theta, want.Theta)
+			break
+		}
+	}
+	for i := range want.Metadata.StdErrors {
+		if !approxEqual(got.Metadata.StdErrors[i], want.Metadata.StdErrors[i], 1e-6*want.Metadata.StdErrors[i]) {
+			t.Errorf("StdErrors = %v, want %v", got.Metadata.StdErrors, want.Metadata.StdErrors)
+			break
+		}
+	}
+	gm, wm := got.Metadata, want.Metadata
+	if gm.NumObservations != wm.NumObservations || gm.NumResiduals != wm.NumResiduals ||
+		!approxEqual(gm.Sigma2, wm.Sigma2, 1e-6*wm.Sigma2) || !approxEqual(gm.TSS, wm.TSS, 1e-9*wm.TSS) {
+		t.Errorf("Metadata = %+v, want %+v", gm, wm)
+	}
+	if !reflect.DeepEqual(got.History, want.History) {
+		t.Errorf("History = %v, want %v", got.History, want.History)
+	}
+}
+
+func TestLSARXAccumulator(t *testing.T) {
+	data := delayedARXData(5000)
+	params := LSARXModelParameters{AutoregressiveLags: 2, ExternalInputLags: 2, InputDelay: 1, Intercept: true, StepSize: 1}
+	predictor, err := NewLSARXPredictor(data, params)
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	want, err := predictor.Fit(0)
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	acc, err := NewLSARXAccumulator(params)
+	if err != nil {
+		t.Fatalf("NewLSARXAccumulator() error = %v", err)
+	}
+	for start := 0; start < len(data); start += 37 {
+		if err := acc.Add(data[start:min(start+37, len(data))]); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	got, err := acc.Fit()
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+	checkAccumulatedModel(t, got, want)
+
+	forecast, err := got.Predict(5)
+	if err != nil {
+		t.Fatalf("Predict() error = %v", err)
+	}
+	wantForecast, err := want.Predict(5)
+	if err != nil {
+		t.Fatalf("Predict() error = %v", err)
+	}
+	for i := range wantForecast {
+		if !approxEqual(forecast[i][1], wantForecast[i][1], 1e-9) {
+			t.Errorf("Predict() = %v, want %v", forecast, wantForecast)
+			break
+		}
+	}
+}
+
+func TestLSARXAccumulatorMerge(t *testing.T) {
+	data := delayedARXData(3000)
+	params := LSARXModelParameters{AutoregressiveLags: 3, ExternalInputLags: 2, Intercept: true, StepSize: 1}
+	predictor, err := NewLSARXPredictor(data, params)
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	want, err := predictor.Fit(0)
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	// Chunks shorter than the largest lag continue the lags of the chunk before them.
+	bounds := []int{0, 1, 2, 1000, 1002, 2500, 3000}
+	chunks := make([]*LSARXAccumulator, len(bounds)-1)
+	var wg sync.WaitGroup
+	for i := range chunks {
+		acc, err := NewLSARXAccumulator(params)
+		if err != nil {
+			t.Fatalf("NewLSARXAccumulator() error = %v", err)
+		}
+		chunks[i] = acc
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := acc.Add(data[bounds[i]:bounds[i+1]]); err != nil {
+				t.Errorf("Add() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The accumulators survive an encoding round trip, as when they are sent between machines.
+	merged := chunks[0]
+	for _, chunk := range chunks[1:] {
+		encoded, err := json.Marshal(chunk)
+		if err != nil {
+			t.Fatalf("json.Marshal() error = %v", err)
+		}
+		var decoded LSARXAccumulator
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+		if err := merged.Merge(&decoded); err != nil {
+			t.Fatalf("Merge() error = %v", err)
+		}
+	}
+	got, err := merged.Fit()
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+	checkAccumulatedModel(t, got, want)
+}
+
+func TestLSARXAccumulatorErrors(t *testing.T) {
+	if _, err := NewLSARXAccumulator(LSARXModelParameters{AutoregressiveLags: 1, StepSize: 1, Strategy: DirectStrategy}); err == nil {
+		t.Error("NewLSARXAccumulator() with the direct strategy returned no error")
+	}
+	if _, err := NewLSARXAccumulator(LSARXModelParameters{StepSize: 1}); err == nil {
+		t.Error("NewLSARXAccumulator() without lags returned no error")
+	}
+
+	acc, err := NewLSARXAccumulator(LSARXModelParameters{AutoregressiveLags: 2, StepSize: 1})
+	if err != nil {
+		t.Fatalf("NewLSARXAccumulator() error = %v", err)
+	}
+	if err := acc.Add([][]float64{{1}}); err == nil {
+		t.Error("Add() of a short row returned no error")
+	}
+	if err := acc.Add([][]float64{{1, 2}, {3, 4}}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if _, err := acc.Fit(); err == nil {
+		t.Error("Fit() on too little data returned no error")
+	}
+	other, err := NewLSARXAccumulator(LSARXModelParameters{AutoregressiveLags: 1, StepSize: 1})
+	if err != nil {
+		t.Fatalf("NewLSARXAccumulator() error = %v", err)
+	}
+	if err := acc.Merge(other); err == nil {
+		t.Error("Merge() of a different model returned no error")
+	}
+}
@@ -0,0 +1,226 @@
+package ar
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// AnomalyKind is the check of an AnomalyDetector that flagged an anomaly.
+type AnomalyKind int
+
+const (
+	// IntervalAnomaly is a value outside the prediction interval of its one-step-ahead prediction.
+	IntervalAnomaly AnomalyKind = iota
+	// RobustZAnomaly is a residual far from the recent residuals, by their median and median absolute deviation.
+	RobustZAnomaly
+	// CUSUMAnomaly is a CUSUM alarm: the residuals have drifted from zero, as after a change point.
+	CUSUMAnomaly
+)
+
+// String returns the name of the anomaly kind.
+func (k AnomalyKind) String() string {
+	switch k {
+	case IntervalAnomaly:
+		return "interval"
+	case RobustZAnomaly:
+		return "robust-z"
+	case CUSUMAnomaly:
+		return "cusum"
+	default:
+		return fmt.Sprintf("AnomalyKind(%d)", int(k))
+	}
+}
+
+// defaultRobustWindow is the number of recent residuals of the robust z-score when
+// AnomalyDetectorParameters.RobustWindow is zero.
+const defaultRobustWindow = 50
+
+// minRobustResiduals is the number of recent residuals the robust z-score needs before it flags anomalies.
+const minRobustResiduals = 10
+
+// AnomalyDetectorParameters holds the configuration of an AnomalyDetector. Every check is enabled
+// by a positive threshold, and at least one must be.
+type AnomalyDetectorParameters struct {
+	IntervalLevel    float64 // Confidence level of the prediction intervals, e.g. 0.99; 0 disables the check.
+	RobustZThreshold float64 // Robust z-score beyond which a residual is anomalous, e.g. 3.5; 0 disables the check.
+	RobustWindow     int     // Number of recent residuals the robust z-score is computed from, 50 when zero.
+	CUSUMThreshold   float64 // Decision threshold h of the CUSUM, in residual standard deviations, e.g. 5; 0 disables the check.
+	CUSUMDrift       float64 // Allowance k of the CUSUM, in residual standard deviations, e.g. 0.5.
+}
+
+// Anomaly is a value flagged by an AnomalyDetector.
+type Anomaly struct {
+	Index    int         // Position of the value in the data passed to Detect, counted across calls.
+	Time     float64     // Time value of the data row.
+	Expected float64     // One-step-ahead prediction of the value.
+	Observed float64     // Observed value.
+	Kind     AnomalyKind // Check that flagged the value.
+	Severity float64     // Statistic of the check divided by its threshold, above 1 for every anomaly.
+}
+
+// AnomalyDetector compares incoming data with the one-step-ahead predictions of a fitted model and
+// flags the values whose residuals are anomalous. The data passed to successive Detect calls
+// continues the training data of the model and every previous call.
+type AnomalyDetector struct {
+	Params AnomalyDetectorParameters // Detector parameters.
+
+	model     Forecaster  // Fitted *LSModel or *LSARXModel.
+	sigma     float64     // Standard deviation of the one-step-ahead residuals of the model.
+	intervalZ float64     // Normal quantile of the prediction intervals.
+	history   [][]float64 // Last rows of the data, the lags of an LSARX model.
+	recent    []float64   // Recent residuals, for the robust z-score.
+	cusumPos  float64     // Upper CUSUM statistic.
+	cusumNeg  float64     // Lower CUSUM statistic.
+	count     int         // Number of data rows seen.
+}
+
+// NewAnomalyDetector creates a detector on the residuals of a fitted *LSModel or *LSARXModel.
+// The prediction intervals and the CUSUM use the residual variance of the fit.
+func NewAnomalyDetector(model Forecaster, params AnomalyDetectorParameters) (*AnomalyDetector, error) {
+	if params.IntervalLevel < 0 || params.IntervalLevel >= 1 {
+		return nil, fmt.Errorf("interval level must be between 0 and 1, interval level: %v", params.IntervalLevel)
+	}
+	if params.RobustZThreshold < 0 || params.CUSUMThreshold < 0 || params.CUSUMDrift < 0 || params.RobustWindow < 0 {
+		return nil, fmt.Errorf("thresholds must not be negative, robust z: %v, cusum: %v, drift: %v, window: %d", params.RobustZThreshold, params.CUSUMThreshold, params.CUSUMDrift, params.RobustWindow)
+	}
+	if params.IntervalLevel == 0 && params.RobustZThreshold == 0 && params.CUSUMThreshold == 0 {
+		return nil, fmt.Errorf("no anomaly check is enabled")
+	}
+
+	d := &AnomalyDetector{Params: params, model: model}
+	var sigma2 float64
+	switch m := model.(type) {
+	case *LSModel:
+		if k := len(m.Params.basisNames()); len(m.Theta) != k {
+			return nil, fmt.Errorf("model has %d coefficients, expected %d", len(m.Theta), k)
+		}
+		sigma2 = m.Metadata.Sigma2
+	case *LSARXModel:
+		if s := m.Params.structure(); len(m.Theta) != s.numParams() {
+			return nil, fmt.Errorf("model has %d coefficients, expected %d", len(m.Theta), s.numParams())
+		}
+		sigma2 = m.Metadata.Sigma2
+		d.history = copyRows(m.History)
+	default:
+		return nil, fmt.Errorf("unsupported model type for anomaly detection: %T", model)
+	}
+	if (params.IntervalLevel > 0 || params.CUSUMThreshold > 0) && !(sigma2 > 0) {
+		return nil, fmt.Errorf("model has no positive residual variance, sigma2: %v", sigma2)
+	}
+	d.sigma = math.Sqrt(sigma2)
+	if params.IntervalLevel > 0 {
+		d.intervalZ = distuv.UnitNormal.Quantile(1 - (1-params.IntervalLevel)/2)
+	}
+	return d, nil
+}
+
+// expected returns the one-step-ahead predictions of the data rows of [data_value, time_value].
+func (d *AnomalyDetector) expected(data [][]float64) []float64 {
+	switch m := d.model.(type) {
+	case *LSModel:
+		times := make([]float64, len(data))
+		for i, row := range data {
+			times[i] = row[1]
+		}
+		var yAp mat.Dense
+		yAp.Mul(constructBasisMatrix(times, m.Params.Regressors), mat.NewDense(len(m.Theta), 1, m.Theta))
+		return mat.Col(nil, 0, &yAp)
+	case *LSARXModel:
+		s := m.Params.structure()
+		rows := append(copyRows(d.history), data...)
+		y := make([]float64, len(rows))
+		u := make([]float64, len(rows))
+		for i, row := range rows {
+			y[i], u[i] = row[0], row[1]
+		}
+		th := mat.NewDense(len(m.Theta), 1, m.Theta)
+		n := len(d.history)
+		expected := make([]float64, len(data))
+		x := make([]float64, s.nx)
+		for i := range expected {
+			expected[i] = s.predictAt(y, u, th, n+i, 1, x)
+		}
+		d.history = copyRows(rows[len(rows)-s.maxLag():])
+		return expected
+	}
+	return nil
+}
+
+// Detect computes the one-step-ahead residuals of the data rows of [data_value, time_value] and
+// returns the anomalies they contain, in data order. A value flagged by several checks appears
+// once per check. A CUSUM alarm restarts the CUSUM.
+func (d *AnomalyDetector) Detect(data [][]float64) ([]Anomaly, error) {
+	if err := checkColumns(data, 2, "data"); err != nil {
+		return nil, err
+	}
+	window := d.Params.RobustWindow
+	if window == 0 {
+		window = defaultRobustWindow
+	}
+
+	var anomalies []Anomaly
+	for i, exp := range d.expected(data) {
+		obs := data[i][0]
+		r := obs - exp
+		flag := func(kind AnomalyKind, severity float64) {
+			anomalies = append(anomalies, Anomaly{Index: d.count + i, Time: data[i][1], Expected: exp, Observed: obs, Kind: kind, Severity: severity})
+		}
+
+		if d.Params.IntervalLevel > 0 {
+			if z := math.Abs(r) / d.sigma; z > d.intervalZ {
+				flag(IntervalAnomaly, z/d.intervalZ)
+			}
+		}
+
+		if d.Params.RobustZThreshold > 0 && len(d.recent) >= minRobustResiduals {
+			med, mad := medianAbsoluteDeviation(d.recent)
+			// 1.4826 MAD estimates the standard deviation of normal residuals.
+			if mad > 0 {
+				if z := math.Abs(r-med) / (1.4826 * mad); z > d.Params.RobustZThreshold {
+					flag(RobustZAnomaly, z/d.Params.RobustZThreshold)
+				}
+			}
+		}
+		d.recent = append(d.recent, r)
+		if len(d.recent) > window {
+			d.recent = d.recent[len(d.recent)-window:]
+		}
+
+		if h := d.Params.CUSUMThreshold; h > 0 {
+			z := r / d.sigma
+			d.cusumPos = math.Max(0, d.cusumPos+z-d.Params.CUSUMDrift)
+			d.cusumNeg = math.Max(0, d.cusumNeg-z-d.Params.CUSUMDrift)
+			if s := math.Max(d.cusumPos, d.cusumNeg); s > h {
+				flag(CUSUMAnomaly, s/h)
+				d.cusumPos, d.cusumNeg = 0, 0
+			}
+		}
+	}
+	d.count += len(data)
+	return anomalies, nil
+}
+
+// medianAbsoluteDeviation returns the median of the values and their median absolute deviation from it.
+func medianAbsoluteDeviation(values []float64) (median float64, mad float64) {
+	median = medianOf(values)
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - median)
+	}
+	return median, medianOf(deviations)
+}
+
+// medianOf returns the median of the values, leaving them unchanged.
+func medianOf(values []float64) float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
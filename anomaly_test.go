@@ -0,0 +1,192 @@
+package ar
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// anomalyTestData returns training data and incoming data with a spike at row 20 and a level
+// shift from row 60 of the incoming data.
+func anomalyTestData() (train [][]float64, incoming [][]float64) {
+	data := delayedARXData(300)
+	train, incoming = data[:200], copyRows(data[200:])
+	incoming[20][0] += 2
+	for i := 60; i < len(incoming); i++ {
+		incoming[i][0] += 0.1
+	}
+	return train, incoming
+}
+
+func fitAnomalyTestModel(t *testing.T, train [][]float64) *LSARXModel {
+	t.Helper()
+	predictor, err := NewLSARXPredictor(train, LSARXModelParameters{AutoregressiveLags: 1, ExternalInputLags: 1, InputDelay: 2, Intercept: true, StepSize: 1})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	model, err := predictor.Fit(0)
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+	return model
+}
+
+func TestAnomalyDetector(t *testing.T) {
+	train, incoming := anomalyTestData()
+	model := fitAnomalyTestModel(t, train)
+
+	tests := []struct {
+		name   string
+		params AnomalyDetectorParameters
+		want   []int // Indices of the anomalies.
+		kind   AnomalyKind
+	}{
+		{"Interval", AnomalyDetectorParameters{IntervalLevel: 0.9999}, []int{20, 21}, IntervalAnomaly},
+		{"Robust z-score", AnomalyDetectorParameters{RobustZThreshold: 6}, []int{20, 21}, RobustZAnomaly},
+		{"CUSUM", AnomalyDetectorParameters{CUSUMThreshold: 8, CUSUMDrift: 0.5}, nil, CUSUMAnomaly},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detector, err := NewAnomalyDetector(model, tt.params)
+			if err != nil {
+				t.Fatalf("NewAnomalyDetector() error = %v", err)
+			}
+			anomalies, err := detector.Detect(incoming)
+			if err != nil {
+				t.Fatalf("Detect() error = %v", err)
+			}
+			if tt.kind == CUSUMAnomaly {
+				// The spike raises an alarm at once; the small level shift only drifts the residuals,
+				// so the CUSUM raises alarms some steps after it.
+				var beforeShift, afterShift int
+				for _, a := range anomalies {
+					switch {
+					case a.Index > 21 && a.Index < 60:
+						t.Errorf("CUSUM alarm at %d, want none between the spike and the shift", a.Index)
+					case a.Index >= 60:
+						afterShift++
+					default:
+						beforeShift++
+					}
+				}
+				if beforeShift == 0 || afterShift == 0 || anomalies[0].Index != 20 {
+					t.Errorf("CUSUM anomalies = %+v, want an alarm at the spike at 20 and after the shift at 60", anomalies)
+				}
+			} else {
+				var got []int
+				for _, a := range anomalies {
+					got = append(got, a.Index)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("anomaly indices = %v, want %v", got, tt.want)
+				}
+			}
+			for _, a := range anomalies {
+				if a.Kind != tt.kind || !(a.Severity > 1) || a.Time != incoming[a.Index][1] || a.Observed != incoming[a.Index][0] {
+					t.Errorf("anomaly = %+v, want kind %v, severity above 1 and the observed row", a, tt.kind)
+				}
+			}
+		})
+	}
+
+	// The spike is 40 residual standard deviations above its expected value.
+	detector, err := NewAnomalyDetector(model, AnomalyDetectorParameters{IntervalLevel: 0.99})
+	if err != nil {
+		t.Fatalf("NewAnomalyDetector() error = %v", err)
+	}
+	anomalies, err := detector.Detect(incoming[:21])
+	if err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+	spike := anomalies[len(anomalies)-1]
+	if spike.Index != 20 || !approxEqual(spike.Observed-spike.Expected, 2, 0.2) {
+		t.Errorf("spike anomaly = %+v, want index 20 and a residual of about 2", spike)
+	}
+}
+
+func TestAnomalyDetectorBatches(t *testing.T) {
+	train, incoming := anomalyTestData()
+	model := fitAnomalyTestModel(t, train)
+	params := AnomalyDetectorParameters{IntervalLevel: 0.999, RobustZThreshold: 5, CUSUMThreshold: 8, CUSUMDrift: 0.5}
+
+	whole, err := NewAnomalyDetector(model, params)
+	if err != nil {
+		t.Fatalf("NewAnomalyDetector() error = %v", err)
+	}
+	want, err := whole.Detect(incoming)
+	if err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+
+	batched, err := NewAnomalyDetector(model, params)
+	if err != nil {
+		t.Fatalf("NewAnomalyDetector() error = %v", err)
+	}
+	var got []Anomaly
+	for start := 0; start < len(incoming); start += 7 {
+		a, err := batched.Detect(incoming[start:min(start+7, len(incoming))])
+		if err != nil {
+			t.Fatalf("Detect() error = %v", err)
+		}
+		got = append(got, a...)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("batched anomalies = %+v, want %+v", got, want)
+	}
+}
+
+func TestAnomalyDetectorLSModel(t *testing.T) {
+	data := make([][]float64, 40)
+	for i := range data {
+		x := float64(i)
+		data[i] = []float64{0.5*x*x - 2*x + 3 + math.Cos(x) + 0.1*math.Sin(7*x), x}
+	}
+	predictor, err := NewLSPredictor(data[:30], LSModelParameters{StepSize: 1})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	model, err := predictor.Fit()
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	incoming := copyRows(data[30:])
+	incoming[4][0] -= 5
+	detector, err := NewAnomalyDetector(model, AnomalyDetectorParameters{IntervalLevel: 0.999})
+	if err != nil {
+		t.Fatalf("NewAnomalyDetector() error = %v", err)
+	}
+	anomalies, err := detector.Detect(incoming)
+	if err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+	if len(anomalies) != 1 || anomalies[0].Index != 4 || anomalies[0].Observed > anomalies[0].Expected {
+		t.Errorf("anomalies = %+v, want the drop at index 4", anomalies)
+	}
+}
+
+func TestNewAnomalyDetector(t *testing.T) {
+	model := &LSARXModel{Params: LSARXModelParameters{AutoregressiveLags: 1}, Theta: []float64{-0.5, 1}, History: [][]float64{{1, 1}}, Metadata: TrainingMetadata{Sigma2: 1}}
+	tests := []struct {
+		name   string
+		model  Forecaster
+		params AnomalyDetectorParameters
+	}{
+		{"No check", model, AnomalyDetectorParameters{}},
+		{"Interval level of 1", model, AnomalyDetectorParameters{IntervalLevel: 1}},
+		{"Negative threshold", model, AnomalyDetectorParameters{CUSUMThreshold: -1}},
+		{"Unsupported model", &ARMAModel{}, AnomalyDetectorParameters{RobustZThreshold: 3}},
+		{"No residual variance", &LSARXModel{Params: model.Params, Theta: model.Theta}, AnomalyDetectorParameters{IntervalLevel: 0.99}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewAnomalyDetector(tt.model, tt.params); err == nil {
+				t.Error("NewAnomalyDetector() returned no error")
+			}
+		})
+	}
+
+	if got := AnomalyKind(2).String(); got != "cusum" {
+		t.Errorf("String() = %q, want %q", got, "cusum")
+	}
+}
@@ -0,0 +1,336 @@
+package ar
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// LikelihoodMethod selects the likelihood maximized when fitting a model.
+type LikelihoodMethod int
+
+const (
+	// ExactLikelihood is the exact Gaussian likelihood, evaluated by the Kalman filter from the
+	// stationary distribution of the initial state.
+	ExactLikelihood LikelihoodMethod = iota
+	// ConditionalLikelihood is the Gaussian likelihood conditional on the first values, with the
+	// noise before them set to zero.
+	ConditionalLikelihood
+)
+
+// String returns the name of the likelihood method.
+func (l LikelihoodMethod) String() string {
+	switch l {
+	case ExactLikelihood:
+		return "exact"
+	case ConditionalLikelihood:
+		return "conditional"
+	default:
+		return fmt.Sprintf("LikelihoodMethod(%d)", int(l))
+	}
+}
+
+// ParseLikelihoodMethod returns the likelihood method with the given name, as returned by String.
+func ParseLikelihoodMethod(name string) (LikelihoodMethod, error) {
+	for l := ExactLikelihood; l <= ConditionalLikelihood; l++ {
+		if l.String() == name {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown likelihood method: %q", name)
+}
+
+// ARMAModelParameters holds the parameters of the ARMA model
+// y[t] - mu = -a1 (y[t-1] - mu) - .. - ap (y[t-p] - mu) + e[t] + c1 e[t-1] + .. + cq e[t-q].
+type ARMAModelParameters struct {
+	AutoregressiveLags int              // Number of autoregressive lags (p).
+	MovingAverageLags  int              // Number of moving average lags (q).
+	StepSize           float64          // Step size for time values in predictions.
+	Likelihood         LikelihoodMethod // Likelihood maximized by Fit.
+	Optimizer          Optimizer        // Optimization method used by Fit.
+	MaxIterations      int              // Maximum number of optimizer iterations, 0 for the default.
+}
+
+// ARMAPredictor holds the data and parameters of an ARMA model fitted by maximum likelihood.
+type ARMAPredictor struct {
+	Data   [][]float64         // Input data: each row is [data_value, time_value].
+	Params ARMAModelParameters // Model parameters.
+}
+
+// NewARMAPredictor creates a new ARMAPredictor instance.
+func NewARMAPredictor(data [][]float64, params ARMAModelParameters) (*ARMAPredictor, error) {
+	p, q := params.AutoregressiveLags, params.MovingAverageLags
+	if p < 0 || q < 0 {
+		return nil, fmt.Errorf("lags must not be negative, autoregressive lags: %d, moving average lags: %d", p, q)
+	}
+	if params.StepSize <= 0 {
+		return nil, fmt.Errorf("step size must be a positive number, step size: %f", params.StepSize)
+	}
+	if params.Likelihood < ExactLikelihood || params.Likelihood > ConditionalLikelihood {
+		return nil, fmt.Errorf("unknown likelihood method: %d", params.Likelihood)
+	}
+	if _, err := params.Optimizer.method(); err != nil {
+		return nil, err
+	}
+	if len(data) <= p+q+2 {
+		return nil, fmt.Errorf("not enough data points for ARMA model, need at least %d points", p+q+3)
+	}
+	return &ARMAPredictor{Data: data, Params: params}, nil
+}
+
+// ARMAModel is a fitted ARMAPredictor.
+type ARMAModel struct {
+	Params        ARMAModelParameters // Model parameters.
+	Mean          float64             // Estimated mean mu.
+	AR            []float64           // Estimated autoregressive coefficients a1 .. ap.
+	MA            []float64           // Estimated moving average coefficients c1 .. cq.
+	Sigma2        float64             // Estimated noise variance.
+	StdErrors     []float64           // Standard errors of [mu, a1 .. ap, c1 .. cq, sigma2].
+	LogLikelihood float64             // Maximized log-likelihood.
+	Converged     bool                // Whether the optimizer reported convergence.
+	LastTime      float64             // Time value of the last training point.
+
+	state      []float64     // Filtered state at the last training point.
+	covariance *mat.SymDense // Filtered state covariance at the last training point.
+}
+
+// Fit estimates the ARMA model by maximizing the likelihood selected in the parameters, keeping
+// the autoregressive part stationary and the moving average part invertible.
+func (p *ARMAPredictor) Fit() (*ARMAModel, error) {
+	np, nq := p.Params.AutoregressiveLags, p.Params.MovingAverageLags
+	values := make([]float64, len(p.Data))
+	for i, row := range p.Data {
+		values[i] = row[0]
+	}
+
+	logLikelihood := func(params []float64) float64 {
+		mean, arCoef, maCoef, sigma2 := splitARMAParams(params, np, nq)
+		if p.Params.Likelihood == ConditionalLikelihood {
+			return ConditionalGaussianLogLikelihood(armaResiduals(values, mean, arCoef, maCoef), sigma2)
+		}
+		ss, err := armaStateSpace(mean, arCoef, maCoef, sigma2)
+		if err != nil {
+			return math.Inf(-1)
+		}
+		ll, err := ss.LogLikelihood(columnRows(values), nil)
+		if err != nil {
+			return math.Inf(-1)
+		}
+		return ll
+	}
+
+	problem := MLEProblem{
+		LogLikelihood: logLikelihood,
+		Initial:       armaInitialParams(values, np, nq),
+		Bounds:        make([]Bounds, 1+np+nq+1),
+	}
+	for i := range problem.Bounds {
+		problem.Bounds[i] = Unbounded()
+	}
+	problem.Bounds[1+np+nq] = Bounds{Lower: 0, Upper: math.Inf(1)}
+	if np > 0 {
+		problem.Stationary = append(problem.Stationary, ParameterBlock{Start: 1, Length: np})
+	}
+	if nq > 0 {
+		problem.Stationary = append(problem.Stationary, ParameterBlock{Start: 1 + np, Length: nq})
+	}
+
+	result, err := MaximizeLikelihood(problem, MLEParameters{Optimizer: p.Params.Optimizer, MaxIterations: p.Params.MaxIterations})
+	if err != nil {
+		return nil, err
+	}
+
+	mean, arCoef, maCoef, sigma2 := splitARMAParams(result.Params, np, nq)
+	ss, err := armaStateSpace(mean, arCoef, maCoef, sigma2)
+	if err != nil {
+		return nil, err
+	}
+	filtered, err := ss.Filter(columnRows(values), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(values)
+	return &ARMAModel{
+		Params:        p.Params,
+		Mean:          mean,
+		AR:            arCoef,
+		MA:            maCoef,
+		Sigma2:        sigma2,
+		StdErrors:     result.StdErrors,
+		LogLikelihood: result.LogLikelihood,
+		Converged:     result.Converged,
+		LastTime:      p.Data[n-1][1],
+		state:         filtered.FilteredStates[n-1],
+		covariance:    filtered.FilteredCovariances[n-1],
+	}, nil
+}
+
+// Predict forecasts the given number of steps past the end of the training data and returns a
+// slice of [time_value, predicted_value] pairs.
+func (m *ARMAModel) Predict(numToPredict int) ([][]float64, error) {
+	forecast, _, err := m.PredictWithVariance(numToPredict)
+	return forecast, err
+}
+
+// PredictWithVariance is Predict that also returns the variance of every forecast value.
+func (m *ARMAModel) PredictWithVariance(numToPredict int) ([][]float64, []float64, error) {
+	if m.state == nil {
+		return nil, nil, fmt.Errorf("model has not been fitted")
+	}
+	ss, err := armaStateSpace(m.Mean, m.AR, m.MA, m.Sigma2)
+	if err != nil {
+		return nil, nil, err
+	}
+	forecast, err := ss.Forecast(m.state, m.covariance, nil, numToPredict)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := make([][]float64, numToPredict)
+	variances := make([]float64, numToPredict)
+	for h := range result {
+		result[h] = []float64{m.LastTime + float64(h+1)*m.Params.StepSize, forecast.Observations[h][0]}
+		variances[h] = forecast.ObservationCovariances[h].At(0, 0)
+	}
+	return result, variances, nil
+}
+
+// splitARMAParams splits the parameter vector [mu, a1 .. ap, c1 .. cq, sigma2].
+func splitARMAParams(params []float64, p int, q int) (mean float64, arCoef []float64, maCoef []float64, sigma2 float64) {
+	return params[0], params[1 : 1+p], params[1+p : 1+p+q], params[1+p+q]
+}
+
+// armaInitialParams returns starting values: the sample mean, a least-squares AR fit, no moving
+// average and the variance of the AR residuals.
+func armaInitialParams(values []float64, p int, q int) []float64 {
+	params := make([]float64, 1+p+q+1)
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	params[0] = mean
+
+	n := len(values) - p
+	X := mat.NewDense(n, max(p, 1), nil)
+	Y := mat.NewDense(n, 1, nil)
+	for t := p; t < len(values); t++ {
+		Y.Set(t-p, 0, values[t]-mean)
+		for j := 1; j <= p; j++ {
+			X.Set(t-p, j-1, -(values[t-j] - mean))
+		}
+	}
+	sse := mat.Sum(mulElemDense(Y, Y))
+	if p > 0 {
+		if B, E, err := solveLeastSquares(X, Y); err == nil {
+			for j := 0; j < p; j++ {
+				params[1+j] = B.At(j, 0)
+			}
+			sse = mat.Sum(mulElemDense(E, E))
+		}
+	}
+	params[1+p+q] = math.Max(sse/float64(n), 1e-8)
+	return params
+}
+
+// mulElemDense returns the element-wise product of a and b.
+func mulElemDense(a, b mat.Matrix) *mat.Dense {
+	var d mat.Dense
+	d.MulElem(a, b)
+	return &d
+}
+
+// armaResiduals returns the noise e[t] of the ARMA model for t >= p, with the noise before p set to zero.
+func armaResiduals(values []float64, mean float64, arCoef []float64, maCoef []float64) []float64 {
+	p, q := len(arCoef), len(maCoef)
+	e := make([]float64, len(values))
+	for t := p; t < len(values); t++ {
+		v := values[t] - mean
+		for j := 1; j <= p; j++ {
+			v += arCoef[j-1] * (values[t-j] - mean)
+		}
+		for j := 1; j <= q && t-j >= 0; j++ {
+			v -= maCoef[j-1] * e[t-j]
+		}
+		e[t] = v
+	}
+	return e[p:]
+}
+
+// armaStateSpace returns the state-space form of the ARMA model, with a state of dimension
+// max(p, q+1) started from its stationary distribution.
+func armaStateSpace(mean float64, arCoef []float64, maCoef []float64, sigma2 float64) (*StateSpaceModel, error) {
+	p, q := len(arCoef), len(maCoef)
+	r := max(p, q+1)
+
+	T := mat.NewDense(r, r, nil)
+	for i := 0; i < p; i++ {
+		T.Set(i, 0, -arCoef[i])
+	}
+	for i := 0; i < r-1; i++ {
+		T.Set(i, i+1, 1)
+	}
+	loading := make([]float64, r)
+	loading[0] = 1
+	copy(loading[1:], maCoef)
+	Q := mat.NewSymDense(r, nil)
+	for i := 0; i < r; i++ {
+		for j := i; j < r; j++ {
+			Q.SetSym(i, j, sigma2*loading[i]*loading[j])
+		}
+	}
+	Z := mat.NewDense(1, r, nil)
+	Z.Set(0, 0, 1)
+
+	P0, err := stationaryCovariance(T, Q)
+	if err != nil {
+		return nil, err
+	}
+	return &StateSpaceModel{
+		T:                 T,
+		Q:                 Q,
+		Z:                 Z,
+		H:                 mat.NewSymDense(1, nil),
+		ObsIntercept:      []float64{mean},
+		InitialState:      make([]float64, r),
+		InitialCovariance: P0,
+	}, nil
+}
+
+// stationaryCovariance solves P = T P T' + Q for the covariance of a stationary state.
+func stationaryCovariance(T *mat.Dense, Q *mat.SymDense) (*mat.SymDense, error) {
+	r, _ := T.Dims()
+	A := mat.NewDense(r*r, r*r, nil)
+	b := mat.NewVecDense(r*r, nil)
+	for i := 0; i < r; i++ {
+		for j := 0; j < r; j++ {
+			row := i*r + j
+			b.SetVec(row, Q.At(i, j))
+			for k := 0; k < r; k++ {
+				for l := 0; l < r; l++ {
+					v := -T.At(i, k) * T.At(j, l)
+					if row == k*r+l {
+						v++
+					}
+					A.Set(row, k*r+l, v)
+				}
+			}
+		}
+	}
+	var vecP mat.VecDense
+	if err := vecP.SolveVec(A, b); err != nil {
+		return nil, fmt.Errorf("state has no stationary distribution: %w", err)
+	}
+	return symmetrize(mat.NewDense(r, r, vecP.RawVector().Data)), nil
+}
+
+// columnRows returns the values as single-value rows, the observation layout of StateSpaceModel.
+func columnRows(values []float64) [][]float64 {
+	rows := make([][]float64, len(values))
+	for i, v := range values {
+		rows[i] = []float64{v}
+	}
+	return rows
+}
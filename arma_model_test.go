@@ -0,0 +1,116 @@
+package ar
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// simulateARMA simulates y[t] - mu = -a (y[t-1] - mu) + e[t] + c e[t-1] with unit noise variance.
+func simulateARMA(n int, mu, a, c float64) [][]float64 {
+	rnd := rand.New(rand.NewSource(11))
+	data := make([][]float64, n)
+	prevY, prevE := 0.0, 0.0
+	for t := 0; t < n; t++ {
+		e := rnd.NormFloat64()
+		y := -a*prevY + e + c*prevE
+		data[t] = []float64{mu + y, float64(t)}
+		prevY, prevE = y, e
+	}
+	return data
+}
+
+func TestARMAFit(t *testing.T) {
+	data := simulateARMA(1000, 5, -0.6, 0.3)
+
+	tests := []struct {
+		likelihood LikelihoodMethod
+		optimizer  Optimizer
+	}{
+		{ExactLikelihood, NelderMead},
+		{ConditionalLikelihood, BFGS},
+		{ConditionalLikelihood, LBFGS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.likelihood.String()+"/"+tt.optimizer.String(), func(t *testing.T) {
+			predictor, err := NewARMAPredictor(data, ARMAModelParameters{
+				AutoregressiveLags: 1,
+				MovingAverageLags:  1,
+				StepSize:           1,
+				Likelihood:         tt.likelihood,
+				Optimizer:          tt.optimizer,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create predictor: %v", err)
+			}
+			model, err := predictor.Fit()
+			if err != nil {
+				t.Fatalf("Fit() error = %v", err)
+			}
+
+			if !approxEqual(model.Mean, 5, 0.3) || !approxEqual(model.AR[0], -0.6, 0.1) || !approxEqual(model.MA[0], 0.3, 0.1) || !approxEqual(model.Sigma2, 1, 0.15) {
+				t.Errorf("Fit() = mean %v, AR %v, MA %v, sigma2 %v, want 5, [-0.6], [0.3], 1", model.Mean, model.AR, model.MA, model.Sigma2)
+			}
+			for i, se := range model.StdErrors {
+				if !(se > 0 && se < 1) {
+					t.Errorf("StdErrors[%d] = %v, want in (0, 1)", i, se)
+				}
+			}
+
+			forecast, variances, err := model.PredictWithVariance(20)
+			if err != nil {
+				t.Fatalf("PredictWithVariance() error = %v", err)
+			}
+			if forecast[0][0] != 1000 || !approxEqual(forecast[19][1], model.Mean, 0.05) {
+				t.Errorf("forecast starts at time %v and ends at %v, want time 1000 and the mean %v", forecast[0][0], forecast[19][1], model.Mean)
+			}
+			if !approxEqual(variances[0], model.Sigma2, 1e-9) || variances[19] < variances[0] {
+				t.Errorf("variances = %v, want sigma2 %v first and increasing", variances, model.Sigma2)
+			}
+		})
+	}
+}
+
+func TestARMAExactLikelihoodWhiteNoise(t *testing.T) {
+	// With an AR(1) state, the exact likelihood of the first value uses the stationary variance.
+	ss, err := armaStateSpace(0, []float64{-0.5}, nil, 1)
+	if err != nil {
+		t.Fatalf("armaStateSpace() error = %v", err)
+	}
+	if got := ss.InitialCovariance.At(0, 0); !approxEqual(got, 1/(1-0.25), 1e-12) {
+		t.Errorf("stationary variance = %v, want %v", got, 1/(1-0.25))
+	}
+
+	ll, err := ss.LogLikelihood([][]float64{{1}, {0.5}}, nil)
+	if err != nil {
+		t.Fatalf("LogLikelihood() error = %v", err)
+	}
+	v0 := 1 / (1 - 0.25)
+	want := -0.5*(math.Log(2*math.Pi*v0)+1/v0) - 0.5*(math.Log(2*math.Pi)+0)
+	if !approxEqual(ll, want, 1e-12) {
+		t.Errorf("LogLikelihood() = %v, want %v", ll, want)
+	}
+}
+
+func TestNewARMAPredictor(t *testing.T) {
+	data := simulateARMA(20, 0, -0.5, 0)
+	tests := []struct {
+		name   string
+		data   [][]float64
+		params ARMAModelParameters
+	}{
+		{"Negative lags", data, ARMAModelParameters{AutoregressiveLags: -1, StepSize: 1}},
+		{"Zero step size", data, ARMAModelParameters{AutoregressiveLags: 1}},
+		{"Negative step size", data, ARMAModelParameters{AutoregressiveLags: 1, StepSize: -1}},
+		{"Unknown likelihood", data, ARMAModelParameters{AutoregressiveLags: 1, StepSize: 1, Likelihood: LikelihoodMethod(5)}},
+		{"Unknown optimizer", data, ARMAModelParameters{AutoregressiveLags: 1, StepSize: 1, Optimizer: Optimizer(5)}},
+		{"Not enough data", data[:3], ARMAModelParameters{AutoregressiveLags: 1, MovingAverageLags: 1, StepSize: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewARMAPredictor(tt.data, tt.params); err == nil {
+				t.Error("NewARMAPredictor() returned no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package ar
+
+import (
+	"fmt"
+	"math"
+)
+
+// Forecaster is implemented by the predictors of this package: it returns the predicted
+// data as a slice of [time, value] pairs, ending with the numToPredict forecast values.
+type Forecaster interface {
+	Predict(numToPredict int) ([][]float64, error)
+}
+
+// BacktestParameters holds the configuration of a rolling-origin backtest.
+type BacktestParameters struct {
+	Horizon       int // Horizon: number of steps forecast from every origin.
+	InitialWindow int // InitialWindow: number of data points used to fit the first model.
+	Step          int // Step: how many data points the origin moves between folds, 1 when zero.
+}
+
+// BacktestResult holds the forecast errors of a rolling-origin backtest.
+type BacktestResult struct {
+	Origins     []int       // Index of the last training data point of each fold.
+	Errors      [][]float64 // Forecast errors (actual - predicted) per fold and horizon step.
+	HorizonRMSE []float64   // Root mean squared error per horizon step, across folds.
+	MAE         float64     // Mean absolute error over all folds and horizon steps.
+	RMSE        float64     // Root mean squared error over all folds and horizon steps.
+}
+
+// Backtest evaluates a model by rolling-origin evaluation: for every origin it builds a
+// forecaster on the data up to that origin and compares its forecast with the next Horizon
+// data values. The build function typically wraps NewLSPredictor or NewLSARXPredictor.
+func Backtest(data [][]float64, params BacktestParameters, build func(train [][]float64) (Forecaster, error)) (*BacktestResult, error) {
+	if params.Horizon <= 0 {
+		return nil, fmt.Errorf("horizon must be a positive integer, horizon: %d", params.Horizon)
+	}
+	if params.InitialWindow <= 0 {
+		return nil, fmt.Errorf("initial window must be a positive integer, initial window: %d", params.InitialWindow)
+	}
+	step := params.Step
+	if step == 0 {
+		step = 1
+	}
+	if step < 0 {
+		return nil, fmt.Errorf("step must be a positive integer, step: %d", params.Step)
+	}
+	if len(data) < params.InitialWindow+params.Horizon {
+		return nil, fmt.Errorf("not enough data points for backtest, need at least %d points", params.InitialWindow+params.Horizon)
+	}
+
+	result := &BacktestResult{HorizonRMSE: make([]float64, params.Horizon)}
+	sumAbs, sumSq := 0.0, 0.0
+	for end := params.InitialWindow; end+params.Horizon <= len(data); end += step {
+		forecaster, err := build(data[:end])
+		if err != nil {
+			return nil, fmt.Errorf("failed to build model at origin %d: %w", end-1, err)
+		}
+		predicted, err := forecaster.Predict(params.Horizon)
+		if err != nil {
+			return nil, fmt.Errorf("failed to predict at origin %d: %w", end-1, err)
+		}
+		if len(predicted) < params.Horizon {
+			return nil, fmt.Errorf("model returned %d values at origin %d, expected at least %d", len(predicted), end-1, params.Horizon)
+		}
+
+		forecast := predicted[len(predicted)-params.Horizon:]
+		errs := make([]float64, params.Horizon)
+		for h := range errs {
+			errs[h] = data[end+h][0] - forecast[h][1]
+			sumAbs += math.Abs(errs[h])
+			sumSq += errs[h] * errs[h]
+			result.HorizonRMSE[h] += errs[h] * errs[h]
+		}
+		result.Origins = append(result.Origins, end-1)
+		result.Errors = append(result.Errors, errs)
+	}
+
+	folds := float64(len(result.Errors))
+	for h := range result.HorizonRMSE {
+		result.HorizonRMSE[h] = math.Sqrt(result.HorizonRMSE[h] / folds)
+	}
+	result.MAE = sumAbs / (folds * float64(params.Horizon))
+	result.RMSE = math.Sqrt(sumSq / (folds * float64(params.Horizon)))
+
+	return result, nil
+}
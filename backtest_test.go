@@ -0,0 +1,94 @@
+package ar
+
+import (
+	"math"
+	"testing"
+)
+
+// sampleData is the series used in the package examples: each row is [data_value, time_value].
+var sampleData = [][]float64{
+	{1578.0077, 0}, {1581.1876, 5}, {1452.4627, 33},
+	{1449.7326, 58}, {1501.0392, 80}, {1460.4557, 110},
+	{1492.824, 130}, {1422.3826, 155}, {1404.3431, 180},
+	{1480.74, 210}, {1410.3936, 230}, {1612.336, 255},
+	{1729.343, 280}, {1735.5231, 305}, {1632.595, 330},
+	{1648.3143, 355}, {1640.1972, 380}, {1658.7949, 405},
+	{1675.4953, 430}, {1712.2672, 455}, {1623.8666, 480},
+	{1622.154, 505}, {1630.9466, 530}, {1595.8407, 555},
+	{1548.5976, 580}, {1598.6558, 605}, {1624.0902, 630},
+	{1616.8663, 655}, {1661.251, 680}, {2012.605, 705},
+	{1904.3356, 730}, {1760.5438, 755}, {2449.3183, 780},
+	{2417.4744, 805}, {2431.7134, 830}, {2391.2651, 855},
+	{2402.8298, 885}, {2417.0901, 905}, {2403.8137, 930},
+	{2407.1756, 955}, {2363.049, 980}, {2364.4589, 1010},
+	{2368.4206, 1030}, {2338.8434, 1055}, {2369.9809, 1080},
+	{2353.5891, 1105}, {2380.8422, 1130}, {2519.2731, 1155},
+	{2557.5253, 1180}, {2536.3437, 1205}, {2517.6042, 1235},
+	{2543.7378, 1255}, {2355.5603, 1280}, {2347.445, 1305},
+	{2269.8631, 1335}, {2307.6435, 1355}, {2274.5249, 1380},
+	{2319.0633, 1405}, {2251.9456, 1430}, {2273.7241, 1455},
+	{2250.0617, 1480}, {2272.8212, 1505}, {2367.9611, 1530},
+	{2351.8406, 1555}, {2348.4958, 1580}, {2308.7974, 1605},
+	{2290.4632, 1630}, {2303.6924, 1655}, {2218.8104, 1680},
+	{2260.9153, 1705}, {2236.759, 1730}, {2238.0003, 1755},
+	{2222.3537, 1780}, {2288.0802, 1805}, {2240.4641, 1830},
+	{2258.3908, 1855}, {2175.4428, 1880}, {2247.978, 1905},
+	{2234.6417, 1930}, {2232.0709, 1955}, {2216.933, 1980},
+	{2219.6263, 2005}, {2304.114, 2030}, {2230.2487, 2055},
+	{2261.5, 2070},
+}
+
+func TestBacktest(t *testing.T) {
+	params := BacktestParameters{Horizon: 4, InitialWindow: 60, Step: 5}
+
+	for _, strategy := range []PredictionStrategy{RecursiveStrategy, DirectStrategy, DirRecStrategy} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			result, err := Backtest(sampleData, params, func(train [][]float64) (Forecaster, error) {
+				return NewLSARXPredictor(train, LSARXModelParameters{
+					AutoregressiveLags: 2,
+					ExternalInputLags:  2,
+					StepSize:           25,
+					Strategy:           strategy,
+				})
+			})
+			if err != nil {
+				t.Fatalf("Backtest() error = %v", err)
+			}
+
+			expectedFolds := (len(sampleData)-params.Horizon-params.InitialWindow)/params.Step + 1
+			if len(result.Errors) != expectedFolds || len(result.Origins) != expectedFolds {
+				t.Errorf("Backtest() returned %d folds, want %d", len(result.Errors), expectedFolds)
+			}
+			if len(result.HorizonRMSE) != params.Horizon {
+				t.Errorf("Backtest() returned %d horizon errors, want %d", len(result.HorizonRMSE), params.Horizon)
+			}
+			if math.IsNaN(result.RMSE) || result.RMSE <= 0 || result.MAE > result.RMSE {
+				t.Errorf("Backtest() returned inconsistent errors, MAE = %f, RMSE = %f", result.MAE, result.RMSE)
+			}
+		})
+	}
+}
+
+func TestBacktestInvalidParameters(t *testing.T) {
+	build := func(train [][]float64) (Forecaster, error) {
+		return NewLSPredictor(train, LSModelParameters{StepSize: 25})
+	}
+
+	testCases := []struct {
+		name   string
+		params BacktestParameters
+	}{
+		{name: "Zero horizon", params: BacktestParameters{Horizon: 0, InitialWindow: 10}},
+		{name: "Zero initial window", params: BacktestParameters{Horizon: 1, InitialWindow: 0}},
+		{name: "Negative step", params: BacktestParameters{Horizon: 1, InitialWindow: 10, Step: -1}},
+		{name: "Window too large", params: BacktestParameters{Horizon: 5, InitialWindow: len(sampleData)}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Backtest(sampleData, tc.params, build); err == nil {
+				t.Errorf("Backtest() expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package ar
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"runtime"
+	"sync"
+)
+
+// BatchParameters holds the configuration of a batch forecast.
+type BatchParameters struct {
+	Model        LSARXModelParameters                       // Model: parameters of the LSARX predictor fitted on every series.
+	NumToPredict int                                        // NumToPredict: number of values forecast for every series.
+	Workers      int                                        // Workers: number of series fitted concurrently, GOMAXPROCS when zero.
+	Build        func(data [][]float64) (Forecaster, error) // Build: builds the forecaster of a series instead of an LSARX predictor with Model when set.
+}
+
+// BatchResult holds the forecast of one series of a batch, or the error that prevented it.
+type BatchResult struct {
+	Key      string      // Key of the series.
+	Index    int         // Position of the series in the batch.
+	Forecast [][]float64 // Forecast [time, value] pairs, the last NumToPredict rows of Predict, which for LSARX models are the forecast of LSARXModel.Predict.
+	Err      error       // Error building or predicting the series, nil on success.
+}
+
+// BatchForecast fits and forecasts every keyed series of the batch, each a slice of
+// [data_value, time_value] rows, on a pool of worker goroutines. It streams one result per series
+// on the returned channel, in completion order, and closes the channel once the batch is done.
+// The error of a series is reported in its result and does not stop the others. Every worker
+// forecasts the LSARX predictors with its own LSARXWorkspace.
+//
+// When the context is cancelled or its deadline passes, BatchForecast stops reading the batch and
+// starting fits, and closes the channel once the fits in progress are done; ctx.Err() tells a
+// cancelled batch from a complete one. The series iterator runs on its own goroutine, and the
+// caller must drain the channel or cancel the context.
+func BatchForecast(ctx context.Context, series iter.Seq2[string, [][]float64], params BatchParameters) (<-chan BatchResult, error) {
+	if params.NumToPredict <= 0 {
+		return nil, fmt.Errorf("number of values to predict must be a positive integer, number to predict: %d", params.NumToPredict)
+	}
+	if params.Workers < 0 {
+		return nil, fmt.Errorf("workers must not be negative, workers: %d", params.Workers)
+	}
+	build := params.Build
+	if build == nil {
+		// The forecast-only output starts from the observed data, like the forecast of a fitted model.
+		model := params.Model
+		model.OutputMode = ForecastOnlyOutput
+		if _, err := NewLSARXPredictor(nil, model); err != nil {
+			return nil, err
+		}
+		build = func(data [][]float64) (Forecaster, error) {
+			return NewLSARXPredictor(data, model)
+		}
+	}
+	workers := params.Workers
+	if workers == 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	type job struct {
+		key   string
+		index int
+		data  [][]float64
+	}
+	jobs := make(chan job)
+	results := make(chan BatchResult, workers)
+
+	go func() {
+		defer close(jobs)
+		index := 0
+		for key, data := range series {
+			select {
+			case jobs <- job{key, index, data}:
+				index++
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var ws LSARXWorkspace
+			for j := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				forecast, err := batchForecastSeries(build, &ws, j.data, params.NumToPredict)
+				select {
+				case results <- BatchResult{Key: j.key, Index: j.index, Forecast: forecast, Err: err}:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results, nil
+}
+
+// batchForecastSeries builds the forecaster of one series and returns its last numToPredict
+// predicted rows. LSARX predictors forecast with the workspace of the worker. A panic of the
+// forecaster is returned as an error, so that it only fails its series.
+func batchForecastSeries(build func(data [][]float64) (Forecaster, error), ws *LSARXWorkspace, data [][]float64, numToPredict int) (forecast [][]float64, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			forecast, err = nil, fmt.Errorf("forecaster panicked: %v", r)
+		}
+	}()
+	forecaster, err := build(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build model: %w", err)
+	}
+	var predicted [][]float64
+	lp, isLSARX := forecaster.(*LSARXPredictor)
+	if isLSARX {
+		predicted, err = lp.PredictWith(ws, numToPredict)
+	} else {
+		predicted, err = forecaster.Predict(numToPredict)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to predict: %w", err)
+	}
+	if len(predicted) < numToPredict {
+		return nil, fmt.Errorf("model returned %d values, expected at least %d", len(predicted), numToPredict)
+	}
+	forecast = predicted[len(predicted)-numToPredict:]
+	if isLSARX {
+		// The rows of PredictWith belong to the workspace.
+		forecast = copyRows(forecast)
+	}
+	return forecast, nil
+}
@@ -0,0 +1,194 @@
+package ar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+	"testing"
+)
+
+// batchTestSeries returns n keyed series: shifted copies of the sample data, except for the
+// series at index bad, which is too short to fit.
+func batchTestSeries(n int, bad int) iter.Seq2[string, [][]float64] {
+	return func(yield func(string, [][]float64) bool) {
+		for i := range n {
+			data := copyRows(sampleData)
+			for _, row := range data {
+				row[0] += float64(i)
+			}
+			if i == bad {
+				data = data[:1]
+			}
+			if !yield(fmt.Sprintf("series-%d", i), data) {
+				return
+			}
+		}
+	}
+}
+
+func TestBatchForecast(t *testing.T) {
+	params := BatchParameters{
+		Model:        LSARXModelParameters{AutoregressiveLags: 2, ExternalInputLags: 1, StepSize: 25},
+		NumToPredict: 3,
+		Workers:      4,
+	}
+	results, err := BatchForecast(context.Background(), batchTestSeries(50, 7), params)
+	if err != nil {
+		t.Fatalf("BatchForecast() error = %v", err)
+	}
+
+	seen := make(map[int]bool)
+	for r := range results {
+		seen[r.Index] = true
+		if r.Key != fmt.Sprintf("series-%d", r.Index) {
+			t.Errorf("result key = %q at index %d", r.Key, r.Index)
+		}
+		if r.Index == 7 {
+			if r.Err == nil {
+				t.Errorf("result of the short series = %+v, want an error", r)
+			}
+			continue
+		}
+		if r.Err != nil {
+			t.Errorf("series %s error = %v", r.Key, r.Err)
+			continue
+		}
+
+		data := copyRows(sampleData)
+		for _, row := range data {
+			row[0] += float64(r.Index)
+		}
+		predictor, err := NewLSARXPredictor(data, params.Model)
+		if err != nil {
+			t.Fatalf("Failed to create predictor: %v", err)
+		}
+		result, err := predictor.Forecast(params.NumToPredict)
+		if err != nil {
+			t.Fatalf("Forecast() error = %v", err)
+		}
+		want := result.Forecast
+		for i := range want {
+			if r.Forecast[i][0] != want[i][0] || !approxEqual(r.Forecast[i][1], want[i][1], 1e-9) {
+				t.Errorf("series %s forecast = %v, want %v", r.Key, r.Forecast, want)
+				break
+			}
+		}
+	}
+	if len(seen) != 50 {
+		t.Errorf("got %d results, want 50", len(seen))
+	}
+}
+
+func TestBatchForecastMatchesFit(t *testing.T) {
+	data := delayedARXData(120)
+	for _, strategy := range []PredictionStrategy{RecursiveStrategy, DirectStrategy, DirRecStrategy} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			params := BatchParameters{
+				Model:        LSARXModelParameters{AutoregressiveLags: 2, ExternalInputLags: 2, Intercept: true, StepSize: 1, Strategy: strategy},
+				NumToPredict: 4,
+			}
+			series := func(yield func(string, [][]float64) bool) { yield("series", data) }
+			results, err := BatchForecast(context.Background(), series, params)
+			if err != nil {
+				t.Fatalf("BatchForecast() error = %v", err)
+			}
+			r := <-results
+			if r.Err != nil {
+				t.Fatalf("series error = %v", r.Err)
+			}
+
+			predictor, err := NewLSARXPredictor(data, params.Model)
+			if err != nil {
+				t.Fatalf("Failed to create predictor: %v", err)
+			}
+			model, err := predictor.Fit(params.NumToPredict)
+			if err != nil {
+				t.Fatalf("Fit() error = %v", err)
+			}
+			want, err := model.Predict(params.NumToPredict)
+			if err != nil {
+				t.Fatalf("Predict() error = %v", err)
+			}
+			for i := range want {
+				if r.Forecast[i][0] != want[i][0] || !approxEqual(r.Forecast[i][1], want[i][1], 1e-9) {
+					t.Errorf("batch forecast = %v, want %v", r.Forecast, want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestBatchForecastBuild(t *testing.T) {
+	params := BatchParameters{
+		NumToPredict: 2,
+		Build: func(data [][]float64) (Forecaster, error) {
+			if len(data) == 1 {
+				panic("bad series")
+			}
+			return NewLSPredictor(data, LSModelParameters{StepSize: 25})
+		},
+	}
+	results, err := BatchForecast(context.Background(), batchTestSeries(5, 2), params)
+	if err != nil {
+		t.Fatalf("BatchForecast() error = %v", err)
+	}
+	var failed []int
+	count := 0
+	for r := range results {
+		count++
+		if r.Err != nil {
+			failed = append(failed, r.Index)
+		} else if len(r.Forecast) != 2 {
+			t.Errorf("series %s forecast = %v, want 2 values", r.Key, r.Forecast)
+		}
+	}
+	if count != 5 || !reflect.DeepEqual(failed, []int{2}) {
+		t.Errorf("got %d results with failed series %v, want 5 with the panicking series 2", count, failed)
+	}
+}
+
+func TestBatchForecastCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	params := BatchParameters{
+		Model:        LSARXModelParameters{AutoregressiveLags: 1, ExternalInputLags: 1, StepSize: 25},
+		NumToPredict: 1,
+		Workers:      2,
+	}
+	results, err := BatchForecast(ctx, batchTestSeries(100000, -1), params)
+	if err != nil {
+		t.Fatalf("BatchForecast() error = %v", err)
+	}
+	count := 0
+	for range results {
+		count++
+		if count == 10 {
+			cancel()
+		}
+	}
+	if count >= 100000 || !errors.Is(ctx.Err(), context.Canceled) {
+		t.Errorf("got %d results after cancelling, want the batch to stop early", count)
+	}
+}
+
+func TestBatchForecastErrors(t *testing.T) {
+	model := LSARXModelParameters{AutoregressiveLags: 1, StepSize: 25}
+	tests := []struct {
+		name   string
+		params BatchParameters
+	}{
+		{"Nothing to predict", BatchParameters{Model: model}},
+		{"Negative workers", BatchParameters{Model: model, NumToPredict: 1, Workers: -1}},
+		{"Invalid model", BatchParameters{Model: LSARXModelParameters{StepSize: 25}, NumToPredict: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := BatchForecast(context.Background(), batchTestSeries(1, -1), tt.params); err == nil {
+				t.Error("BatchForecast() returned no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,506 @@
+package ar
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// Frequency is the spacing of the timestamps of a Calendar.
+type Frequency int
+
+const (
+	// MinuteFrequency spaces the timestamps by one minute of elapsed time.
+	MinuteFrequency Frequency = iota
+	// HourlyFrequency spaces the timestamps by one hour of elapsed time, so the wall clock skips or
+	// repeats an hour at daylight saving changes.
+	HourlyFrequency
+	// DailyFrequency spaces the timestamps by one calendar day at the wall-clock time of the start,
+	// so days across daylight saving changes last 23 or 25 hours.
+	DailyFrequency
+	// BusinessDayFrequency spaces the timestamps like DailyFrequency, skipping weekends and holidays.
+	BusinessDayFrequency
+	// MonthlyFrequency spaces the timestamps by one calendar month on the day of the month of the
+	// start, or the last day of shorter months.
+	MonthlyFrequency
+)
+
+// String returns the name of the frequency.
+func (f Frequency) String() string {
+	switch f {
+	case MinuteFrequency:
+		return "minute"
+	case HourlyFrequency:
+		return "hourly"
+	case DailyFrequency:
+		return "daily"
+	case BusinessDayFrequency:
+		return "business-day"
+	case MonthlyFrequency:
+		return "monthly"
+	default:
+		return fmt.Sprintf("Frequency(%d)", int(f))
+	}
+}
+
+// ParseFrequency returns the frequency with the given name, as returned by String.
+func ParseFrequency(name string) (Frequency, error) {
+	for f := MinuteFrequency; f <= MonthlyFrequency; f++ {
+		if f.String() == name {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown frequency: %q", name)
+}
+
+// CalendarFeature selects columns computed from the timestamps of a Calendar.
+type CalendarFeature int
+
+const (
+	// DayOfWeekFeature adds one dummy column per day of the week from Tuesday to Sunday, Monday being the baseline.
+	DayOfWeekFeature CalendarFeature = iota
+	// WeekendFeature adds a column that is 1 on Saturdays and Sundays.
+	WeekendFeature
+	// HolidayFeature adds a column that is 1 on the holidays of the calendar.
+	HolidayFeature
+	// MonthFeature adds one dummy column per month from February to December, January being the baseline.
+	MonthFeature
+	// HourFeature adds one dummy column per hour of the day from 1 to 23, midnight being the baseline.
+	HourFeature
+)
+
+// String returns the name of the calendar feature.
+func (f CalendarFeature) String() string {
+	switch f {
+	case DayOfWeekFeature:
+		return "day-of-week"
+	case WeekendFeature:
+		return "weekend"
+	case HolidayFeature:
+		return "holiday"
+	case MonthFeature:
+		return "month"
+	case HourFeature:
+		return "hour"
+	default:
+		return fmt.Sprintf("CalendarFeature(%d)", int(f))
+	}
+}
+
+// ParseCalendarFeature returns the calendar feature with the given name, as returned by String.
+func ParseCalendarFeature(name string) (CalendarFeature, error) {
+	for f := DayOfWeekFeature; f <= HourFeature; f++ {
+		if f.String() == name {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown calendar feature: %q", name)
+}
+
+// names returns the labels of the columns of the feature.
+func (f CalendarFeature) names() []string {
+	var names []string
+	switch f {
+	case DayOfWeekFeature:
+		for d := time.Tuesday; d <= time.Saturday+1; d++ {
+			names = append(names, "dow_"+lowerPrefix(time.Weekday(d%7).String()))
+		}
+	case WeekendFeature, HolidayFeature:
+		names = []string{f.String()}
+	case MonthFeature:
+		for m := time.February; m <= time.December; m++ {
+			names = append(names, "month_"+lowerPrefix(m.String()))
+		}
+	case HourFeature:
+		for h := 1; h < 24; h++ {
+			names = append(names, fmt.Sprintf("hour_%d", h))
+		}
+	}
+	return names
+}
+
+// lowerPrefix returns the first three letters of the English name of a day or month, in lower case.
+func lowerPrefix(name string) string {
+	b := []byte(name[:3])
+	b[0] += 'a' - 'A'
+	return string(b)
+}
+
+// Calendar maps the time values of a series to real timestamps: time value k is the k-th
+// timestamp of the frequency after Start. Series on a calendar use the step numbers as time
+// values, with a step size of 1, so forecasts continue on the calendar and deterministic
+// regressors such as CalendarRegressor can evaluate the future timestamps.
+type Calendar struct {
+	Start     time.Time   // Timestamp of time value 0, whose location sets the wall clock of the calendar.
+	Frequency Frequency   // Spacing of the timestamps.
+	Holidays  []time.Time // Dates of the holidays, skipped by the business-day frequency.
+
+	holidays        []int // Sorted days of the holidays, see civilDay.
+	workdayHolidays []int // Sorted days of the holidays on weekdays.
+}
+
+// NewCalendar creates a calendar starting at the given timestamp. With the business-day frequency,
+// the start must be a weekday that is not a holiday.
+func NewCalendar(start time.Time, frequency Frequency, holidays []time.Time) (*Calendar, error) {
+	if frequency < MinuteFrequency || frequency > MonthlyFrequency {
+		return nil, fmt.Errorf("unknown frequency: %d", frequency)
+	}
+	c := &Calendar{Start: start, Frequency: frequency, Holidays: holidays}
+	for _, h := range holidays {
+		day := civilDay(h)
+		c.holidays = append(c.holidays, day)
+		if !isWeekend(h.Weekday()) {
+			c.workdayHolidays = append(c.workdayHolidays, day)
+		}
+	}
+	slices.Sort(c.holidays)
+	slices.Sort(c.workdayHolidays)
+	c.workdayHolidays = slices.Compact(c.workdayHolidays)
+	if frequency == BusinessDayFrequency && (isWeekend(start.Weekday()) || c.IsHoliday(start)) {
+		return nil, fmt.Errorf("start of a business-day calendar must be a business day, start: %v", start)
+	}
+	return c, nil
+}
+
+// civilDay returns the number of days from 1970-01-01 to the date of t, in the location of t.
+func civilDay(t time.Time) int {
+	y, m, d := t.Date()
+	return int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+// isWeekend reports whether the day is a Saturday or a Sunday.
+func isWeekend(d time.Weekday) bool {
+	return d == time.Saturday || d == time.Sunday
+}
+
+// IsHoliday reports whether the date of t, in the location of the calendar, is a holiday.
+func (c *Calendar) IsHoliday(t time.Time) bool {
+	_, ok := slices.BinarySearch(c.holidays, civilDay(t.In(c.Start.Location())))
+	return ok
+}
+
+// Timestamp returns the timestamp of time value step, which may be negative.
+func (c *Calendar) Timestamp(step int) time.Time {
+	y, m, d := c.Start.Date()
+	hour, minute, sec := c.Start.Clock()
+	nsec, loc := c.Start.Nanosecond(), c.Start.Location()
+	switch c.Frequency {
+	case MinuteFrequency:
+		return c.Start.Add(time.Duration(step) * time.Minute)
+	case HourlyFrequency:
+		return c.Start.Add(time.Duration(step) * time.Hour)
+	case DailyFrequency:
+		return time.Date(y, m, d+step, hour, minute, sec, nsec, loc)
+	case BusinessDayFrequency:
+		return time.Date(y, m, d+c.businessDayOffset(step), hour, minute, sec, nsec, loc)
+	default:
+		// The day is clamped to the length of the target month, the day before the first of the next one.
+		days := time.Date(y, m+time.Month(step)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		return time.Date(y, m+time.Month(step), min(d, days), hour, minute, sec, nsec, loc)
+	}
+}
+
+// businessDayOffset returns the number of calendar days from the start to the step-th business day.
+// It counts step weekdays, then extends the count by the holidays it spans until no more are spanned.
+func (c *Calendar) businessDayOffset(step int) int {
+	start := civilDay(c.Start)
+	dir := 1
+	if step < 0 {
+		dir = -1
+	}
+	spanned := 0
+	for {
+		days := weekdayOffset(c.Start.Weekday(), step+dir*spanned)
+		lo, hi := start+1, start+days
+		if step < 0 {
+			lo, hi = start+days, start-1
+		}
+		i, _ := slices.BinarySearch(c.workdayHolidays, lo)
+		j, _ := slices.BinarySearch(c.workdayHolidays, hi+1)
+		if j-i == spanned {
+			return days
+		}
+		spanned = j - i
+	}
+}
+
+// weekdayOffset returns the number of calendar days from weekday wd to the k-th weekday after it,
+// or before it when k is negative.
+func weekdayOffset(wd time.Weekday, k int) int {
+	dir := 1
+	if k < 0 {
+		dir, k = -1, -k
+	}
+	days := dir * k / 5 * 7
+	for r := k % 5; r > 0; {
+		days += dir
+		if !isWeekend(time.Weekday(((int(wd)+days)%7 + 7) % 7)) {
+			r--
+		}
+	}
+	return days
+}
+
+// Step returns the time value of timestamp t, or an error if t is not a timestamp of the calendar.
+func (c *Calendar) Step(t time.Time) (int, error) {
+	// Find steps lo <= hi whose timestamps enclose t, then bisect between them.
+	lo, hi := 0, 0
+	if c.Start.Before(t) {
+		for hi = 1; c.Timestamp(hi).Before(t); hi *= 2 {
+			if hi > math.MaxInt32 {
+				return 0, fmt.Errorf("timestamp %v is too far from the calendar start %v", t, c.Start)
+			}
+			lo = hi
+		}
+	} else {
+		for lo = -1; c.Timestamp(lo).After(t); lo *= 2 {
+			if lo < math.MinInt32 {
+				return 0, fmt.Errorf("timestamp %v is too far from the calendar start %v", t, c.Start)
+			}
+			hi = lo
+		}
+	}
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if c.Timestamp(mid).Before(t) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	if !c.Timestamp(lo).Equal(t) {
+		return 0, fmt.Errorf("timestamp %v is not on the %s calendar starting at %v", t, c.Frequency, c.Start)
+	}
+	return lo, nil
+}
+
+// Data returns the rows [data_value, time_value] of the values observed at the timestamps, which
+// must be consecutive timestamps of the calendar.
+func (c *Calendar) Data(timestamps []time.Time, values []float64) ([][]float64, error) {
+	if len(timestamps) != len(values) {
+		return nil, fmt.Errorf("number of timestamps and values must match, timestamps: %d, values: %d", len(timestamps), len(values))
+	}
+	if len(timestamps) == 0 {
+		return nil, nil
+	}
+	first, err := c.Step(timestamps[0])
+	if err != nil {
+		return nil, err
+	}
+	data := make([][]float64, len(values))
+	for i, v := range values {
+		if !c.Timestamp(first + i).Equal(timestamps[i]) {
+			return nil, fmt.Errorf("timestamp %d is %v, expected the next calendar timestamp %v", i, timestamps[i], c.Timestamp(first+i))
+		}
+		data[i] = []float64{v, float64(first + i)}
+	}
+	return data, nil
+}
+
+// Future returns the n timestamps of the calendar that follow timestamp last.
+func (c *Calendar) Future(last time.Time, n int) ([]time.Time, error) {
+	step, err := c.Step(last)
+	if err != nil {
+		return nil, err
+	}
+	future := make([]time.Time, n)
+	for i := range future {
+		future[i] = c.Timestamp(step + 1 + i)
+	}
+	return future, nil
+}
+
+// Timestamps returns the timestamps of the [time, value] rows returned by Predict on data of the calendar.
+func (c *Calendar) Timestamps(rows [][]float64) []time.Time {
+	timestamps := make([]time.Time, len(rows))
+	for i, row := range rows {
+		timestamps[i] = c.Timestamp(int(math.Round(row[0])))
+	}
+	return timestamps
+}
+
+// Features returns the columns of the calendar features at every timestamp, one row per timestamp,
+// for example as the exogenous inputs of a VARPredictor.
+func (c *Calendar) Features(timestamps []time.Time, features []CalendarFeature) [][]float64 {
+	width := len(CalendarFeatureNames(features))
+	rows := make([][]float64, len(timestamps))
+	for i, t := range timestamps {
+		rows[i] = make([]float64, width)
+		c.evaluateFeatures(t, features, rows[i])
+	}
+	return rows
+}
+
+// CalendarFeatureNames returns the labels of the columns of the calendar features, in the order of Features.
+func CalendarFeatureNames(features []CalendarFeature) []string {
+	var names []string
+	for _, f := range features {
+		names = append(names, f.names()...)
+	}
+	return names
+}
+
+// evaluateFeatures writes the columns of the calendar features at timestamp t to dst.
+func (c *Calendar) evaluateFeatures(t time.Time, features []CalendarFeature, dst []float64) {
+	t = t.In(c.Start.Location())
+	clear(dst)
+	for _, f := range features {
+		switch f {
+		case DayOfWeekFeature:
+			if d := t.Weekday(); d != time.Monday {
+				dst[(int(d)+5)%7] = 1
+			}
+			dst = dst[6:]
+		case WeekendFeature:
+			if isWeekend(t.Weekday()) {
+				dst[0] = 1
+			}
+			dst = dst[1:]
+		case HolidayFeature:
+			if c.IsHoliday(t) {
+				dst[0] = 1
+			}
+			dst = dst[1:]
+		case MonthFeature:
+			if m := t.Month(); m != time.January {
+				dst[m-time.February] = 1
+			}
+			dst = dst[11:]
+		case HourFeature:
+			if h := t.Hour(); h != 0 {
+				dst[h-1] = 1
+			}
+			dst = dst[23:]
+		}
+	}
+}
+
+// CalendarRegressor is a Regressor of calendar features, evaluated at the timestamps of the time
+// values of a series on the calendar.
+type CalendarRegressor struct {
+	Calendar *Calendar         // Calendar of the time values.
+	Features []CalendarFeature // Calendar features, in column order.
+}
+
+// NewCalendarRegressor creates a regressor of the calendar features on the calendar.
+func NewCalendarRegressor(c *Calendar, features ...CalendarFeature) (*CalendarRegressor, error) {
+	if c == nil {
+		return nil, fmt.Errorf("calendar regressor needs a calendar")
+	}
+	if len(features) == 0 {
+		return nil, fmt.Errorf("calendar regressor needs at least one feature")
+	}
+	for _, f := range features {
+		if f < DayOfWeekFeature || f > HourFeature {
+			return nil, fmt.Errorf("unknown calendar feature: %d", f)
+		}
+	}
+	return &CalendarRegressor{Calendar: c, Features: features}, nil
+}
+
+// Names returns the labels of the columns of the features.
+func (r *CalendarRegressor) Names() []string {
+	return CalendarFeatureNames(r.Features)
+}
+
+// Evaluate writes the columns of the features at the timestamp of time value t to dst.
+func (r *CalendarRegressor) Evaluate(t float64, dst []float64) {
+	r.Calendar.evaluateFeatures(r.Calendar.Timestamp(int(math.Round(t))), r.Features, dst)
+}
+
+// calendarJSON is the JSON encoding of a Calendar, with the start in RFC 3339 format, the name of
+// its location and the holidays as dates. A location without a name, such as the fixed zone of a
+// start parsed with a numeric offset, is encoded by its offset from UTC in seconds.
+type calendarJSON struct {
+	Start     string   `json:"start"`
+	Location  string   `json:"location"`
+	Offset    int      `json:"offset,omitempty"`
+	Frequency string   `json:"frequency"`
+	Holidays  []string `json:"holidays,omitempty"`
+}
+
+// newCalendarJSON encodes the calendar.
+func newCalendarJSON(c *Calendar) calendarJSON {
+	v := calendarJSON{
+		Start:     c.Start.Format(time.RFC3339Nano),
+		Location:  c.Start.Location().String(),
+		Frequency: c.Frequency.String(),
+	}
+	if v.Location == "" {
+		_, v.Offset = c.Start.Zone()
+	}
+	for _, h := range c.Holidays {
+		v.Holidays = append(v.Holidays, h.Format(time.DateOnly))
+	}
+	return v
+}
+
+// calendar decodes the calendar. A named location of the start must be known to the time zone
+// database.
+func (v calendarJSON) calendar() (*Calendar, error) {
+	loc := time.FixedZone("", v.Offset)
+	if v.Location != "" {
+		var err error
+		if loc, err = time.LoadLocation(v.Location); err != nil {
+			return nil, fmt.Errorf("unknown calendar location %q: %v", v.Location, err)
+		}
+	}
+	start, err := time.Parse(time.RFC3339Nano, v.Start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid calendar start: %v", err)
+	}
+	frequency, err := ParseFrequency(v.Frequency)
+	if err != nil {
+		return nil, err
+	}
+	var holidays []time.Time
+	for _, s := range v.Holidays {
+		h, err := time.ParseInLocation(time.DateOnly, s, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid calendar holiday: %v", err)
+		}
+		holidays = append(holidays, h)
+	}
+	return NewCalendar(start.In(loc), frequency, holidays)
+}
+
+// calendarRegressorJSON is the JSON encoding of CalendarRegressor.
+type calendarRegressorJSON struct {
+	Calendar calendarJSON `json:"calendar"`
+	Features []string     `json:"features"`
+}
+
+// MarshalJSON encodes the calendar and features of the regressor.
+func (r *CalendarRegressor) MarshalJSON() ([]byte, error) {
+	v := calendarRegressorJSON{Calendar: newCalendarJSON(r.Calendar)}
+	for _, f := range r.Features {
+		v.Features = append(v.Features, f.String())
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes a regressor encoded by MarshalJSON.
+func (r *CalendarRegressor) UnmarshalJSON(data []byte) error {
+	var v calendarRegressorJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	c, err := v.Calendar.calendar()
+	if err != nil {
+		return err
+	}
+	features := make([]CalendarFeature, len(v.Features))
+	for i, name := range v.Features {
+		if features[i], err = ParseCalendarFeature(name); err != nil {
+			return err
+		}
+	}
+	regressor, err := NewCalendarRegressor(c, features...)
+	if err != nil {
+		return err
+	}
+	*r = *regressor
+	return nil
+}
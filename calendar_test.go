@@ -0,0 +1,335 @@
+package ar
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestCalendarTimestamp(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+	date := func(y int, m time.Month, d, h int, loc *time.Location) time.Time {
+		return time.Date(y, m, d, h, 0, 0, 0, loc)
+	}
+	// 2024-03-29 is Good Friday, 2024-03-30 a Saturday and 2024-04-01 Easter Monday.
+	holidays := []time.Time{date(2024, 3, 29, 0, time.UTC), date(2024, 3, 30, 0, time.UTC), date(2024, 4, 1, 0, time.UTC)}
+
+	tests := []struct {
+		name      string
+		start     time.Time
+		frequency Frequency
+		holidays  []time.Time
+		step      int
+		want      time.Time
+	}{
+		{"Minute", date(2024, 1, 1, 0, time.UTC), MinuteFrequency, nil, 90, time.Date(2024, 1, 1, 1, 30, 0, 0, time.UTC)},
+		{"Hourly across DST", date(2024, 3, 9, 12, newYork), HourlyFrequency, nil, 24, date(2024, 3, 10, 13, newYork)},
+		{"Daily across DST", date(2024, 3, 9, 12, newYork), DailyFrequency, nil, 2, date(2024, 3, 11, 12, newYork)},
+		{"Daily backwards", date(2024, 3, 1, 9, time.UTC), DailyFrequency, nil, -1, date(2024, 2, 29, 9, time.UTC)},
+		{"Business day over weekend", date(2024, 3, 22, 9, time.UTC), BusinessDayFrequency, nil, 1, date(2024, 3, 25, 9, time.UTC)},
+		{"Business day weeks", date(2024, 3, 4, 9, time.UTC), BusinessDayFrequency, nil, 12, date(2024, 3, 20, 9, time.UTC)},
+		{"Business day over holidays", date(2024, 3, 28, 9, time.UTC), BusinessDayFrequency, holidays, 1, date(2024, 4, 2, 9, time.UTC)},
+		{"Business day backwards over holidays", date(2024, 4, 2, 9, time.UTC), BusinessDayFrequency, holidays, -1, date(2024, 3, 28, 9, time.UTC)},
+		{"Business day backwards", date(2024, 3, 25, 9, time.UTC), BusinessDayFrequency, nil, -6, date(2024, 3, 15, 9, time.UTC)},
+		{"Monthly clamps the day", date(2024, 1, 31, 0, time.UTC), MonthlyFrequency, nil, 1, date(2024, 2, 29, 0, time.UTC)},
+		{"Monthly keeps the anchor", date(2024, 1, 31, 0, time.UTC), MonthlyFrequency, nil, 2, date(2024, 3, 31, 0, time.UTC)},
+		{"Monthly backwards", date(2024, 3, 31, 0, time.UTC), MonthlyFrequency, nil, -13, date(2023, 2, 28, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCalendar(tt.start, tt.frequency, tt.holidays)
+			if err != nil {
+				t.Fatalf("NewCalendar() error = %v", err)
+			}
+			if got := c.Timestamp(tt.step); !got.Equal(tt.want) {
+				t.Errorf("Timestamp(%d) = %v, want %v", tt.step, got, tt.want)
+			}
+			step, err := c.Step(tt.want)
+			if err != nil {
+				t.Fatalf("Step() error = %v", err)
+			}
+			if step != tt.step {
+				t.Errorf("Step(%v) = %d, want %d", tt.want, step, tt.step)
+			}
+		})
+	}
+}
+
+func TestCalendarBusinessDaysAreConsecutive(t *testing.T) {
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	holidays := []time.Time{time.Date(2024, 5, 27, 0, 0, 0, 0, time.UTC), time.Date(2024, 7, 4, 0, 0, 0, 0, time.UTC), time.Date(2024, 12, 25, 0, 0, 0, 0, time.UTC)}
+	c, err := NewCalendar(start, BusinessDayFrequency, holidays)
+	if err != nil {
+		t.Fatalf("NewCalendar() error = %v", err)
+	}
+
+	// Walking day by day must visit the same business days as Timestamp.
+	step := 0
+	for day := start; day.Year() == 2024; day = day.AddDate(0, 0, 1) {
+		if isWeekend(day.Weekday()) || c.IsHoliday(day) {
+			continue
+		}
+		if got := c.Timestamp(step); !got.Equal(day) {
+			t.Fatalf("Timestamp(%d) = %v, want %v", step, got, day)
+		}
+		step++
+	}
+	// 2024 has 262 weekdays, one before the start and three holidays.
+	if want := 262 - 1 - 3; step != want {
+		t.Errorf("2024 has %d business days, want %d", step, want)
+	}
+}
+
+func TestCalendarErrors(t *testing.T) {
+	saturday := time.Date(2024, 3, 23, 0, 0, 0, 0, time.UTC)
+	if _, err := NewCalendar(saturday, BusinessDayFrequency, nil); err == nil {
+		t.Error("NewCalendar() on a Saturday returned no error")
+	}
+	if _, err := NewCalendar(saturday, Frequency(9), nil); err == nil {
+		t.Error("NewCalendar() with an unknown frequency returned no error")
+	}
+
+	c, err := NewCalendar(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), DailyFrequency, nil)
+	if err != nil {
+		t.Fatalf("NewCalendar() error = %v", err)
+	}
+	if _, err := c.Step(time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)); err == nil {
+		t.Error("Step() off the calendar returned no error")
+	}
+	gap := []time.Time{c.Timestamp(0), c.Timestamp(2)}
+	if _, err := c.Data(gap, []float64{1, 2}); err == nil || !strings.Contains(err.Error(), "expected the next calendar timestamp") {
+		t.Errorf("Data() with a gap error = %v, want a missing timestamp error", err)
+	}
+	if _, err := c.Data(gap, []float64{1}); err == nil {
+		t.Error("Data() with mismatched lengths returned no error")
+	}
+	if _, err := NewCalendarRegressor(c); err == nil {
+		t.Error("NewCalendarRegressor() without features returned no error")
+	}
+	if _, err := NewCalendarRegressor(c, CalendarFeature(9)); err == nil {
+		t.Error("NewCalendarRegressor() with an unknown feature returned no error")
+	}
+}
+
+func TestCalendarRegressorJSON(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+	// A start parsed with a numeric offset has a location without a name.
+	offset, err := time.Parse(time.RFC3339, "2024-01-01T00:30:00+02:00")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	tests := []struct {
+		name  string
+		start time.Time
+	}{
+		{"UTC", time.Date(2024, 1, 1, 0, 30, 0, 0, time.UTC)},
+		{"Named location", time.Date(2024, 1, 1, 0, 30, 0, 0, newYork)},
+		{"Numeric offset", offset},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCalendar(tt.start, HourlyFrequency, []time.Time{tt.start})
+			if err != nil {
+				t.Fatalf("NewCalendar() error = %v", err)
+			}
+			r, err := NewCalendarRegressor(c, DayOfWeekFeature, HolidayFeature, MonthFeature, HourFeature)
+			if err != nil {
+				t.Fatalf("NewCalendarRegressor() error = %v", err)
+			}
+			data, err := json.Marshal(r)
+			if err != nil {
+				t.Fatalf("MarshalJSON() error = %v", err)
+			}
+			var decoded CalendarRegressor
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("UnmarshalJSON() error = %v", err)
+			}
+			if got := decoded.Calendar.Timestamp(0); !got.Equal(tt.start) || got.String() != tt.start.String() {
+				t.Errorf("decoded start = %v, want %v", got, tt.start)
+			}
+			for _, step := range []float64{0, 30} {
+				want := make([]float64, len(r.Names()))
+				got := make([]float64, len(want))
+				r.Evaluate(step, want)
+				decoded.Evaluate(step, got)
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("decoded Evaluate(%v) = %v, want %v", step, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCalendarDataAndFuture(t *testing.T) {
+	c, err := NewCalendar(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), MonthlyFrequency, nil)
+	if err != nil {
+		t.Fatalf("NewCalendar() error = %v", err)
+	}
+	timestamps := []time.Time{c.Timestamp(3), c.Timestamp(4), c.Timestamp(5)}
+	data, err := c.Data(timestamps, []float64{10, 11, 12})
+	if err != nil {
+		t.Fatalf("Data() error = %v", err)
+	}
+	if want := [][]float64{{10, 3}, {11, 4}, {12, 5}}; !reflect.DeepEqual(data, want) {
+		t.Errorf("Data() = %v, want %v", data, want)
+	}
+
+	future, err := c.Future(timestamps[2], 2)
+	if err != nil {
+		t.Fatalf("Future() error = %v", err)
+	}
+	want := []time.Time{time.Date(2024, 7, 31, 0, 0, 0, 0, time.UTC), time.Date(2024, 8, 31, 0, 0, 0, 0, time.UTC)}
+	if !reflect.DeepEqual(future, want) {
+		t.Errorf("Future() = %v, want %v", future, want)
+	}
+	if got := c.Timestamps([][]float64{{6, 1.5}, {7, 2.5}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Timestamps() = %v, want %v", got, want)
+	}
+}
+
+func TestCalendarFeatures(t *testing.T) {
+	christmas := time.Date(2024, 12, 25, 0, 0, 0, 0, time.UTC)
+	c, err := NewCalendar(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), HourlyFrequency, []time.Time{christmas})
+	if err != nil {
+		t.Fatalf("NewCalendar() error = %v", err)
+	}
+	features := []CalendarFeature{DayOfWeekFeature, WeekendFeature, HolidayFeature, MonthFeature, HourFeature}
+	names := CalendarFeatureNames(features)
+	if len(names) != 6+1+1+11+23 || names[0] != "dow_tue" || names[5] != "dow_sun" || names[8] != "month_feb" || names[19] != "hour_1" {
+		t.Fatalf("CalendarFeatureNames() = %v", names)
+	}
+
+	// Christmas 2024 at 15:00 is a Wednesday in December, Monday 2024-01-01 at midnight the baseline.
+	rows := c.Features([]time.Time{christmas.Add(15 * time.Hour), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}, features)
+	set := func(row []float64) []string {
+		var on []string
+		for i, v := range row {
+			if v != 0 {
+				on = append(on, names[i])
+			}
+		}
+		return on
+	}
+	if got, want := set(rows[0]), []string{"dow_wed", "holiday", "month_dec", "hour_15"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Features() of Christmas = %v, want %v", got, want)
+	}
+	if got := set(rows[1]); got != nil {
+		t.Errorf("Features() of the baseline = %v, want none", got)
+	}
+
+	for f := DayOfWeekFeature; f <= HourFeature; f++ {
+		if parsed, err := ParseCalendarFeature(f.String()); err != nil || parsed != f {
+			t.Errorf("ParseCalendarFeature(%q) = %v, %v", f.String(), parsed, err)
+		}
+	}
+	for f := MinuteFrequency; f <= MonthlyFrequency; f++ {
+		if parsed, err := ParseFrequency(f.String()); err != nil || parsed != f {
+			t.Errorf("ParseFrequency(%q) = %v, %v", f.String(), parsed, err)
+		}
+	}
+}
+
+// weekendCalendarData returns n daily rows of an AR(1) series that rises by 5 on weekends, on a
+// calendar starting on Monday 2024-01-01, and the weekend regressor of the calendar.
+func weekendCalendarData(t *testing.T, n int) ([][]float64, *CalendarRegressor) {
+	t.Helper()
+	c, err := NewCalendar(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), DailyFrequency, []time.Time{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatalf("NewCalendar() error = %v", err)
+	}
+	weekend, err := NewCalendarRegressor(c, WeekendFeature)
+	if err != nil {
+		t.Fatalf("NewCalendarRegressor() error = %v", err)
+	}
+
+	rnd := rand.New(rand.NewSource(4))
+	timestamps := make([]time.Time, n)
+	values := make([]float64, n)
+	for i := range values {
+		timestamps[i] = c.Timestamp(i)
+		values[i] = 20 + 0.1*rnd.NormFloat64()
+		if i > 0 {
+			values[i] = 10 + 0.5*values[i-1] + 0.1*rnd.NormFloat64()
+		}
+		if isWeekend(timestamps[i].Weekday()) {
+			values[i] += 5
+		}
+	}
+	data, err := c.Data(timestamps, values)
+	if err != nil {
+		t.Fatalf("Data() error = %v", err)
+	}
+	return data, weekend
+}
+
+func TestLSARXCalendarRegressor(t *testing.T) {
+	data, weekend := weekendCalendarData(t, 117)
+	predictor, err := NewLSARXPredictor(data, LSARXModelParameters{
+		AutoregressiveLags: 1,
+		ExternalInputLags:  1,
+		Intercept:          true,
+		StepSize:           1,
+		Regressors:         []Regressor{weekend},
+		OutputMode:         ForecastOnlyOutput,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	model, err := predictor.Fit(0)
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	summary := model.Summary()
+	last := summary.Coefficients[len(summary.Coefficients)-1]
+	if last.Label != "weekend" || !approxEqual(last.Estimate, 5, 0.05) {
+		t.Errorf("weekend coefficient = %+v, want about 5", last)
+	}
+
+	// The data ends on Friday 2024-04-26 near the weekday mean of 20: the forecast rises by the
+	// weekend effect on Saturday and Sunday and decays on Monday.
+	predicted, err := predictor.Predict(3)
+	if err != nil {
+		t.Fatalf("Predict() error = %v", err)
+	}
+	timestamps := weekend.Calendar.Timestamps(predicted)
+	if timestamps[0].Weekday() != time.Saturday {
+		t.Fatalf("first forecast is on %v, want Saturday", timestamps[0].Weekday())
+	}
+	sat, sun, mon := predicted[0][1], predicted[1][1], predicted[2][1]
+	if !approxEqual(sat, 25, 0.02) || !approxEqual(sun, 27.5, 0.02) || !approxEqual(mon, 23.75, 0.02) {
+		t.Errorf("forecast of the weekend and Monday = %v, %v, %v, want about 25, 27.5 and 23.75", sat, sun, mon)
+	}
+
+	// The fit on the accumulated stream matches the batch fit.
+	acc, err := NewLSARXAccumulator(predictor.Params)
+	if err != nil {
+		t.Fatalf("NewLSARXAccumulator() error = %v", err)
+	}
+	if err := acc.Add(data); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	streamed, err := acc.Fit()
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+	for i := range model.Theta {
+		if !approxEqual(streamed.Theta[i], model.Theta[i], 1e-9) {
+			t.Errorf("streamed Theta[%d] = %v, want %v", i, streamed.Theta[i], model.Theta[i])
+		}
+	}
+
+	if _, err := model.StateSpace(); err == nil {
+		t.Error("StateSpace() of a model with regressors returned no error")
+	}
+}
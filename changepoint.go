@@ -0,0 +1,371 @@
+package ar
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// ChangePointMethod is the search method of the change-point detection.
+type ChangePointMethod int
+
+const (
+	// PELT finds the optimal segmentation under the penalized cost by dynamic programming,
+	// pruning the candidates that cannot be optimal (Killick et al., 2012).
+	PELT ChangePointMethod = iota
+	// BinarySegmentation repeatedly splits the segment whose best split lowers the cost the most,
+	// while that lowers it by more than the penalty.
+	BinarySegmentation
+)
+
+// String returns the name of the change-point method.
+func (m ChangePointMethod) String() string {
+	switch m {
+	case PELT:
+		return "pelt"
+	case BinarySegmentation:
+		return "binseg"
+	default:
+		return fmt.Sprintf("ChangePointMethod(%d)", int(m))
+	}
+}
+
+// ParseChangePointMethod returns the change-point method with the given name, as returned by String.
+func ParseChangePointMethod(name string) (ChangePointMethod, error) {
+	for m := PELT; m <= BinarySegmentation; m++ {
+		if m.String() == name {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown change-point method: %q", name)
+}
+
+// ChangePointCost is the segment cost of the change-point detection: the change it detects.
+type ChangePointCost int
+
+const (
+	// MeanCost detects changes of the mean of normal values with a constant variance.
+	MeanCost ChangePointCost = iota
+	// VarianceCost detects changes of the mean and the variance of normal values.
+	VarianceCost
+	// RegressionCost detects changes of the coefficients and the noise variance of a regression,
+	// the one-step-ahead model of an LSARXPredictor.
+	RegressionCost
+)
+
+// String returns the name of the change-point cost.
+func (c ChangePointCost) String() string {
+	switch c {
+	case MeanCost:
+		return "mean"
+	case VarianceCost:
+		return "variance"
+	case RegressionCost:
+		return "regression"
+	default:
+		return fmt.Sprintf("ChangePointCost(%d)", int(c))
+	}
+}
+
+// ParseChangePointCost returns the change-point cost with the given name, as returned by String.
+func ParseChangePointCost(name string) (ChangePointCost, error) {
+	for c := MeanCost; c <= RegressionCost; c++ {
+		if c.String() == name {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown change-point cost: %q", name)
+}
+
+// ChangePointParameters holds the configuration of the change-point detection.
+type ChangePointParameters struct {
+	Method           ChangePointMethod // Search method, PELT by default.
+	Cost             ChangePointCost   // Segment cost, a change of the mean by default.
+	Penalty          float64           // Penalty per change point; 0 for the BIC penalty, (k+1) ln(n) for k parameters per segment.
+	MinSegmentLength int               // Minimum number of values between change points; 0 for the smallest length the cost can fit.
+	MaxChangePoints  int               // Maximum number of change points of BinarySegmentation, 0 for no limit.
+}
+
+// segmentCost is twice the negative Gaussian log-likelihood, up to a constant, of the values
+// [start, end) fitted with their own parameters.
+type segmentCost interface {
+	cost(start int, end int) float64
+	numParams() int // Number of parameters fitted per segment.
+	len() int       // Number of values.
+}
+
+// meanCost is the cost of a segment around its mean, with the variance of the whole series
+// estimated from its first differences so that the changes do not inflate it.
+type meanCost struct {
+	sum, sumSq []float64 // Cumulative sums of the values and their squares.
+	variance   float64
+}
+
+// newMeanCost returns the mean cost of the values.
+func newMeanCost(values []float64) *meanCost {
+	c := &meanCost{sum: make([]float64, len(values)+1), sumSq: make([]float64, len(values)+1)}
+	for i, v := range values {
+		c.sum[i+1] = c.sum[i] + v
+		c.sumSq[i+1] = c.sumSq[i] + v*v
+	}
+	if len(values) > 2 {
+		diffs := make([]float64, len(values)-1)
+		for i := range diffs {
+			diffs[i] = values[i+1] - values[i]
+		}
+		_, mad := medianAbsoluteDeviation(diffs)
+		c.variance = math.Pow(1.4826*mad, 2) / 2
+	}
+	if !(c.variance > 0) {
+		c.variance = math.Max(c.sse(0, len(values))/float64(len(values)), 1)
+	}
+	return c
+}
+
+// sse returns the sum of squares of the values [start, end) around their mean.
+func (c *meanCost) sse(start int, end int) float64 {
+	n := float64(end - start)
+	s := c.sum[end] - c.sum[start]
+	return math.Max(c.sumSq[end]-c.sumSq[start]-s*s/n, 0)
+}
+
+func (c *meanCost) cost(start int, end int) float64 { return c.sse(start, end) / c.variance }
+func (c *meanCost) numParams() int                  { return 1 }
+func (c *meanCost) len() int                        { return len(c.sum) - 1 }
+
+// varianceCost is the cost of a segment with its own mean and variance.
+type varianceCost struct {
+	*meanCost
+	minVariance float64 // Floor of the segment variances, so that constant segments have a finite cost.
+}
+
+// newVarianceCost returns the mean and variance cost of the values.
+func newVarianceCost(values []float64) *varianceCost {
+	c := &varianceCost{meanCost: newMeanCost(values)}
+	c.minVariance = 1e-12 * math.Max(c.sse(0, len(values))/float64(len(values)), 1e-300)
+	return c
+}
+
+func (c *varianceCost) cost(start int, end int) float64 {
+	n := float64(end - start)
+	return n * math.Log(math.Max(c.sse(start, end)/n, c.minVariance))
+}
+func (c *varianceCost) numParams() int { return 2 }
+
+// regressionCost is the cost of a segment of the regression y = X b + e with its own
+// coefficients and noise variance.
+type regressionCost struct {
+	xtx         []*mat.SymDense // Cumulative X'X.
+	xty         []*mat.VecDense // Cumulative X'y.
+	yty         []float64       // Cumulative y'y.
+	minVariance float64
+}
+
+// newRegressionCost returns the regression cost of the rows of X and y.
+func newRegressionCost(X *mat.Dense, y []float64) *regressionCost {
+	n, k := X.Dims()
+	c := &regressionCost{xtx: make([]*mat.SymDense, n+1), xty: make([]*mat.VecDense, n+1), yty: make([]float64, n+1)}
+	c.xtx[0], c.xty[0] = mat.NewSymDense(k, nil), mat.NewVecDense(k, nil)
+	mean := 0.0
+	for i := 0; i < n; i++ {
+		row := X.RawRowView(i)
+		c.xtx[i+1] = mat.NewSymDense(k, nil)
+		c.xtx[i+1].SymRankOne(c.xtx[i], 1, mat.NewVecDense(k, row))
+		c.xty[i+1] = mat.NewVecDense(k, nil)
+		c.xty[i+1].AddScaledVec(c.xty[i], y[i], mat.NewVecDense(k, row))
+		c.yty[i+1] = c.yty[i] + y[i]*y[i]
+		mean += y[i] / float64(n)
+	}
+	c.minVariance = 1e-12 * math.Max(c.yty[n]/float64(n)-mean*mean, 1e-300)
+	return c
+}
+
+func (c *regressionCost) cost(start int, end int) float64 {
+	var xtx mat.Dense
+	xtx.Sub(c.xtx[end], c.xtx[start])
+	var xty mat.VecDense
+	xty.SubVec(c.xty[end], c.xty[start])
+
+	var b mat.VecDense
+	b.MulVec(pseudoInverse(&xtx), &xty)
+	sse := c.yty[end] - c.yty[start] - mat.Dot(&b, &xty)
+	n := float64(end - start)
+	return n * math.Log(math.Max(sse/n, c.minVariance))
+}
+func (c *regressionCost) numParams() int { return c.xty[0].Len() + 1 }
+func (c *regressionCost) len() int       { return len(c.yty) - 1 }
+
+// DetectChangePoints returns the change points of the values: the indices that start a new
+// segment, in increasing order. The mean and variance costs apply to the values directly; the
+// regression cost needs the regressors of LSARXPredictor.ChangePoints.
+func DetectChangePoints(values []float64, params ChangePointParameters) ([]int, error) {
+	var c segmentCost
+	switch params.Cost {
+	case MeanCost:
+		c = newMeanCost(values)
+	case VarianceCost:
+		c = newVarianceCost(values)
+	case RegressionCost:
+		return nil, fmt.Errorf("the regression cost needs regressors, use LSARXPredictor.ChangePoints")
+	default:
+		return nil, fmt.Errorf("unknown change-point cost: %d", params.Cost)
+	}
+	return detectChangePoints(c, params)
+}
+
+// detectChangePoints segments the values of the cost with the configured method.
+func detectChangePoints(c segmentCost, params ChangePointParameters) ([]int, error) {
+	if params.Penalty < 0 || params.MinSegmentLength < 0 || params.MaxChangePoints < 0 {
+		return nil, fmt.Errorf("change-point parameters must not be negative, penalty: %v, min segment length: %d, max change points: %d", params.Penalty, params.MinSegmentLength, params.MaxChangePoints)
+	}
+	n := c.len()
+	penalty := params.Penalty
+	if penalty == 0 {
+		penalty = float64(c.numParams()+1) * math.Log(float64(n))
+	}
+	minLen := params.MinSegmentLength
+	if minLen == 0 {
+		// A segment needs more values than parameters for a positive variance.
+		minLen = c.numParams() + 1
+	}
+	if n < minLen {
+		return nil, fmt.Errorf("not enough data points for change-point detection, need at least %d points", minLen)
+	}
+
+	switch params.Method {
+	case PELT:
+		return pelt(c, penalty, minLen), nil
+	case BinarySegmentation:
+		return binarySegmentation(c, penalty, minLen, params.MaxChangePoints), nil
+	default:
+		return nil, fmt.Errorf("unknown change-point method: %d", params.Method)
+	}
+}
+
+// pelt returns the change points minimizing the total cost plus penalty per change point.
+func pelt(c segmentCost, penalty float64, minLen int) []int {
+	n := c.len()
+	// best[t] is the minimal penalized cost of the values [0, t), last[t] its last change point.
+	best := make([]float64, n+1)
+	last := make([]int, n+1)
+	best[0] = -penalty
+	candidates := []int{0}
+	for t := minLen; t <= n; t++ {
+		if s := t - minLen; s >= minLen {
+			candidates = append(candidates, s)
+		}
+		costs := make([]float64, len(candidates))
+		best[t] = math.Inf(1)
+		for i, s := range candidates {
+			costs[i] = best[s] + c.cost(s, t)
+			if v := costs[i] + penalty; v < best[t] {
+				best[t], last[t] = v, s
+			}
+		}
+		// A candidate that is already worse than the best segmentation never becomes optimal.
+		kept := candidates[:0]
+		for i, s := range candidates {
+			if costs[i] <= best[t] {
+				kept = append(kept, s)
+			}
+		}
+		candidates = kept
+	}
+
+	var points []int
+	for t := last[n]; t > 0; t = last[t] {
+		points = append([]int{t}, points...)
+	}
+	return points
+}
+
+// binarySegmentation returns the change points found by splitting the segments greedily.
+func binarySegmentation(c segmentCost, penalty float64, minLen int, maxPoints int) []int {
+	type split struct {
+		start, end, at int
+		gain           float64
+	}
+	bestSplit := func(start, end int) split {
+		sp := split{start: start, end: end, gain: math.Inf(-1)}
+		whole := c.cost(start, end)
+		for s := start + minLen; s <= end-minLen; s++ {
+			if gain := whole - c.cost(start, s) - c.cost(s, end); gain > sp.gain {
+				sp.at, sp.gain = s, gain
+			}
+		}
+		return sp
+	}
+
+	var points []int
+	splits := []split{bestSplit(0, c.len())}
+	for maxPoints == 0 || len(points) < maxPoints {
+		i := 0
+		for j := range splits {
+			if splits[j].gain > splits[i].gain {
+				i = j
+			}
+		}
+		if !(splits[i].gain > penalty) {
+			break
+		}
+		sp := splits[i]
+		points = append(points, sp.at)
+		splits = append(splits[:i], splits[i+1:]...)
+		splits = append(splits, bestSplit(sp.start, sp.at), bestSplit(sp.at, sp.end))
+	}
+	slices.Sort(points)
+	return points
+}
+
+// ChangePoints detects the structural changes of the historical data and returns the indices of
+// the data rows that start a new regime. The regression cost detects changes of the one-step-ahead
+// model; the mean and variance costs detect changes of the data values. A zero MinSegmentLength
+// is the smallest segment the model can be fitted on, max(na, nk+nb-1) lags and one more row than
+// coefficients.
+func (p *LSARXPredictor) ChangePoints(params ChangePointParameters) ([]int, error) {
+	s := p.Params.structure()
+	m := s.maxLag()
+	if params.MinSegmentLength == 0 {
+		params.MinSegmentLength = m + s.numParams() + 1
+	}
+
+	dataValues := make([]float64, len(p.Data))
+	timeValues := make([]float64, len(p.Data))
+	for i, row := range p.Data {
+		dataValues[i], timeValues[i] = row[0], row[1]
+	}
+	if params.Cost != RegressionCost {
+		return DetectChangePoints(dataValues, params)
+	}
+
+	if len(p.Data) <= m {
+		return nil, fmt.Errorf("not enough data points for change-point detection, need at least %d points", m+params.MinSegmentLength)
+	}
+	// Regression row r predicts data row m + r.
+	points, err := detectChangePoints(newRegressionCost(constructPhiMatrix(dataValues, timeValues, s, m), dataValues[m:]), params)
+	if err != nil {
+		return nil, err
+	}
+	for i := range points {
+		points[i] += m
+	}
+	return points, nil
+}
+
+// latestRegime returns the predictor restricted to the data from the last change point detected
+// with Params.LatestRegime, and the change points. The first rows of the regime are the lags of its
+// first prediction, so the fit uses no value of the previous regimes.
+func (p *LSARXPredictor) latestRegime() (*LSARXPredictor, []int, error) {
+	points, err := p.ChangePoints(*p.Params.LatestRegime)
+	if err != nil {
+		return nil, nil, err
+	}
+	regime := &LSARXPredictor{Data: p.Data, Params: p.Params}
+	regime.Params.LatestRegime = nil
+	if len(points) > 0 {
+		regime.Data = p.Data[points[len(points)-1]:]
+	}
+	return regime, points, nil
+}
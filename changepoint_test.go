@@ -0,0 +1,147 @@
+package ar
+
+import (
+	"math/rand"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDetectChangePoints(t *testing.T) {
+	rnd := rand.New(rand.NewSource(4))
+	meanShift := make([]float64, 200)
+	varianceShift := make([]float64, 200)
+	for i := range meanShift {
+		level, scale := 0.0, 1.0
+		if i >= 50 {
+			level = 4
+		}
+		if i >= 120 {
+			level = 1
+		}
+		if i >= 100 {
+			scale = 5
+		}
+		meanShift[i] = level + rnd.NormFloat64()
+		varianceShift[i] = 10 + scale*rnd.NormFloat64()
+	}
+
+	tests := []struct {
+		name   string
+		values []float64
+		params ChangePointParameters
+		want   []int
+	}{
+		{"Mean/PELT", meanShift, ChangePointParameters{Method: PELT, Cost: MeanCost}, []int{50, 120}},
+		{"Mean/BinarySegmentation", meanShift, ChangePointParameters{Method: BinarySegmentation, Cost: MeanCost}, []int{50, 120}},
+		{"Mean/MaxChangePoints", meanShift, ChangePointParameters{Method: BinarySegmentation, Cost: MeanCost, MaxChangePoints: 1}, []int{50}},
+		{"Variance/PELT", varianceShift, ChangePointParameters{Method: PELT, Cost: VarianceCost}, []int{100}},
+		{"Variance/BinarySegmentation", varianceShift, ChangePointParameters{Method: BinarySegmentation, Cost: VarianceCost}, []int{100}},
+		{"Huge penalty", meanShift, ChangePointParameters{Cost: MeanCost, Penalty: 1e9}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectChangePoints(tt.values, tt.params)
+			if err != nil {
+				t.Fatalf("DetectChangePoints() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("DetectChangePoints() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] < tt.want[i]-2 || got[i] > tt.want[i]+2 {
+					t.Errorf("DetectChangePoints() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestDetectChangePointsErrors(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6}
+	tests := []struct {
+		name   string
+		values []float64
+		params ChangePointParameters
+	}{
+		{"Regression cost", values, ChangePointParameters{Cost: RegressionCost}},
+		{"Unknown cost", values, ChangePointParameters{Cost: ChangePointCost(9)}},
+		{"Unknown method", values, ChangePointParameters{Method: ChangePointMethod(9)}},
+		{"Negative penalty", values, ChangePointParameters{Penalty: -1}},
+		{"Not enough data", values, ChangePointParameters{MinSegmentLength: 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DetectChangePoints(tt.values, tt.params); err == nil {
+				t.Error("DetectChangePoints() returned no error")
+			}
+		})
+	}
+}
+
+func TestLSARXChangePoints(t *testing.T) {
+	params := LSARXModelParameters{AutoregressiveLags: 1, ExternalInputLags: 1, Intercept: true, StepSize: 25}
+	predictor, err := NewLSARXPredictor(sampleData, params)
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	// The level shift of the sample data at time 780 is row 32.
+	for _, method := range []ChangePointMethod{PELT, BinarySegmentation} {
+		points, err := predictor.ChangePoints(ChangePointParameters{Method: method, Cost: RegressionCost})
+		if err != nil {
+			t.Fatalf("ChangePoints() error = %v", err)
+		}
+		if !slices.Contains(points, 32) {
+			t.Errorf("%v change points = %v, want the shift at row 32", method, points)
+		}
+	}
+
+	regime := &ChangePointParameters{Cost: RegressionCost}
+	params.LatestRegime = regime
+	predictor, err = NewLSARXPredictor(sampleData, params)
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	model, err := predictor.Fit(0)
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+	points := model.Metadata.ChangePoints
+	if len(points) == 0 || model.Params.LatestRegime != regime {
+		t.Fatalf("Fit() change points = %v and params %+v, want the change points and the parameters", points, model.Params)
+	}
+
+	// The model equals a model fitted on the data from the last change point.
+	last := points[len(points)-1]
+	params.LatestRegime = nil
+	direct, err := NewLSARXPredictor(sampleData[last:], params)
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	want, err := direct.Fit(0)
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+	if !reflect.DeepEqual(model.Theta, want.Theta) || model.Metadata.NumObservations != len(sampleData)-last {
+		t.Errorf("Fit() = %v on %d observations, want %v on %d", model.Theta, model.Metadata.NumObservations, want.Theta, len(sampleData)-last)
+	}
+
+	forecast, err := predictor.Forecast(3)
+	if err != nil {
+		t.Fatalf("Forecast() error = %v", err)
+	}
+	wantForecast, err := direct.Forecast(3)
+	if err != nil {
+		t.Fatalf("Forecast() error = %v", err)
+	}
+	if !reflect.DeepEqual(forecast, wantForecast) {
+		t.Errorf("Forecast() = %+v, want %+v", forecast, wantForecast)
+	}
+
+	summary := model.Summary().String()
+	if !strings.Contains(summary, "Change points: ") || !strings.Contains(summary, "(fitted from row ") {
+		t.Errorf("Summary() = %q, want the change points", summary)
+	}
+}
@@ -0,0 +1,29 @@
+// Command ar-server serves the forecasting HTTP API of package server, storing fitted models in memory.
+//
+// Usage:
+//
+//	ar-server [-addr :8080] [-max-horizon 10000]
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/MagdielCAS/go-autoregression/server"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	maxHorizon := flag.Int("max-horizon", server.DefaultMaxHorizon, "largest forecast horizon and max_horizon of the requests")
+	flag.Parse()
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           server.NewHandler(server.NewMemoryStore(), server.WithMaxHorizon(*maxHorizon)),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("listening on %s", *addr)
+	log.Fatal(srv.ListenAndServe())
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// table holds the columns of a CSV or TSV file.
+type table struct {
+	header []string   // Column names, empty when the file has no header.
+	rows   [][]string // Data rows.
+}
+
+// readTable reads a delimited file, with or without a header row.
+func readTable(r io.Reader, delimiter rune, header bool) (*table, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = delimiter
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+
+	t := &table{rows: records}
+	if header {
+		if len(records) == 0 {
+			return nil, fmt.Errorf("input has no header row")
+		}
+		t.header, t.rows = records[0], records[1:]
+	}
+	if len(t.rows) == 0 {
+		return nil, fmt.Errorf("input has no data rows")
+	}
+	return t, nil
+}
+
+// column returns the index of the column given by name or by 0-based index.
+func (t *table) column(spec string) (int, error) {
+	for i, name := range t.header {
+		if name == spec {
+			return i, nil
+		}
+	}
+	i, err := strconv.Atoi(spec)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("unknown column: %q", spec)
+	}
+	if len(t.rows[0]) <= i {
+		return 0, fmt.Errorf("column %d out of range, input has %d columns", i, len(t.rows[0]))
+	}
+	return i, nil
+}
+
+// floats parses the given column as numbers.
+func (t *table) floats(col int) ([]float64, error) {
+	values := make([]float64, len(t.rows))
+	for i, row := range t.rows {
+		if col >= len(row) {
+			return nil, fmt.Errorf("row %d has %d columns, expected at least %d", i+1, len(row), col+1)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(row[col]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("row %d, column %d: %w", i+1, col, err)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// parseDelimiter returns the field delimiter given by a flag value, "tab" for a tab.
+func parseDelimiter(s string) (rune, error) {
+	switch s {
+	case "tab", `\t`, "\t":
+		return '\t', nil
+	}
+	r := []rune(s)
+	if len(r) != 1 {
+		return 0, fmt.Errorf("delimiter must be a single character or \"tab\", got: %q", s)
+	}
+	return r[0], nil
+}
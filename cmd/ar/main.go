@@ -0,0 +1,673 @@
+// Command ar fits autoregressive models to CSV or TSV files and forecasts from them.
+//
+// Usage:
+//
+//	ar fit [flags] [file]           fit a model and write it as JSON, binary or a summary table
+//	ar forecast [flags] [file]      forecast from a file, or from a fitted model with -load, with
+//	                                intervals for recursive LSARX models
+//	ar backtest [flags] [file]      evaluate the model with a rolling-origin backtest
+//	ar validate [flags] [file]      compare a simulation or k-step-ahead prediction with the data
+//	ar select-order [flags] [file]  rank LSARX lag orders by an information criterion
+//
+// The data is read from the file, or from the standard input when the file is missing or "-".
+// Columns are given by header name or by 0-based index. Results are written to the standard output.
+// Run "ar <command> -h" for the flags of each command.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	ar "github.com/MagdielCAS/go-autoregression"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "ar: %v\n", err)
+		}
+		os.Exit(2)
+	}
+}
+
+const usage = `usage: ar <command> [flags] [file]
+
+commands:
+  fit           fit a model and write it as JSON, binary or a summary table
+  forecast      forecast from a file, or from a fitted model with -load, with intervals for recursive LSARX models
+  backtest      evaluate the model with a rolling-origin backtest
+  validate      compare a simulation or k-step-ahead prediction with the data
+  select-order  rank LSARX lag orders by an information criterion
+`
+
+// run executes the command line args, without the program name.
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return fmt.Errorf("missing command")
+	}
+
+	switch args[0] {
+	case "fit":
+		return runFit(args[1:], stdin, stdout, stderr)
+	case "forecast":
+		return runForecast(args[1:], stdin, stdout, stderr)
+	case "backtest":
+		return runBacktest(args[1:], stdin, stdout, stderr)
+	case "validate":
+		return runValidate(args[1:], stdin, stdout, stderr)
+	case "select-order":
+		return runSelectOrder(args[1:], stdin, stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	default:
+		fmt.Fprint(stderr, usage)
+		return fmt.Errorf("unknown command: %q", args[0])
+	}
+}
+
+// dataOptions holds the flags describing the input file.
+type dataOptions struct {
+	valueCol  string
+	timeCol   string
+	inputCol  string
+	delimiter string
+	noHeader  bool
+}
+
+func (o *dataOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.valueCol, "value-col", "0", "column of the data values, by name or 0-based index")
+	fs.StringVar(&o.timeCol, "time-col", "1", "column of the time values, by name or index; empty to use the row number")
+	fs.StringVar(&o.inputCol, "input-col", "", "column of the LSARX external input, by name or index; defaults to the time column")
+	fs.StringVar(&o.delimiter, "delimiter", "", `field delimiter, a single character or "tab"; defaults to tab for .tsv files and comma otherwise`)
+	fs.BoolVar(&o.noHeader, "no-header", false, "the input has no header row")
+}
+
+// series is the data read from the input: the rows passed to the predictors and the time values
+// the forecasts are reported at.
+type series struct {
+	data     [][]float64 // Each row is [data_value, input_value].
+	times    []float64   // Time value of every row.
+	separate bool        // Whether the input column differs from the time column.
+}
+
+// load reads the series from the file named by args, or from stdin.
+func (o *dataOptions) load(args []string, stdin io.Reader) (*series, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("expected at most one input file, got %d", len(args))
+	}
+
+	r, name := stdin, "-"
+	if len(args) == 1 && args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r, name = f, args[0]
+	}
+
+	delimiter := ','
+	if o.delimiter != "" {
+		d, err := parseDelimiter(o.delimiter)
+		if err != nil {
+			return nil, err
+		}
+		delimiter = d
+	} else if filepath.Ext(name) == ".tsv" {
+		delimiter = '\t'
+	}
+
+	t, err := readTable(r, delimiter, !o.noHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	valueCol, err := t.column(o.valueCol)
+	if err != nil {
+		return nil, err
+	}
+	values, err := t.floats(valueCol)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &series{data: make([][]float64, len(values))}
+	if o.timeCol == "" {
+		s.times = make([]float64, len(values))
+		for i := range s.times {
+			s.times[i] = float64(i)
+		}
+	} else {
+		timeCol, err := t.column(o.timeCol)
+		if err != nil {
+			return nil, err
+		}
+		if s.times, err = t.floats(timeCol); err != nil {
+			return nil, err
+		}
+	}
+
+	inputs := s.times
+	if o.inputCol != "" {
+		inputCol, err := t.column(o.inputCol)
+		if err != nil {
+			return nil, err
+		}
+		if inputs, err = t.floats(inputCol); err != nil {
+			return nil, err
+		}
+		s.separate = true
+	}
+
+	for i := range values {
+		s.data[i] = []float64{values[i], inputs[i]}
+	}
+	return s, nil
+}
+
+// meanStep returns the mean difference between consecutive values.
+func meanStep(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	return (values[len(values)-1] - values[0]) / float64(len(values)-1)
+}
+
+// forecastTimes returns the time values of the forecast rows. With a separate input column the
+// predictors extrapolate the input, so the times are extrapolated from the time column instead.
+func (s *series) forecastTimes(forecast [][]float64) []float64 {
+	times := make([]float64, len(forecast))
+	step := meanStep(s.times)
+	for i, row := range forecast {
+		times[i] = row[0]
+		if s.separate {
+			times[i] = s.times[len(s.times)-1] + float64(i+1)*step
+		}
+	}
+	return times
+}
+
+// modelOptions holds the flags configuring the model.
+type modelOptions struct {
+	model     string
+	na        int
+	nb        int
+	nk        int
+	intercept bool
+	step      float64
+	strategy  string
+	regime    string
+}
+
+func (o *modelOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.model, "model", "lsarx", `model to fit, "ls" or "lsarx"`)
+	fs.IntVar(&o.na, "na", 3, "LSARX autoregressive lags")
+	fs.IntVar(&o.nb, "nb", 1, "LSARX external input terms; the lags of regularly spaced time values are collinear, so set -input-col for more than one")
+	fs.IntVar(&o.nk, "nk", 0, "LSARX input delay (dead time)")
+	fs.BoolVar(&o.intercept, "intercept", false, "add a constant term to the LSARX model")
+	fs.Float64Var(&o.step, "step", 0, "step size of the input values; defaults to their mean step")
+	fs.StringVar(&o.strategy, "strategy", "recursive", `LSARX multi-step strategy, "recursive", "direct" or "dirrec"`)
+	fs.StringVar(&o.regime, "latest-regime", "", `fit LSARX only on the latest regime, detected by PELT with the change-point cost "mean", "variance" or "regression"`)
+}
+
+// predictor is implemented by both predictors of the package.
+type predictor interface {
+	ar.Forecaster
+	Forecast(numToPredict int) (*ar.ForecastResult, error)
+}
+
+// build creates the configured predictor for the given data.
+func (o *modelOptions) build(data [][]float64) (predictor, error) {
+	step := o.step
+	if step == 0 {
+		inputs := make([]float64, len(data))
+		for i, row := range data {
+			inputs[i] = row[1]
+		}
+		step = meanStep(inputs)
+	}
+
+	switch o.model {
+	case "ls":
+		return ar.NewLSPredictor(data, ar.LSModelParameters{StepSize: step})
+	case "lsarx":
+		strategy, err := ar.ParsePredictionStrategy(o.strategy)
+		if err != nil {
+			return nil, err
+		}
+		var latestRegime *ar.ChangePointParameters
+		if o.regime != "" {
+			cost, err := ar.ParseChangePointCost(o.regime)
+			if err != nil {
+				return nil, err
+			}
+			latestRegime = &ar.ChangePointParameters{Method: ar.PELT, Cost: cost}
+		}
+		return ar.NewLSARXPredictor(data, ar.LSARXModelParameters{
+			AutoregressiveLags: o.na,
+			ExternalInputLags:  o.nb,
+			InputDelay:         o.nk,
+			Intercept:          o.intercept,
+			StepSize:           step,
+			Strategy:           strategy,
+			LatestRegime:       latestRegime,
+		})
+	default:
+		return nil, fmt.Errorf("unknown model: %q", o.model)
+	}
+}
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: ar %s [flags] [file]\n\nflags:\n", name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func checkFormat(format string, allowed ...string) error {
+	for _, f := range allowed {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown format: %q", format)
+}
+
+func runFit(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("fit", stderr)
+	var data dataOptions
+	var model modelOptions
+	data.register(fs)
+	model.register(fs)
+	horizon := fs.Int("horizon", 1, "max horizon of the direct strategies")
+	format := fs.String("format", "json", `output format, "json", "binary" or "summary" for the regression table`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format, "json", "binary", "summary"); err != nil {
+		return err
+	}
+
+	s, err := data.load(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	p, err := model.build(s.data)
+	if err != nil {
+		return err
+	}
+
+	fitted, err := fit(p, *horizon)
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	switch *format {
+	case "summary":
+		out = []byte(fitted.(interface{ Summary() *ar.FitSummary }).Summary().String())
+	case "binary":
+		out, err = fitted.(interface{ MarshalBinary() ([]byte, error) }).MarshalBinary()
+	default:
+		out, err = json.MarshalIndent(fitted, "", "  ")
+		out = append(out, '\n')
+	}
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(out)
+	return err
+}
+
+// fit fits the model of the predictor, with the direct strategies fitted up to the horizon.
+func fit(p predictor, horizon int) (ar.Forecaster, error) {
+	switch p := p.(type) {
+	case *ar.LSPredictor:
+		return p.Fit()
+	case *ar.LSARXPredictor:
+		return p.Fit(horizon)
+	}
+	return nil, fmt.Errorf("unknown predictor %T", p)
+}
+
+// loadModel reads a fitted model written by the fit command, in either format.
+func loadModel(path string) (ar.Forecaster, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > 0 && b[0] == '{' {
+		return ar.LoadModelJSON(b)
+	}
+	return ar.LoadModelBinary(b)
+}
+
+// forecastRow is a forecast value in the JSON output, with its interval when available.
+type forecastRow struct {
+	Time  float64  `json:"time"`
+	Value float64  `json:"value"`
+	Lower *float64 `json:"lower,omitempty"`
+	Upper *float64 `json:"upper,omitempty"`
+}
+
+// forecastVariances returns the variances of the forecast values of a fitted model, or nil when
+// the model has none: they come from the state-space form of a recursive LSARX model without
+// deterministic regressors, whose forecast is that of PredictWithVariance.
+func forecastVariances(m ar.Forecaster, horizon int) ([]float64, error) {
+	lsarx, ok := m.(*ar.LSARXModel)
+	if !ok || lsarx.Params.Strategy != ar.RecursiveStrategy || len(lsarx.Params.Regressors) > 0 {
+		return nil, nil
+	}
+	_, variances, err := lsarx.PredictWithVariance(horizon)
+	return variances, err
+}
+
+func runForecast(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("forecast", stderr)
+	var data dataOptions
+	var model modelOptions
+	data.register(fs)
+	model.register(fs)
+	horizon := fs.Int("horizon", 10, "number of values to forecast")
+	load := fs.String("load", "", "fitted model file written by the fit command; no input file is read when set")
+	format := fs.String("format", "csv", `output format, "csv" or "json"`)
+	level := fs.Float64("level", 0.95, "confidence level of the lower and upper forecast bounds, 0 for none; only recursive LSARX models have intervals, other models omit the columns")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format, "csv", "json"); err != nil {
+		return err
+	}
+	if *horizon <= 0 {
+		return fmt.Errorf("horizon must be a positive integer, horizon: %d", *horizon)
+	}
+	if *level < 0 || *level >= 1 {
+		return fmt.Errorf("level must be in [0, 1), level: %v", *level)
+	}
+
+	var m ar.Forecaster
+	var s *series
+	var err error
+	if *load != "" {
+		if m, err = loadModel(*load); err != nil {
+			return err
+		}
+	} else {
+		if s, err = data.load(fs.Args(), stdin); err != nil {
+			return err
+		}
+		p, err := model.build(s.data)
+		if err != nil {
+			return err
+		}
+		if m, err = fit(p, *horizon); err != nil {
+			return err
+		}
+	}
+	forecast, err := m.Predict(*horizon)
+	if err != nil {
+		return err
+	}
+	var times []float64
+	if s != nil {
+		times = s.forecastTimes(forecast)
+	} else {
+		times = make([]float64, len(forecast))
+		for i, row := range forecast {
+			times[i] = row[0]
+		}
+	}
+	var variances []float64
+	if *level > 0 {
+		if variances, err = forecastVariances(m, *horizon); err != nil {
+			return err
+		}
+	}
+
+	z := distuv.UnitNormal.Quantile(1 - (1-*level)/2)
+	rows := make([]forecastRow, len(forecast))
+	for i, row := range forecast {
+		rows[i] = forecastRow{Time: times[i], Value: row[1]}
+		if variances != nil {
+			lower, upper := row[1]-z*math.Sqrt(variances[i]), row[1]+z*math.Sqrt(variances[i])
+			rows[i].Lower, rows[i].Upper = &lower, &upper
+		}
+	}
+	if *format == "json" {
+		return writeJSON(stdout, struct {
+			Forecast []forecastRow `json:"forecast"`
+		}{rows})
+	}
+
+	header := []string{"time", "value"}
+	if variances != nil {
+		header = append(header, "lower", "upper")
+	}
+	records := [][]string{header}
+	for _, row := range rows {
+		record := []string{formatFloat(row.Time), formatFloat(row.Value)}
+		if variances != nil {
+			record = append(record, formatFloat(*row.Lower), formatFloat(*row.Upper))
+		}
+		records = append(records, record)
+	}
+	return writeCSV(stdout, records)
+}
+
+func runBacktest(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("backtest", stderr)
+	var data dataOptions
+	var model modelOptions
+	data.register(fs)
+	model.register(fs)
+	horizon := fs.Int("horizon", 1, "number of values forecast from every origin")
+	initialWindow := fs.Int("initial-window", 0, "number of data points of the first fold; defaults to half the data")
+	originStep := fs.Int("origin-step", 1, "number of data points the origin moves between folds")
+	format := fs.String("format", "csv", `output format, "csv" or "json"`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format, "csv", "json"); err != nil {
+		return err
+	}
+
+	s, err := data.load(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	if *initialWindow == 0 {
+		*initialWindow = len(s.data) / 2
+	}
+
+	result, err := ar.Backtest(s.data, ar.BacktestParameters{
+		Horizon:       *horizon,
+		InitialWindow: *initialWindow,
+		Step:          *originStep,
+	}, func(train [][]float64) (ar.Forecaster, error) {
+		return model.build(train)
+	})
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		return writeJSON(stdout, struct {
+			Folds       int       `json:"folds"`
+			MAE         float64   `json:"mae"`
+			RMSE        float64   `json:"rmse"`
+			HorizonRMSE []float64 `json:"horizon_rmse"`
+		}{len(result.Origins), result.MAE, result.RMSE, result.HorizonRMSE})
+	}
+
+	records := [][]string{{"horizon", "rmse"}}
+	for h, rmse := range result.HorizonRMSE {
+		records = append(records, []string{strconv.Itoa(h + 1), formatFloat(rmse)})
+	}
+	records = append(records, []string{"all", formatFloat(result.RMSE)})
+	if err := writeCSV(stdout, records); err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "folds: %d, MAE: %s, RMSE: %s\n", len(result.Origins), formatFloat(result.MAE), formatFloat(result.RMSE))
+	return nil
+}
+
+func runValidate(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("validate", stderr)
+	var data dataOptions
+	var model modelOptions
+	data.register(fs)
+	model.register(fs)
+	k := fs.Int("k", 0, "prediction horizon; 0 runs a free-run simulation from the inputs alone")
+	load := fs.String("load", "", "fitted LSARX model file written by the fit command, instead of fitting on the input file")
+	format := fs.String("format", "csv", `output format, "csv" or "json"`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format, "csv", "json"); err != nil {
+		return err
+	}
+	if *k < 0 {
+		return fmt.Errorf("prediction horizon must not be negative, k: %d", *k)
+	}
+
+	s, err := data.load(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	var fitted *ar.LSARXModel
+	if *load != "" {
+		m, err := loadModel(*load)
+		if err != nil {
+			return err
+		}
+		var ok bool
+		if fitted, ok = m.(*ar.LSARXModel); !ok {
+			return fmt.Errorf("validate needs an LSARX model, got %T", m)
+		}
+	} else {
+		if model.model != "lsarx" {
+			return fmt.Errorf("validate needs an LSARX model, got: %q", model.model)
+		}
+		p, err := model.build(s.data)
+		if err != nil {
+			return err
+		}
+		if fitted, err = p.(*ar.LSARXPredictor).Fit(max(*k, 1)); err != nil {
+			return err
+		}
+	}
+
+	var result *ar.ValidationResult
+	if *k == 0 {
+		result, err = fitted.Simulate(s.data)
+	} else {
+		result, err = fitted.PredictK(s.data, *k)
+	}
+	if err != nil {
+		return err
+	}
+
+	type row struct {
+		Time     float64 `json:"time"`
+		Measured float64 `json:"measured"`
+		Output   float64 `json:"output"`
+	}
+	rows := make([]row, 0, len(s.data)-result.Start)
+	for i := result.Start; i < len(s.data); i++ {
+		rows = append(rows, row{Time: s.times[i], Measured: s.data[i][0], Output: result.Output[i]})
+	}
+	if *format == "json" {
+		return writeJSON(stdout, struct {
+			Fit  float64 `json:"fit"`
+			Rows []row   `json:"rows"`
+		}{result.Fit, rows})
+	}
+
+	records := [][]string{{"time", "measured", "output"}}
+	for _, r := range rows {
+		records = append(records, []string{formatFloat(r.Time), formatFloat(r.Measured), formatFloat(r.Output)})
+	}
+	if err := writeCSV(stdout, records); err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "fit: %s%%\n", formatFloat(result.Fit))
+	return nil
+}
+
+func runSelectOrder(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("select-order", stderr)
+	var data dataOptions
+	data.register(fs)
+	maxNa := fs.Int("max-na", 5, "largest autoregressive lag order to try")
+	maxNb := fs.Int("max-nb", 5, "largest external input lag order to try")
+	maxNk := fs.Int("max-nk", 0, "largest input delay to try")
+	intercept := fs.Bool("intercept", false, "add a constant term to every candidate")
+	criterion := fs.String("criterion", "bic", `information criterion, "aic", "aicc" or "bic"`)
+	format := fs.String("format", "csv", `output format, "csv" or "json"`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format, "csv", "json"); err != nil {
+		return err
+	}
+	c, err := ar.ParseInformationCriterion(*criterion)
+	if err != nil {
+		return err
+	}
+
+	s, err := data.load(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	result, err := ar.SelectOrder(s.data, ar.OrderSelectionParameters{
+		MaxAutoregressiveLags: *maxNa,
+		MaxExternalInputLags:  *maxNb,
+		MaxInputDelay:         *maxNk,
+		Intercept:             *intercept,
+		Criterion:             c,
+	})
+	if err != nil {
+		return err
+	}
+
+	type score struct {
+		Na    int     `json:"na"`
+		Nb    int     `json:"nb"`
+		Nk    int     `json:"nk"`
+		Score float64 `json:"score"`
+	}
+	var best score
+	scores := make([]score, len(result.Scores))
+	for i, s := range result.Scores {
+		scores[i] = score{Na: s.AutoregressiveLags, Nb: s.ExternalInputLags, Nk: s.InputDelay, Score: s.Score}
+		if s.AutoregressiveLags == result.AutoregressiveLags && s.ExternalInputLags == result.ExternalInputLags && s.InputDelay == result.InputDelay {
+			best = scores[i]
+		}
+	}
+	if *format == "json" {
+		return writeJSON(stdout, struct {
+			Criterion string  `json:"criterion"`
+			Best      score   `json:"best"`
+			Scores    []score `json:"scores"`
+		}{c.String(), best, scores})
+	}
+
+	records := [][]string{{"na", "nb", "nk", c.String(), "best"}}
+	for _, s := range scores {
+		records = append(records, []string{strconv.Itoa(s.Na), strconv.Itoa(s.Nb), strconv.Itoa(s.Nk), formatFloat(s.Score), strconv.FormatBool(s == best)})
+	}
+	return writeCSV(stdout, records)
+}
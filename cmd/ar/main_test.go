@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	ar "github.com/MagdielCAS/go-autoregression"
+)
+
+// writeSampleCSV writes a series with a header row to a temporary file and returns its path.
+func writeSampleCSV(t *testing.T, name string, delimiter string) string {
+	t.Helper()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "value%stime%sload\n", delimiter, delimiter)
+	y := 100.0
+	for i := 0; i < 120; i++ {
+		load := float64(i%7) + 0.1*float64(i%3)
+		y = 0.6*y + 20 + 3*load
+		fmt.Fprintf(&b, "%g%s%d%s%g\n", y, delimiter, 10*i, delimiter, load)
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatalf("failed to write sample: %v", err)
+	}
+	return path
+}
+
+func runCommand(t *testing.T, args ...string) string {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	if err := run(args, strings.NewReader(""), &stdout, &stderr); err != nil {
+		t.Fatalf("run(%v) error = %v, stderr: %s", args, err, stderr.String())
+	}
+	return stdout.String()
+}
+
+func TestForecastCSV(t *testing.T) {
+	path := writeSampleCSV(t, "series.csv", ",")
+
+	out := runCommand(t, "forecast", "-na", "1", "-nb", "2", "-horizon", "4", path)
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+	if len(records) != 5 || records[0][0] != "time" || records[0][1] != "value" {
+		t.Fatalf("forecast output = %q, want a header and 4 rows", out)
+	}
+	if records[1][0] != "1200" || records[4][0] != "1230" {
+		t.Errorf("forecast times = %v .. %v, want 1200 .. 1230", records[1][0], records[4][0])
+	}
+}
+
+func TestForecastDefaults(t *testing.T) {
+	path := writeSampleCSV(t, "series.csv", ",")
+
+	// The input defaults to the time column, at regular steps.
+	out := runCommand(t, "forecast", path)
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+	if len(records) != 11 || len(records[0]) != 4 {
+		t.Fatalf("forecast output = %q, want a header and 10 rows with intervals", out)
+	}
+	for _, record := range records[1:] {
+		// The series stays between 50 and 100.
+		if v, err := strconv.ParseFloat(record[1], 64); err != nil || v < 40 || v > 110 {
+			t.Errorf("forecast row %v has a value out of the range of the data", record)
+		}
+	}
+}
+
+func TestForecastIntervals(t *testing.T) {
+	path := writeSampleCSV(t, "series.csv", ",")
+
+	out := runCommand(t, "forecast", "-na", "1", "-nb", "2", "-horizon", "4", "-level", "0.9", path)
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+	if len(records) != 5 || len(records[0]) != 4 || records[0][2] != "lower" || records[0][3] != "upper" {
+		t.Fatalf("forecast output = %q, want time, value, lower and upper columns", out)
+	}
+	width := 0.0
+	for _, record := range records[1:] {
+		var value, lower, upper float64
+		for j, v := range []*float64{&value, &lower, &upper} {
+			if *v, err = strconv.ParseFloat(record[j+1], 64); err != nil {
+				t.Fatalf("failed to parse %q: %v", record[j+1], err)
+			}
+		}
+		// The intervals widen with the horizon.
+		if !(lower < value && value < upper) || upper-lower < width {
+			t.Errorf("forecast row %v has no widening interval around its value", record)
+		}
+		width = upper - lower
+	}
+
+	// A fitted recursive model gives the same intervals, and models without them omit the columns.
+	model := runCommand(t, "fit", "-na", "1", "-nb", "2", path)
+	modelPath := filepath.Join(t.TempDir(), "model.json")
+	if err := os.WriteFile(modelPath, []byte(model), 0o644); err != nil {
+		t.Fatalf("failed to write model: %v", err)
+	}
+	if fromModel := runCommand(t, "forecast", "-load", modelPath, "-horizon", "4", "-level", "0.9"); fromModel != out {
+		t.Errorf("forecast from model = %q, want %q", fromModel, out)
+	}
+	for _, args := range [][]string{
+		{"forecast", "-na", "1", "-nb", "2", "-horizon", "2", "-level", "0", path},
+		{"forecast", "-model", "ls", "-horizon", "2", path},
+		{"forecast", "-na", "1", "-nb", "2", "-strategy", "direct", "-horizon", "2", path},
+	} {
+		if out := runCommand(t, args...); !strings.HasPrefix(out, "time,value\n") {
+			t.Errorf("run(%v) output = %q, want only time and value columns", args, out)
+		}
+	}
+
+	// The state-space form of a model with deterministic regressors has no intervals either.
+	data := make([][]float64, 60)
+	for i := range data {
+		data[i] = []float64{10 + float64(i%7), float64(i)}
+	}
+	weekly, err := ar.NewFourierRegressor(ar.SeasonalPeriod{Period: 7, Harmonics: 1})
+	if err != nil {
+		t.Fatalf("NewFourierRegressor() error = %v", err)
+	}
+	p, err := ar.NewLSARXPredictor(data, ar.LSARXModelParameters{AutoregressiveLags: 1, ExternalInputLags: 1, StepSize: 1, Regressors: []ar.Regressor{weekly}})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	fitted, err := p.Fit(0)
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+	encoded, err := json.Marshal(fitted)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if err := os.WriteFile(modelPath, encoded, 0o644); err != nil {
+		t.Fatalf("failed to write model: %v", err)
+	}
+	if out := runCommand(t, "forecast", "-load", modelPath, "-horizon", "2"); !strings.HasPrefix(out, "time,value\n") {
+		t.Errorf("forecast of a model with regressors = %q, want only time and value columns", out)
+	}
+}
+
+func TestForecastTSVWithInputColumn(t *testing.T) {
+	path := writeSampleCSV(t, "series.tsv", "\t")
+
+	out := runCommand(t, "forecast", "-value-col", "value", "-input-col", "load", "-na", "1", "-nb", "1", "-horizon", "3", "-format", "json", path)
+	var result struct {
+		Forecast []struct {
+			Time  float64 `json:"time"`
+			Value float64 `json:"value"`
+		} `json:"forecast"`
+	}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatalf("failed to parse output %q: %v", out, err)
+	}
+	if len(result.Forecast) != 3 {
+		t.Fatalf("forecast returned %d values, want 3", len(result.Forecast))
+	}
+	if result.Forecast[0].Time != 1200 {
+		t.Errorf("first forecast time = %f, want 1200", result.Forecast[0].Time)
+	}
+}
+
+func TestFitAndForecastFromModel(t *testing.T) {
+	path := writeSampleCSV(t, "series.csv", ",")
+
+	for _, format := range []string{"json", "binary"} {
+		t.Run(format, func(t *testing.T) {
+			model := runCommand(t, "fit", "-na", "2", "-nb", "2", "-strategy", "direct", "-horizon", "3", "-format", format, path)
+			modelPath := filepath.Join(t.TempDir(), "model")
+			if err := os.WriteFile(modelPath, []byte(model), 0o644); err != nil {
+				t.Fatalf("failed to write model: %v", err)
+			}
+
+			fromModel := runCommand(t, "forecast", "-load", modelPath, "-horizon", "3")
+			fromData := runCommand(t, "forecast", "-na", "2", "-nb", "2", "-strategy", "direct", "-horizon", "3", path)
+			if fromModel != fromData {
+				t.Errorf("forecast from model = %q, want %q", fromModel, fromData)
+			}
+		})
+	}
+}
+
+func TestFitSummary(t *testing.T) {
+	path := writeSampleCSV(t, "series.csv", ",")
+
+	out := runCommand(t, "fit", "-input-col", "load", "-na", "1", "-nb", "1", "-format", "summary", path)
+	for _, want := range []string{"LSARX model: na = 1, nb = 1", "R-squared", "y[t-1]", "u[t]"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("fit summary = %q, want it to contain %q", out, want)
+		}
+	}
+}
+
+func TestBacktestAndSelectOrder(t *testing.T) {
+	path := writeSampleCSV(t, "series.csv", ",")
+
+	out := runCommand(t, "backtest", "-model", "ls", "-horizon", "2", "-initial-window", "100", path)
+	if !strings.HasPrefix(out, "horizon,rmse\n1,") || !strings.Contains(out, "\nall,") {
+		t.Errorf("backtest output = %q", out)
+	}
+
+	out = runCommand(t, "select-order", "-input-col", "load", "-max-na", "2", "-max-nb", "2", "-format", "json", path)
+	var result struct {
+		Best struct {
+			Na int `json:"na"`
+			Nb int `json:"nb"`
+		} `json:"best"`
+		Scores []json.RawMessage `json:"scores"`
+	}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatalf("failed to parse output %q: %v", out, err)
+	}
+	if len(result.Scores) != 6 || result.Best.Na == 0 {
+		t.Errorf("select-order output = %q, want 6 scores and the best order", out)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	path := writeSampleCSV(t, "series.csv", ",")
+
+	out := runCommand(t, "validate", "-input-col", "load", "-na", "1", "-nb", "1", path)
+	if !strings.HasPrefix(out, "time,measured,output\n10,") {
+		t.Errorf("validate output = %q, want a header and rows from time 10", out)
+	}
+
+	out = runCommand(t, "validate", "-input-col", "load", "-na", "1", "-nb", "1", "-intercept", "-k", "2", "-format", "json", path)
+	var result struct {
+		Fit  float64           `json:"fit"`
+		Rows []json.RawMessage `json:"rows"`
+	}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatalf("failed to parse output %q: %v", out, err)
+	}
+	if len(result.Rows) != 119 || result.Fit < 99 {
+		t.Errorf("validate output has %d rows and a fit of %v, want 119 rows and a fit of the noise-free data near 100", len(result.Rows), result.Fit)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	path := writeSampleCSV(t, "series.csv", ",")
+
+	testCases := [][]string{
+		{},
+		{"unknown"},
+		{"forecast", "-model", "arima", path},
+		{"forecast", "-value-col", "missing", path},
+		{"forecast", "-na", "0", path},
+		{"forecast", "-format", "xml", path},
+		{"forecast", "-level", "1", path},
+		{"forecast", "-horizon", "-1", path},
+		{"forecast", "-horizon", "0", path},
+		{"fit", "-strategy", "sideways", path},
+		{"fit", "-latest-regime", "median", path},
+		{"select-order", "-criterion", "hqic", path},
+		{"validate", "-model", "ls", path},
+		{"validate", "-k", "-1", path},
+		{"forecast", filepath.Join(t.TempDir(), "missing.csv")},
+	}
+
+	for _, args := range testCases {
+		var stdout, stderr bytes.Buffer
+		if err := run(args, strings.NewReader(""), &stdout, &stderr); err == nil {
+			t.Errorf("run(%v) expected an error", args)
+		}
+	}
+}
@@ -0,0 +1,434 @@
+package ar
+
+import (
+	"fmt"
+	"math"
+)
+
+// DecompositionMethod selects how Decompose splits a series into trend, seasonal and remainder.
+type DecompositionMethod int
+
+const (
+	// AdditiveDecomposition is the classical decomposition value = trend + seasonal + remainder, with
+	// a centred moving-average trend and the seasonal averages of the detrended values.
+	AdditiveDecomposition DecompositionMethod = iota
+	// MultiplicativeDecomposition is the classical decomposition value = trend * seasonal * remainder.
+	MultiplicativeDecomposition
+	// STLDecomposition is the additive seasonal-trend decomposition by LOESS of Cleveland et al. (1990).
+	STLDecomposition
+)
+
+// String returns the name of the decomposition method.
+func (d DecompositionMethod) String() string {
+	switch d {
+	case AdditiveDecomposition:
+		return "additive"
+	case MultiplicativeDecomposition:
+		return "multiplicative"
+	case STLDecomposition:
+		return "stl"
+	default:
+		return fmt.Sprintf("DecompositionMethod(%d)", int(d))
+	}
+}
+
+// ParseDecompositionMethod returns the decomposition method with the given name, as returned by String.
+func ParseDecompositionMethod(name string) (DecompositionMethod, error) {
+	for d := AdditiveDecomposition; d <= STLDecomposition; d++ {
+		if d.String() == name {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown decomposition method: %q", name)
+}
+
+// DecompositionParameters holds the configuration of Decompose. The windows and iterations only
+// apply to STL, and are numbers of data points, odd and at least 3.
+type DecompositionParameters struct {
+	Method           DecompositionMethod // Method: how the series is decomposed, classical additive by default.
+	Period           int                 // Period: number of data points of a seasonal cycle, at least 2.
+	SeasonalWindow   int                 // SeasonalWindow: LOESS window of the cycle-subseries, in cycles, 7 when zero.
+	TrendWindow      int                 // TrendWindow: LOESS window of the trend, the smallest odd integer above 1.5 Period / (1 - 1.5 / SeasonalWindow) when zero.
+	InnerIterations  int                 // InnerIterations: passes of the STL inner loop, 2 when zero.
+	RobustIterations int                 // RobustIterations: passes of the STL outer loop, which downweight outliers; 0 gives a non-robust fit.
+}
+
+// Decomposition holds the components of a decomposed series, aligned with its values. The
+// classical trend and remainder are NaN for the half cycle at either end of the series.
+type Decomposition struct {
+	Method    DecompositionMethod // Method used.
+	Period    int                 // Number of data points of a seasonal cycle.
+	Trend     []float64           // Trend component.
+	Seasonal  []float64           // Seasonal component, repeating with the period for the classical methods.
+	Remainder []float64           // Remainder component.
+	Weights   []float64           // STL robustness weights of the values, nil for the classical methods and a non-robust STL.
+}
+
+// Decompose splits the values of a series into trend, seasonal and remainder components.
+func Decompose(values []float64, params DecompositionParameters) (*Decomposition, error) {
+	if params.Period < 2 {
+		return nil, fmt.Errorf("period must be at least 2, period: %d", params.Period)
+	}
+	if len(values) < 2*params.Period {
+		return nil, fmt.Errorf("not enough data points for decomposition, need at least %d points", 2*params.Period)
+	}
+	switch params.Method {
+	case AdditiveDecomposition, MultiplicativeDecomposition:
+		if params.Method == MultiplicativeDecomposition {
+			for _, v := range values {
+				if v <= 0 {
+					return nil, fmt.Errorf("multiplicative decomposition needs positive values, got: %v", v)
+				}
+			}
+		}
+		return classicalDecomposition(values, params.Period, params.Method), nil
+	case STLDecomposition:
+		return stl(values, params)
+	default:
+		return nil, fmt.Errorf("unknown decomposition method: %d", params.Method)
+	}
+}
+
+// classicalDecomposition decomposes the values with a centred moving average of the period as the
+// trend, a 2 x period moving average for an even period.
+func classicalDecomposition(values []float64, period int, method DecompositionMethod) *Decomposition {
+	n := len(values)
+	multiplicative := method == MultiplicativeDecomposition
+	d := &Decomposition{
+		Method:    method,
+		Period:    period,
+		Trend:     make([]float64, n),
+		Seasonal:  make([]float64, n),
+		Remainder: make([]float64, n),
+	}
+
+	half := period / 2
+	for t := range d.Trend {
+		if t < half || t+half >= n {
+			d.Trend[t] = math.NaN()
+			continue
+		}
+		sum := 0.0
+		if period%2 == 1 {
+			for j := t - half; j <= t+half; j++ {
+				sum += values[j]
+			}
+			d.Trend[t] = sum / float64(period)
+			continue
+		}
+		for j := t - half + 1; j < t+half; j++ {
+			sum += values[j]
+		}
+		d.Trend[t] = (sum + (values[t-half]+values[t+half])/2) / float64(period)
+	}
+
+	// The seasonal index of every position in the cycle is the average of the detrended values,
+	// normalized to sum to zero (additive) or average one (multiplicative) over a cycle.
+	indices := make([]float64, period)
+	counts := make([]int, period)
+	for t, v := range values {
+		if math.IsNaN(d.Trend[t]) {
+			continue
+		}
+		if multiplicative {
+			indices[t%period] += v / d.Trend[t]
+		} else {
+			indices[t%period] += v - d.Trend[t]
+		}
+		counts[t%period]++
+	}
+	mean := 0.0
+	for k := range indices {
+		indices[k] /= float64(counts[k])
+		mean += indices[k] / float64(period)
+	}
+	for k := range indices {
+		if multiplicative {
+			indices[k] /= mean
+		} else {
+			indices[k] -= mean
+		}
+	}
+
+	for t, v := range values {
+		d.Seasonal[t] = indices[t%period]
+		if multiplicative {
+			d.Remainder[t] = v / (d.Trend[t] * d.Seasonal[t])
+		} else {
+			d.Remainder[t] = v - d.Trend[t] - d.Seasonal[t]
+		}
+	}
+	return d
+}
+
+// stl decomposes the values by the STL procedure: every inner pass smooths the cycle-subseries of
+// the detrended values, removes their low-frequency part to get the seasonal component, and
+// smooths the deseasonalized values to get the trend; every outer pass recomputes the robustness
+// weights of the values from the remainder.
+func stl(values []float64, params DecompositionParameters) (*Decomposition, error) {
+	n, period := len(values), params.Period
+	seasonal := params.SeasonalWindow
+	if seasonal == 0 {
+		seasonal = 7
+	}
+	trend := params.TrendWindow
+	if trend == 0 {
+		trend = nextOdd(1.5 * float64(period) / (1 - 1.5/float64(seasonal)))
+	}
+	lowPass := nextOdd(float64(period))
+	inner := params.InnerIterations
+	if inner == 0 {
+		inner = 2
+	}
+	if seasonal < 3 || seasonal%2 == 0 || trend < 3 || trend%2 == 0 {
+		return nil, fmt.Errorf("windows must be odd integers of at least 3, seasonal window: %d, trend window: %d", seasonal, trend)
+	}
+	if inner < 0 || params.RobustIterations < 0 {
+		return nil, fmt.Errorf("iterations must not be negative, inner iterations: %d, robust iterations: %d", params.InnerIterations, params.RobustIterations)
+	}
+
+	d := &Decomposition{
+		Method:    STLDecomposition,
+		Period:    period,
+		Trend:     make([]float64, n),
+		Seasonal:  make([]float64, n),
+		Remainder: make([]float64, n),
+	}
+	var weights []float64
+	detrended := make([]float64, n)
+	cycle := make([]float64, n+2*period)
+	for pass := 0; pass <= params.RobustIterations; pass++ {
+		for range inner {
+			for t, v := range values {
+				detrended[t] = v - d.Trend[t]
+			}
+			// Smooth every cycle-subseries, extended by one cycle at either end.
+			for k := range period {
+				var sub, subWeights []float64
+				for t := k; t < n; t += period {
+					sub = append(sub, detrended[t])
+					if weights != nil {
+						subWeights = append(subWeights, weights[t])
+					}
+				}
+				for j := -1; j <= len(sub); j++ {
+					cycle[k+(j+1)*period] = loess(sub, subWeights, seasonal, float64(j))
+				}
+			}
+			// Remove the low-frequency part of the smoothed cycle-subseries.
+			low := movingAverage(movingAverage(movingAverage(cycle, period), period), 3)
+			for t := range d.Seasonal {
+				d.Seasonal[t] = cycle[t+period] - loess(low, nil, lowPass, float64(t))
+			}
+			deseasonalized := make([]float64, n)
+			for t, v := range values {
+				deseasonalized[t] = v - d.Seasonal[t]
+			}
+			for t := range d.Trend {
+				d.Trend[t] = loess(deseasonalized, weights, trend, float64(t))
+			}
+		}
+
+		for t, v := range values {
+			d.Remainder[t] = v - d.Trend[t] - d.Seasonal[t]
+		}
+		if pass < params.RobustIterations {
+			weights = robustnessWeights(d.Remainder)
+		}
+	}
+	d.Weights = weights
+	return d, nil
+}
+
+// nextOdd returns the smallest odd integer at least x.
+func nextOdd(x float64) int {
+	n := int(math.Ceil(x))
+	if n%2 == 0 {
+		n++
+	}
+	return n
+}
+
+// movingAverage returns the moving averages of window consecutive values.
+func movingAverage(values []float64, window int) []float64 {
+	result := make([]float64, len(values)-window+1)
+	sum := 0.0
+	for i, v := range values {
+		sum += v
+		if i >= window {
+			sum -= values[i-window]
+		}
+		if i >= window-1 {
+			result[i-window+1] = sum / float64(window)
+		}
+	}
+	return result
+}
+
+// robustnessWeights returns the bisquare weights of the residuals scaled by six times their median
+// absolute value.
+func robustnessWeights(residuals []float64) []float64 {
+	abs := make([]float64, len(residuals))
+	for i, r := range residuals {
+		abs[i] = math.Abs(r)
+	}
+	h := 6 * medianOf(abs)
+	weights := make([]float64, len(residuals))
+	for i, r := range abs {
+		switch u := r / h; {
+		case h == 0 || u <= 0.001:
+			weights[i] = 1
+		case u < 0.999:
+			weights[i] = (1 - u*u) * (1 - u*u)
+		}
+	}
+	return weights
+}
+
+// loess returns the locally linear fit at position x of the values, observed at positions
+// 0 .. len(values)-1, with the tricube weights of the window nearest points, multiplied by the
+// robustness weights when given. A window larger than the series widens the neighbourhood beyond it.
+// When every weight is zero, it returns the value nearest to x.
+func loess(values []float64, robustness []float64, window int, x float64) float64 {
+	n := len(values)
+	left, right := 0, n-1
+	if window < n {
+		left = min(max(int(math.Round(x-float64(window-1)/2)), 0), n-window)
+		right = left + window - 1
+	}
+	h := math.Max(x-float64(left), float64(right)-x)
+	if window > n {
+		h += float64(window-n) / 2
+	}
+
+	sumW, sumX, sumY := 0.0, 0.0, 0.0
+	weights := make([]float64, right-left+1)
+	for j := left; j <= right; j++ {
+		w := 1.0
+		if h > 0 {
+			switch u := math.Abs(float64(j)-x) / h; {
+			case u >= 0.999:
+				w = 0
+			case u > 0.001:
+				w = math.Pow(1-u*u*u, 3)
+			}
+		}
+		if robustness != nil {
+			w *= robustness[j]
+		}
+		weights[j-left] = w
+		sumW += w
+		sumX += w * float64(j)
+		sumY += w * values[j]
+	}
+	if sumW <= 0 {
+		return values[min(max(int(math.Round(x)), 0), n-1)]
+	}
+	meanX, meanY := sumX/sumW, sumY/sumW
+
+	// The slope is only used when the weighted positions are spread enough to estimate it.
+	sxx, sxy := 0.0, 0.0
+	for j := left; j <= right; j++ {
+		dx := float64(j) - meanX
+		sxx += weights[j-left] * dx * dx
+		sxy += weights[j-left] * dx * values[j]
+	}
+	if math.Sqrt(sxx/sumW) <= 0.001*float64(n-1) {
+		return meanY
+	}
+	return meanY + sxy/sxx*(x-meanX)
+}
+
+// DecompositionPredictorParameters holds the configuration of a DecompositionPredictor.
+type DecompositionPredictorParameters struct {
+	Decomposition DecompositionParameters // Decomposition: how the data values are decomposed.
+	Model         LSARXModelParameters    // Model: LSARX model of the remainder, with the time values as external input.
+}
+
+// DecompositionPredictor forecasts a seasonal series by decomposing it, forecasting the remainder
+// with an LSARX model and recomposing the forecast with the seasonal component of the last cycle
+// and the trend extrapolated along its slope over the last cycle it is defined on.
+type DecompositionPredictor struct {
+	Data   [][]float64                      // Historical data: each row is [data_value, time_value].
+	Params DecompositionPredictorParameters // Predictor parameters.
+}
+
+// NewDecompositionPredictor creates a decompose-forecast-recompose predictor with the given data and parameters.
+func NewDecompositionPredictor(data [][]float64, params DecompositionPredictorParameters) (*DecompositionPredictor, error) {
+	if err := checkColumns(data, 2, "data"); err != nil {
+		return nil, err
+	}
+	if _, err := NewLSARXPredictor(data, params.Model); err != nil {
+		return nil, err
+	}
+	return &DecompositionPredictor{Data: data, Params: params}, nil
+}
+
+// Predict decomposes the data values, forecasts the remainder with the LSARX model and recomposes
+// the forecast. It returns [time, value] pairs: the recomposed in-sample predictions of the LSARX
+// model, from the first data point with a remainder, followed by the numToPredict forecast
+// values, or only the forecast values with the ForecastOnlyOutput mode of the model. The
+// classical remainder ends half a cycle before the data, so the LSARX model also forecasts the
+// remainder of the last half cycle.
+func (p *DecompositionPredictor) Predict(numToPredict int) ([][]float64, error) {
+	if numToPredict < 0 {
+		return nil, fmt.Errorf("number of values to predict must not be negative, got: %d", numToPredict)
+	}
+	values := make([]float64, len(p.Data))
+	for i, row := range p.Data {
+		values[i] = row[0]
+	}
+	d, err := Decompose(values, p.Params.Decomposition)
+	if err != nil {
+		return nil, err
+	}
+
+	// The remainder is defined on the data points first .. last.
+	first, last := 0, len(values)-1
+	for math.IsNaN(d.Remainder[first]) {
+		first++
+	}
+	for math.IsNaN(d.Remainder[last]) {
+		last--
+	}
+	remainder := make([][]float64, last-first+1)
+	for i := range remainder {
+		remainder[i] = []float64{d.Remainder[first+i], p.Data[first+i][1]}
+	}
+	model := p.Params.Model
+	model.OutputMode = FullOutput
+	predictor, err := NewLSARXPredictor(remainder, model)
+	if err != nil {
+		return nil, err
+	}
+	predicted, err := predictor.Predict(len(values) - 1 - last + numToPredict)
+	if err != nil {
+		return nil, fmt.Errorf("failed to forecast remainder: %w", err)
+	}
+
+	n, period := len(values), d.Period
+	start := max(first, last-period)
+	slope := (d.Trend[last] - d.Trend[start]) / float64(last-start)
+	result := make([][]float64, len(predicted))
+	for i, row := range predicted {
+		t := first + i
+		trend, seasonal := d.Trend[last]+slope*float64(t-last), 0.0
+		if t <= last {
+			trend = d.Trend[t]
+		}
+		if t < n {
+			seasonal = d.Seasonal[t]
+		} else {
+			seasonal = d.Seasonal[n-period+(t-n)%period]
+		}
+		if d.Method == MultiplicativeDecomposition {
+			result[i] = []float64{row[0], trend * seasonal * row[1]}
+		} else {
+			result[i] = []float64{row[0], trend + seasonal + row[1]}
+		}
+	}
+	if p.Params.Model.OutputMode == ForecastOnlyOutput {
+		return result[len(result)-numToPredict:], nil
+	}
+	return result, nil
+}
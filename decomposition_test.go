@@ -0,0 +1,186 @@
+package ar
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestClassicalDecomposition(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern []float64
+	}{
+		{"Even period", []float64{3, -1, -2, 0}},
+		{"Odd period", []float64{1, -2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			period := len(tt.pattern)
+			values := make([]float64, 30)
+			for i := range values {
+				values[i] = 0.5*float64(i) + tt.pattern[i%period]
+			}
+			d, err := Decompose(values, DecompositionParameters{Period: period})
+			if err != nil {
+				t.Fatalf("Decompose() error = %v", err)
+			}
+			half := period / 2
+			for i := range values {
+				if i < half || i >= len(values)-half {
+					if !math.IsNaN(d.Trend[i]) || !math.IsNaN(d.Remainder[i]) {
+						t.Errorf("trend and remainder at %d = %v and %v, want NaN", i, d.Trend[i], d.Remainder[i])
+					}
+				} else if !approxEqual(d.Trend[i], 0.5*float64(i), 1e-12) || !approxEqual(d.Remainder[i], 0, 1e-12) {
+					t.Errorf("trend and remainder at %d = %v and %v, want %v and 0", i, d.Trend[i], d.Remainder[i], 0.5*float64(i))
+				}
+				if !approxEqual(d.Seasonal[i], tt.pattern[i%period], 1e-12) {
+					t.Errorf("seasonal at %d = %v, want %v", i, d.Seasonal[i], tt.pattern[i%period])
+				}
+			}
+		})
+	}
+
+	values := make([]float64, 48)
+	pattern := []float64{1.2, 0.8, 1.1, 0.9}
+	for i := range values {
+		values[i] = (10 + float64(i)) * pattern[i%4]
+	}
+	d, err := Decompose(values, DecompositionParameters{Method: MultiplicativeDecomposition, Period: 4})
+	if err != nil {
+		t.Fatalf("Decompose() error = %v", err)
+	}
+	mean := 0.0
+	for i, v := range values {
+		mean += d.Seasonal[i] / float64(len(values))
+		if !approxEqual(d.Seasonal[i], pattern[i%4], 0.01) {
+			t.Errorf("seasonal at %d = %v, want about %v", i, d.Seasonal[i], pattern[i%4])
+		}
+		if r := d.Remainder[i]; !math.IsNaN(r) && !approxEqual(d.Trend[i]*d.Seasonal[i]*r, v, 1e-9) {
+			t.Errorf("trend * seasonal * remainder at %d = %v, want %v", i, d.Trend[i]*d.Seasonal[i]*r, v)
+		}
+	}
+	if !approxEqual(mean, 1, 1e-12) {
+		t.Errorf("mean seasonal factor = %v, want 1", mean)
+	}
+}
+
+// seasonalTestData returns a series with a slow trend, a seasonal cycle of period 12 and noise,
+// and its seasonal component.
+func seasonalTestData(n int) (values []float64, seasonal []float64) {
+	rnd := rand.New(rand.NewSource(8))
+	values = make([]float64, n)
+	seasonal = make([]float64, n)
+	for i := range values {
+		x := float64(i)
+		seasonal[i] = 3*math.Sin(2*math.Pi*x/12) + math.Cos(4*math.Pi*x/12)
+		values[i] = 20 + 0.1*x + 2*math.Sin(x/40) + seasonal[i] + 0.2*rnd.NormFloat64()
+	}
+	return values, seasonal
+}
+
+func TestSTLDecomposition(t *testing.T) {
+	values, seasonal := seasonalTestData(240)
+	d, err := Decompose(values, DecompositionParameters{Method: STLDecomposition, Period: 12})
+	if err != nil {
+		t.Fatalf("Decompose() error = %v", err)
+	}
+	for i, v := range values {
+		if !approxEqual(d.Trend[i]+d.Seasonal[i]+d.Remainder[i], v, 1e-9) {
+			t.Fatalf("components at %d add up to %v, want %v", i, d.Trend[i]+d.Seasonal[i]+d.Remainder[i], v)
+		}
+		if !approxEqual(d.Seasonal[i], seasonal[i], 0.3) {
+			t.Errorf("seasonal at %d = %v, want about %v", i, d.Seasonal[i], seasonal[i])
+		}
+	}
+	if d.Weights != nil {
+		t.Errorf("weights = %v, want none for a non-robust fit", d.Weights)
+	}
+
+	// The robust fit downweights an outlier, which shifts the trend less.
+	outlier := append([]float64(nil), values...)
+	outlier[100] += 30
+	plain, err := Decompose(outlier, DecompositionParameters{Method: STLDecomposition, Period: 12})
+	if err != nil {
+		t.Fatalf("Decompose() error = %v", err)
+	}
+	robust, err := Decompose(outlier, DecompositionParameters{Method: STLDecomposition, Period: 12, RobustIterations: 5})
+	if err != nil {
+		t.Fatalf("Decompose() error = %v", err)
+	}
+	if robust.Weights[100] > 0.01 || robust.Weights[50] < 0.5 {
+		t.Errorf("robustness weights of the outlier and a normal value = %v and %v", robust.Weights[100], robust.Weights[50])
+	}
+	if math.Abs(robust.Trend[100]-d.Trend[100]) >= math.Abs(plain.Trend[100]-d.Trend[100]) {
+		t.Errorf("robust trend shift = %v, want less than the non-robust %v", robust.Trend[100]-d.Trend[100], plain.Trend[100]-d.Trend[100])
+	}
+}
+
+func TestDecomposeErrors(t *testing.T) {
+	values, _ := seasonalTestData(48)
+	tests := []struct {
+		name   string
+		values []float64
+		params DecompositionParameters
+	}{
+		{"Short period", values, DecompositionParameters{Period: 1}},
+		{"Not enough data", values[:20], DecompositionParameters{Period: 12}},
+		{"Non-positive multiplicative", []float64{1, 2, 0, 4}, DecompositionParameters{Method: MultiplicativeDecomposition, Period: 2}},
+		{"Even window", values, DecompositionParameters{Method: STLDecomposition, Period: 12, SeasonalWindow: 8}},
+		{"Negative iterations", values, DecompositionParameters{Method: STLDecomposition, Period: 12, RobustIterations: -1}},
+		{"Unknown method", values, DecompositionParameters{Method: DecompositionMethod(9), Period: 12}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decompose(tt.values, tt.params); err == nil {
+				t.Error("Decompose() returned no error")
+			}
+		})
+	}
+}
+
+func TestDecompositionPredictor(t *testing.T) {
+	values, _ := seasonalTestData(264)
+	data := make([][]float64, 240)
+	for i := range data {
+		data[i] = []float64{values[i], float64(i)}
+	}
+	for _, method := range []DecompositionMethod{AdditiveDecomposition, STLDecomposition} {
+		t.Run(method.String(), func(t *testing.T) {
+			params := DecompositionPredictorParameters{
+				Decomposition: DecompositionParameters{Method: method, Period: 12},
+				Model:         LSARXModelParameters{AutoregressiveLags: 1, ExternalInputLags: 1, StepSize: 1, OutputMode: ForecastOnlyOutput},
+			}
+			predictor, err := NewDecompositionPredictor(data, params)
+			if err != nil {
+				t.Fatalf("NewDecompositionPredictor() error = %v", err)
+			}
+			forecast, err := predictor.Predict(24)
+			if err != nil {
+				t.Fatalf("Predict() error = %v", err)
+			}
+			if len(forecast) != 24 {
+				t.Fatalf("Predict() returned %d values, want 24", len(forecast))
+			}
+			for h, row := range forecast {
+				if row[0] != float64(240+h) || !approxEqual(row[1], values[240+h], 1.5) {
+					t.Errorf("forecast %d = %v, want about [%d %v]", h, row, 240+h, values[240+h])
+				}
+			}
+
+			params.Model.OutputMode = FullOutput
+			predictor.Params = params
+			full, err := predictor.Predict(24)
+			if err != nil {
+				t.Fatalf("Predict() error = %v", err)
+			}
+			if last := full[len(full)-24:]; !approxEqual(last[5][1], forecast[5][1], 1e-9) {
+				t.Errorf("full output ends with %v, want the forecast %v", last, forecast)
+			}
+		})
+	}
+
+	if _, err := NewDecompositionPredictor(data, DecompositionPredictorParameters{Model: LSARXModelParameters{StepSize: 1}}); err == nil {
+		t.Error("NewDecompositionPredictor() without lags returned no error")
+	}
+}
@@ -0,0 +1,291 @@
+package ar
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/optimize"
+)
+
+// CombinationMethod selects how an EnsemblePredictor combines the forecasts of its members.
+type CombinationMethod int
+
+const (
+	// MeanCombination averages the member forecasts with equal weights.
+	MeanCombination CombinationMethod = iota
+	// MedianCombination takes the median of the member forecasts at every step.
+	MedianCombination
+	// InverseMSECombination weights every member by the inverse of its backtest mean squared error.
+	InverseMSECombination
+	// StackingCombination uses the non-negative weights, summing to one, whose combination of the
+	// backtest forecasts has the least squared error.
+	StackingCombination
+)
+
+// String returns the name of the combination method.
+func (c CombinationMethod) String() string {
+	switch c {
+	case MeanCombination:
+		return "mean"
+	case MedianCombination:
+		return "median"
+	case InverseMSECombination:
+		return "inverse-mse"
+	case StackingCombination:
+		return "stacking"
+	default:
+		return fmt.Sprintf("CombinationMethod(%d)", int(c))
+	}
+}
+
+// ParseCombinationMethod returns the combination method with the given name, as returned by String.
+func ParseCombinationMethod(name string) (CombinationMethod, error) {
+	for c := MeanCombination; c <= StackingCombination; c++ {
+		if c.String() == name {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown combination method: %q", name)
+}
+
+// EnsembleMember is one configured model of an ensemble.
+type EnsembleMember struct {
+	Name  string                                      // Name of the member, reported with its forecast.
+	Build func(train [][]float64) (Forecaster, error) // Builds the forecaster of the member on training data, e.g. with NewLSARXPredictor.
+}
+
+// EnsembleParameters holds the configuration of an EnsemblePredictor.
+type EnsembleParameters struct {
+	Method   CombinationMethod  // Method: how the member forecasts are combined, the mean by default.
+	Backtest BacktestParameters // Backtest: rolling-origin evaluation the inverse-MSE and stacking weights are estimated from.
+}
+
+// EnsemblePredictor fits several models on the same data and combines their forecasts.
+type EnsemblePredictor struct {
+	Data    [][]float64        // Historical data: each row is [data_value, time_value].
+	Members []EnsembleMember   // Configured models.
+	Params  EnsembleParameters // Ensemble parameters.
+}
+
+// EnsembleForecast holds the combined forecast of an EnsemblePredictor with the forecasts and
+// weights of its members. Every forecast holds [time, value] pairs.
+type EnsembleForecast struct {
+	Forecast   [][]float64   // Combined forecast.
+	Names      []string      // Member names.
+	Components [][][]float64 // Forecast of every member.
+	Weights    []float64     // Weight of every member, nil for the median combination.
+	MSE        []float64     // Backtest mean squared error of every member, nil for the mean and median combinations.
+}
+
+// NewEnsemblePredictor creates an ensemble of the given members on the data.
+func NewEnsemblePredictor(data [][]float64, members []EnsembleMember, params EnsembleParameters) (*EnsemblePredictor, error) {
+	if len(members) == 0 {
+		return nil, fmt.Errorf("ensemble needs at least one member")
+	}
+	for i, member := range members {
+		if member.Build == nil {
+			return nil, fmt.Errorf("ensemble member %d (%q) has no build function", i, member.Name)
+		}
+	}
+	if params.Method < MeanCombination || params.Method > StackingCombination {
+		return nil, fmt.Errorf("unknown combination method: %d", params.Method)
+	}
+	return &EnsemblePredictor{Data: data, Members: members, Params: params}, nil
+}
+
+// Predict returns the combined forecast of numToPredict values as [time, value] pairs.
+func (p *EnsemblePredictor) Predict(numToPredict int) ([][]float64, error) {
+	result, err := p.Forecast(numToPredict)
+	if err != nil {
+		return nil, err
+	}
+	return result.Forecast, nil
+}
+
+// Forecast fits every member on the data and combines their forecasts of numToPredict values.
+// The inverse-MSE and stacking weights are estimated from a backtest of every member on the data
+// with Params.Backtest. The time values of the combined forecast are those of the first member.
+func (p *EnsemblePredictor) Forecast(numToPredict int) (*EnsembleForecast, error) {
+	if numToPredict <= 0 {
+		return nil, fmt.Errorf("number of values to predict must be a positive integer, number to predict: %d", numToPredict)
+	}
+
+	result := &EnsembleForecast{
+		Names:      make([]string, len(p.Members)),
+		Components: make([][][]float64, len(p.Members)),
+	}
+	for i, member := range p.Members {
+		result.Names[i] = member.Name
+		forecaster, err := member.Build(p.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build ensemble member %q: %w", member.Name, err)
+		}
+		predicted, err := forecaster.Predict(numToPredict)
+		if err != nil {
+			return nil, fmt.Errorf("failed to predict ensemble member %q: %w", member.Name, err)
+		}
+		if len(predicted) < numToPredict {
+			return nil, fmt.Errorf("ensemble member %q returned %d values, expected at least %d", member.Name, len(predicted), numToPredict)
+		}
+		result.Components[i] = predicted[len(predicted)-numToPredict:]
+	}
+
+	switch p.Params.Method {
+	case MeanCombination:
+		result.Weights = make([]float64, len(p.Members))
+		for i := range result.Weights {
+			result.Weights[i] = 1 / float64(len(p.Members))
+		}
+	case InverseMSECombination, StackingCombination:
+		actual, forecasts, err := p.backtestForecasts()
+		if err != nil {
+			return nil, err
+		}
+		result.MSE = make([]float64, len(p.Members))
+		for i, f := range forecasts {
+			for j, v := range f {
+				result.MSE[i] += (actual[j] - v) * (actual[j] - v)
+			}
+			result.MSE[i] /= float64(len(actual))
+		}
+		if p.Params.Method == InverseMSECombination {
+			result.Weights = inverseMSEWeights(result.MSE)
+		} else {
+			result.Weights = stackingWeights(actual, forecasts)
+		}
+	}
+
+	result.Forecast = make([][]float64, numToPredict)
+	values := make([]float64, len(p.Members))
+	for h := range result.Forecast {
+		for i, c := range result.Components {
+			values[i] = c[h][1]
+		}
+		combined := 0.0
+		if result.Weights == nil {
+			combined = medianOf(values)
+		} else {
+			for i, v := range values {
+				combined += result.Weights[i] * v
+			}
+		}
+		result.Forecast[h] = []float64{result.Components[0][h][0], combined}
+	}
+	return result, nil
+}
+
+// backtestForecasts backtests every member on the data and returns the actual values of every fold
+// and horizon step, and the forecasts of every member for them.
+func (p *EnsemblePredictor) backtestForecasts() (actual []float64, forecasts [][]float64, err error) {
+	forecasts = make([][]float64, len(p.Members))
+	for i, member := range p.Members {
+		bt, err := Backtest(p.Data, p.Params.Backtest, member.Build)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to backtest ensemble member %q: %w", member.Name, err)
+		}
+		for f, origin := range bt.Origins {
+			for h, e := range bt.Errors[f] {
+				v := p.Data[origin+1+h][0]
+				if i == 0 {
+					actual = append(actual, v)
+				}
+				forecasts[i] = append(forecasts[i], v-e)
+			}
+		}
+	}
+	return actual, forecasts, nil
+}
+
+// inverseMSEWeights returns weights proportional to the inverse of the mean squared errors. Members
+// with no error share the whole weight.
+func inverseMSEWeights(mse []float64) []float64 {
+	weights := make([]float64, len(mse))
+	exact := 0
+	for _, e := range mse {
+		if e == 0 {
+			exact++
+		}
+	}
+	sum := 0.0
+	for i, e := range mse {
+		switch {
+		case exact > 0 && e == 0:
+			weights[i] = 1
+		case exact == 0:
+			weights[i] = 1 / e
+		}
+		sum += weights[i]
+	}
+	for i := range weights {
+		weights[i] /= sum
+	}
+	return weights
+}
+
+// stackingWeights returns the non-negative weights, summing to one, that minimize the squared error
+// of the weighted forecasts against the actual values. The weights are the softmax of unconstrained
+// parameters, found by BFGS from equal weights.
+func stackingWeights(actual []float64, forecasts [][]float64) []float64 {
+	k := len(forecasts)
+	softmax := func(z []float64) []float64 {
+		w := make([]float64, k)
+		maxZ := z[0]
+		for _, v := range z {
+			maxZ = math.Max(maxZ, v)
+		}
+		sum := 0.0
+		for i, v := range z {
+			w[i] = math.Exp(v - maxZ)
+			sum += w[i]
+		}
+		for i := range w {
+			w[i] /= sum
+		}
+		return w
+	}
+	// residuals returns the errors of the combination with weights w.
+	residuals := func(w []float64) []float64 {
+		r := make([]float64, len(actual))
+		for j, y := range actual {
+			r[j] = y
+			for i, f := range forecasts {
+				r[j] -= w[i] * f[j]
+			}
+		}
+		return r
+	}
+
+	problem := optimize.Problem{
+		Func: func(z []float64) float64 {
+			sse := 0.0
+			for _, r := range residuals(softmax(z)) {
+				sse += r * r
+			}
+			return sse
+		},
+		Grad: func(grad, z []float64) {
+			// dSSE/dw_i = -2 sum_j r_j f_ij, and dw_i/dz_l = w_i (delta_il - w_l).
+			w := softmax(z)
+			r := residuals(w)
+			dw := make([]float64, k)
+			for i, f := range forecasts {
+				for j, rj := range r {
+					dw[i] -= 2 * rj * f[j]
+				}
+			}
+			mean := 0.0
+			for i := range w {
+				mean += w[i] * dw[i]
+			}
+			for l := range grad {
+				grad[l] = w[l] * (dw[l] - mean)
+			}
+		},
+	}
+	// A failed line search still returns the best weights found.
+	result, _ := optimize.Minimize(problem, make([]float64, k), nil, &optimize.BFGS{})
+	if result == nil {
+		return softmax(make([]float64, k))
+	}
+	return softmax(result.X)
+}
@@ -0,0 +1,175 @@
+package ar
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// constantForecaster forecasts a constant value at the time values following the data.
+type constantForecaster struct {
+	data  [][]float64
+	value float64
+}
+
+func (c constantForecaster) Predict(numToPredict int) ([][]float64, error) {
+	last := c.data[len(c.data)-1][1]
+	result := make([][]float64, numToPredict)
+	for i := range result {
+		result[i] = []float64{last + float64(i+1), c.value}
+	}
+	return result, nil
+}
+
+func ensembleTestMembers() []EnsembleMember {
+	return []EnsembleMember{
+		{"lsarx", func(train [][]float64) (Forecaster, error) {
+			return NewLSARXPredictor(train, LSARXModelParameters{AutoregressiveLags: 1, ExternalInputLags: 1, InputDelay: 2, Intercept: true, StepSize: 1, OutputMode: ForecastOnlyOutput})
+		}},
+		{"zero", func(train [][]float64) (Forecaster, error) {
+			return constantForecaster{train, 0}, nil
+		}},
+		{"mean", func(train [][]float64) (Forecaster, error) {
+			mean := 0.0
+			for _, row := range train {
+				mean += row[0]
+			}
+			return constantForecaster{train, mean / float64(len(train))}, nil
+		}},
+	}
+}
+
+func TestEnsemblePredictor(t *testing.T) {
+	data := delayedARXData(200)
+	backtest := BacktestParameters{Horizon: 1, InitialWindow: 150, Step: 5}
+	tests := []struct {
+		name   string
+		method CombinationMethod
+		check  func(t *testing.T, r *EnsembleForecast)
+	}{
+		{"Mean", MeanCombination, func(t *testing.T, r *EnsembleForecast) {
+			if !reflect.DeepEqual(r.Weights, []float64{1.0 / 3, 1.0 / 3, 1.0 / 3}) || r.MSE != nil {
+				t.Errorf("weights = %v and MSE = %v, want equal weights", r.Weights, r.MSE)
+			}
+		}},
+		{"Median", MedianCombination, func(t *testing.T, r *EnsembleForecast) {
+			for h, row := range r.Forecast {
+				values := []float64{r.Components[0][h][1], r.Components[1][h][1], r.Components[2][h][1]}
+				if row[1] != medianOf(values) {
+					t.Errorf("combined forecast = %v, want the median of %v", row[1], values)
+				}
+			}
+			if r.Weights != nil {
+				t.Errorf("weights = %v, want none", r.Weights)
+			}
+		}},
+		{"Inverse MSE", InverseMSECombination, func(t *testing.T, r *EnsembleForecast) {
+			sum := 1/r.MSE[0] + 1/r.MSE[1] + 1/r.MSE[2]
+			for i, w := range r.Weights {
+				if !approxEqual(w, 1/r.MSE[i]/sum, 1e-12) {
+					t.Errorf("weights = %v, want the normalized inverse of MSE %v", r.Weights, r.MSE)
+				}
+			}
+			if !(r.Weights[0] > 0.99) {
+				t.Errorf("LSARX weight = %v, want almost all the weight", r.Weights[0])
+			}
+		}},
+		{"Stacking", StackingCombination, func(t *testing.T, r *EnsembleForecast) {
+			sum := 0.0
+			for _, w := range r.Weights {
+				sum += w
+				if w < 0 {
+					t.Errorf("weights = %v, want non-negative weights", r.Weights)
+				}
+			}
+			if !approxEqual(sum, 1, 1e-12) || !(r.Weights[0] > 0.95) {
+				t.Errorf("weights = %v, want weights summing to 1 with the LSARX member first", r.Weights)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			predictor, err := NewEnsemblePredictor(data, ensembleTestMembers(), EnsembleParameters{Method: tt.method, Backtest: backtest})
+			if err != nil {
+				t.Fatalf("NewEnsemblePredictor() error = %v", err)
+			}
+			result, err := predictor.Forecast(4)
+			if err != nil {
+				t.Fatalf("Forecast() error = %v", err)
+			}
+			if len(result.Forecast) != 4 || len(result.Components) != 3 || !reflect.DeepEqual(result.Names, []string{"lsarx", "zero", "mean"}) {
+				t.Fatalf("Forecast() = %+v, want 4 values and 3 components", result)
+			}
+			if result.Weights != nil {
+				for h, row := range result.Forecast {
+					want := 0.0
+					for i, c := range result.Components {
+						want += result.Weights[i] * c[h][1]
+					}
+					if !approxEqual(row[1], want, 1e-9) || row[0] != result.Components[0][h][0] {
+						t.Errorf("combined forecast %d = %v, want %v", h, row, want)
+					}
+				}
+			}
+			tt.check(t, result)
+
+			predicted, err := predictor.Predict(4)
+			if err != nil {
+				t.Fatalf("Predict() error = %v", err)
+			}
+			if !reflect.DeepEqual(predicted, result.Forecast) {
+				t.Errorf("Predict() = %v, want %v", predicted, result.Forecast)
+			}
+		})
+	}
+}
+
+func TestStackingWeights(t *testing.T) {
+	// The actual values are 0.25 f1 + 0.75 f2.
+	f1 := []float64{1, 4, 2, 8, 5, 7}
+	f2 := []float64{3, 1, 6, 2, 9, 4}
+	actual := make([]float64, len(f1))
+	for i := range actual {
+		actual[i] = 0.25*f1[i] + 0.75*f2[i]
+	}
+	w := stackingWeights(actual, [][]float64{f1, f2})
+	if !approxEqual(w[0], 0.25, 1e-5) || !approxEqual(w[1], 0.75, 1e-5) {
+		t.Errorf("stackingWeights() = %v, want [0.25 0.75]", w)
+	}
+
+	if w := inverseMSEWeights([]float64{0, 2, 0}); !reflect.DeepEqual(w, []float64{0.5, 0, 0.5}) {
+		t.Errorf("inverseMSEWeights() = %v, want the exact members to share the weight", w)
+	}
+	if got := math.Round(inverseMSEWeights([]float64{1, 3})[0] * 4); got != 3 {
+		t.Errorf("inverseMSEWeights() first weight = %v/4, want 3/4", got)
+	}
+}
+
+func TestEnsemblePredictorErrors(t *testing.T) {
+	data := delayedARXData(50)
+	members := ensembleTestMembers()
+	if _, err := NewEnsemblePredictor(data, nil, EnsembleParameters{}); err == nil {
+		t.Error("NewEnsemblePredictor() without members returned no error")
+	}
+	if _, err := NewEnsemblePredictor(data, []EnsembleMember{{Name: "nil"}}, EnsembleParameters{}); err == nil {
+		t.Error("NewEnsemblePredictor() without a build function returned no error")
+	}
+	if _, err := NewEnsemblePredictor(data, members, EnsembleParameters{Method: CombinationMethod(9)}); err == nil {
+		t.Error("NewEnsemblePredictor() with an unknown method returned no error")
+	}
+
+	predictor, err := NewEnsemblePredictor(data, members, EnsembleParameters{Method: InverseMSECombination})
+	if err != nil {
+		t.Fatalf("NewEnsemblePredictor() error = %v", err)
+	}
+	if _, err := predictor.Forecast(3); err == nil {
+		t.Error("Forecast() without backtest parameters returned no error")
+	}
+	if _, err := predictor.Forecast(0); err == nil {
+		t.Error("Forecast(0) returned no error")
+	}
+
+	if c, err := ParseCombinationMethod("inverse-mse"); err != nil || c != InverseMSECombination {
+		t.Errorf("ParseCombinationMethod() = %v, %v, want %v", c, err, InverseMSECombination)
+	}
+}
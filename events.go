@@ -0,0 +1,302 @@
+package ar
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// EventRule is a recurring date of an event: a fixed day of the month, such as December 25, or
+// the n-th weekday of the month, such as the fourth Thursday of November.
+type EventRule struct {
+	Month   time.Month   // Month of the occurrences, every month when zero.
+	Day     int          // Day of the month of the occurrences, zero for a weekday rule.
+	Weekday time.Weekday // Weekday of the occurrences of a weekday rule.
+	Week    int          // Occurrence of the weekday in the month of a weekday rule, from 1 to 5, or -1 for the last one.
+}
+
+// validate checks that the rule is either a day rule or a weekday rule.
+func (r EventRule) validate() error {
+	if r.Month < 0 || r.Month > time.December {
+		return fmt.Errorf("event rule month must be between 1 and 12, or 0 for every month, month: %d", r.Month)
+	}
+	switch {
+	case r.Day != 0 && r.Week != 0:
+		return fmt.Errorf("event rule must set either a day or a week, day: %d, week: %d", r.Day, r.Week)
+	case r.Day != 0:
+		if r.Day < 1 || r.Day > 31 {
+			return fmt.Errorf("event rule day must be between 1 and 31, day: %d", r.Day)
+		}
+	case r.Week < -1 || r.Week == 0 || r.Week > 5:
+		return fmt.Errorf("event rule week must be between 1 and 5, or -1 for the last one, week: %d", r.Week)
+	case r.Weekday < time.Sunday || r.Weekday > time.Saturday:
+		return fmt.Errorf("unknown event rule weekday: %d", r.Weekday)
+	}
+	return nil
+}
+
+// matches reports whether the date is an occurrence of the rule.
+func (r EventRule) matches(date time.Time) bool {
+	y, m, d := date.Date()
+	if r.Month != 0 && m != r.Month {
+		return false
+	}
+	if r.Day != 0 {
+		return d == r.Day
+	}
+	if date.Weekday() != r.Weekday {
+		return false
+	}
+	if r.Week < 0 {
+		// The last weekday of the month is less than a week before the first of the next one.
+		return d+7 > time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	}
+	return (d-1)/7+1 == r.Week
+}
+
+// DateRange is the range of dates from Start to End, both included.
+type DateRange struct {
+	Start time.Time // First date of the range.
+	End   time.Time // Last date of the range.
+}
+
+// Event is a dated occurrence, such as a holiday or a campaign, whose effect on a series is
+// estimated through dummy columns: one for every day from Before days before each occurrence to
+// After days after it, each with its own effect. Every date of the ranges is an occurrence.
+type Event struct {
+	Name   string      // Name of the event, the label of its columns.
+	Dates  []time.Time // Single dates of the event.
+	Ranges []DateRange // Date ranges of the event.
+	Rules  []EventRule // Recurring dates of the event.
+	Before int         // Number of days before every occurrence with their own effect.
+	After  int         // Number of days after every occurrence with their own effect.
+}
+
+// EventRegressor is a Regressor of the dummy columns of events, evaluated at the timestamps of the
+// time values of a series on the calendar. The column of offset k of an event is 1 at the
+// timestamps whose date is k days after an occurrence, so events suit the minute to daily
+// frequencies. Every column must be 1 somewhere in the training data for its effect to be estimated.
+type EventRegressor struct {
+	Calendar *Calendar // Calendar of the time values.
+	Events   []Event   // Events, in column order.
+
+	days [][]int // Sorted days of the dates and ranges of every event, see civilDay.
+}
+
+// NewEventRegressor creates a regressor of the events on the calendar.
+func NewEventRegressor(c *Calendar, events ...Event) (*EventRegressor, error) {
+	if c == nil {
+		return nil, fmt.Errorf("event regressor needs a calendar")
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("event regressor needs at least one event")
+	}
+	r := &EventRegressor{Calendar: c, Events: events, days: make([][]int, len(events))}
+	names := make(map[string]bool, len(events))
+	for i, e := range events {
+		if e.Name == "" || names[e.Name] {
+			return nil, fmt.Errorf("event %d must have a unique name, name: %q", i, e.Name)
+		}
+		names[e.Name] = true
+		if e.Before < 0 || e.After < 0 {
+			return nil, fmt.Errorf("event windows must not be negative, event: %q, before: %d, after: %d", e.Name, e.Before, e.After)
+		}
+		if len(e.Dates)+len(e.Ranges)+len(e.Rules) == 0 {
+			return nil, fmt.Errorf("event %q has no dates", e.Name)
+		}
+		for _, rule := range e.Rules {
+			if err := rule.validate(); err != nil {
+				return nil, fmt.Errorf("event %q: %w", e.Name, err)
+			}
+		}
+		for _, d := range e.Dates {
+			r.days[i] = append(r.days[i], civilDay(d))
+		}
+		for _, dr := range e.Ranges {
+			first, last := civilDay(dr.Start), civilDay(dr.End)
+			if last < first {
+				return nil, fmt.Errorf("event %q has a date range ending before it starts, %v to %v", e.Name, dr.Start, dr.End)
+			}
+			for d := first; d <= last; d++ {
+				r.days[i] = append(r.days[i], d)
+			}
+		}
+		slices.Sort(r.days[i])
+	}
+	return r, nil
+}
+
+// eventColumnName returns the label of the column of an event at the offset in days.
+func eventColumnName(name string, offset int) string {
+	if offset == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s[%+d]", name, offset)
+}
+
+// Names returns the labels of the columns of the events: the name of the event for the occurrences
+// and the name followed by the offset in days, e.g. "christmas[-1]", for the windows.
+func (r *EventRegressor) Names() []string {
+	var names []string
+	for _, e := range r.Events {
+		for k := -e.Before; k <= e.After; k++ {
+			names = append(names, eventColumnName(e.Name, k))
+		}
+	}
+	return names
+}
+
+// Evaluate writes the columns of the events at the timestamp of time value t to dst.
+func (r *EventRegressor) Evaluate(t float64, dst []float64) {
+	day := civilDay(r.Calendar.Timestamp(int(math.Round(t))).In(r.Calendar.Start.Location()))
+	for i, e := range r.Events {
+		for k := -e.Before; k <= e.After; k++ {
+			dst[0] = 0
+			if r.occurs(i, day-k) {
+				dst[0] = 1
+			}
+			dst = dst[1:]
+		}
+	}
+}
+
+// occurs reports whether event i occurs on the day, see civilDay.
+func (r *EventRegressor) occurs(i int, day int) bool {
+	if _, ok := slices.BinarySearch(r.days[i], day); ok {
+		return true
+	}
+	date := time.Unix(int64(day)*86400, 0).UTC()
+	for _, rule := range r.Events[i].Rules {
+		if rule.matches(date) {
+			return true
+		}
+	}
+	return false
+}
+
+// EventEffect is the fitted effect of an event at an offset from its occurrences.
+type EventEffect struct {
+	Event              string // Name of the event.
+	Offset             int    // Days from the occurrences, negative before them.
+	CoefficientSummary        // Inference on the effect.
+}
+
+// Effects returns the fitted effects of the events from the summary of a model with the regressor,
+// in column order. The effects are the coefficients of the columns: for an LSARX model, the
+// immediate effect on the one-step-ahead prediction, which the autoregressive part carries over
+// to the following values.
+func (r *EventRegressor) Effects(summary *FitSummary) ([]EventEffect, error) {
+	names := r.Names()
+	for first := 0; first+len(names) <= len(summary.Coefficients); first++ {
+		coefficients := summary.Coefficients[first : first+len(names)]
+		if !slices.EqualFunc(coefficients, names, func(c CoefficientSummary, name string) bool { return c.Label == name }) {
+			continue
+		}
+		effects := make([]EventEffect, 0, len(names))
+		for _, e := range r.Events {
+			for k := -e.Before; k <= e.After; k++ {
+				effects = append(effects, EventEffect{Event: e.Name, Offset: k, CoefficientSummary: coefficients[len(effects)]})
+			}
+		}
+		return effects, nil
+	}
+	return nil, fmt.Errorf("fit summary has no coefficients of the event regressor")
+}
+
+// eventRuleJSON is the JSON encoding of EventRule.
+type eventRuleJSON struct {
+	Month   int `json:"month,omitempty"`
+	Day     int `json:"day,omitempty"`
+	Weekday int `json:"weekday,omitempty"`
+	Week    int `json:"week,omitempty"`
+}
+
+// eventJSON is the JSON encoding of Event, with the dates as dates in the location of the calendar.
+type eventJSON struct {
+	Name   string          `json:"name"`
+	Dates  []string        `json:"dates,omitempty"`
+	Ranges [][2]string     `json:"ranges,omitempty"`
+	Rules  []eventRuleJSON `json:"rules,omitempty"`
+	Before int             `json:"before,omitempty"`
+	After  int             `json:"after,omitempty"`
+}
+
+// eventRegressorJSON is the JSON encoding of EventRegressor.
+type eventRegressorJSON struct {
+	Calendar calendarJSON `json:"calendar"`
+	Events   []eventJSON  `json:"events"`
+}
+
+// MarshalJSON encodes the calendar and events of the regressor.
+func (r *EventRegressor) MarshalJSON() ([]byte, error) {
+	v := eventRegressorJSON{Calendar: newCalendarJSON(r.Calendar)}
+	for _, e := range r.Events {
+		ev := eventJSON{Name: e.Name, Before: e.Before, After: e.After}
+		for _, d := range e.Dates {
+			ev.Dates = append(ev.Dates, d.Format(time.DateOnly))
+		}
+		for _, dr := range e.Ranges {
+			ev.Ranges = append(ev.Ranges, [2]string{dr.Start.Format(time.DateOnly), dr.End.Format(time.DateOnly)})
+		}
+		for _, rule := range e.Rules {
+			ev.Rules = append(ev.Rules, eventRuleJSON{Month: int(rule.Month), Day: rule.Day, Weekday: int(rule.Weekday), Week: rule.Week})
+		}
+		v.Events = append(v.Events, ev)
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes a regressor encoded by MarshalJSON.
+func (r *EventRegressor) UnmarshalJSON(data []byte) error {
+	var v eventRegressorJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	c, err := v.Calendar.calendar()
+	if err != nil {
+		return err
+	}
+	loc := c.Start.Location()
+	parseDate := func(s string) (time.Time, error) {
+		d, err := time.ParseInLocation(time.DateOnly, s, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid event date: %v", err)
+		}
+		return d, nil
+	}
+
+	events := make([]Event, len(v.Events))
+	for i, ev := range v.Events {
+		e := Event{Name: ev.Name, Before: ev.Before, After: ev.After}
+		for _, s := range ev.Dates {
+			d, err := parseDate(s)
+			if err != nil {
+				return err
+			}
+			e.Dates = append(e.Dates, d)
+		}
+		for _, dr := range ev.Ranges {
+			start, err := parseDate(dr[0])
+			if err != nil {
+				return err
+			}
+			end, err := parseDate(dr[1])
+			if err != nil {
+				return err
+			}
+			e.Ranges = append(e.Ranges, DateRange{Start: start, End: end})
+		}
+		for _, rule := range ev.Rules {
+			e.Rules = append(e.Rules, EventRule{Month: time.Month(rule.Month), Day: rule.Day, Weekday: time.Weekday(rule.Weekday), Week: rule.Week})
+		}
+		events[i] = e
+	}
+	regressor, err := NewEventRegressor(c, events...)
+	if err != nil {
+		return err
+	}
+	*r = *regressor
+	return nil
+}
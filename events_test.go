@@ -0,0 +1,213 @@
+package ar
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEventRuleMatches(t *testing.T) {
+	tests := []struct {
+		name string
+		rule EventRule
+		date time.Time
+		want bool
+	}{
+		{"Fixed day", EventRule{Month: time.December, Day: 25}, time.Date(2024, 12, 25, 0, 0, 0, 0, time.UTC), true},
+		{"Fixed day in another month", EventRule{Month: time.December, Day: 25}, time.Date(2024, 11, 25, 0, 0, 0, 0, time.UTC), false},
+		{"Every month", EventRule{Day: 1}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), true},
+		{"Fourth Thursday", EventRule{Month: time.November, Weekday: time.Thursday, Week: 4}, time.Date(2024, 11, 28, 0, 0, 0, 0, time.UTC), true},
+		{"Third Thursday", EventRule{Month: time.November, Weekday: time.Thursday, Week: 4}, time.Date(2024, 11, 21, 0, 0, 0, 0, time.UTC), false},
+		{"Last Monday", EventRule{Month: time.May, Weekday: time.Monday, Week: -1}, time.Date(2024, 5, 27, 0, 0, 0, 0, time.UTC), true},
+		{"Second to last Monday", EventRule{Month: time.May, Weekday: time.Monday, Week: -1}, time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.validate(); err != nil {
+				t.Fatalf("validate() error = %v", err)
+			}
+			if got := tt.rule.matches(tt.date); got != tt.want {
+				t.Errorf("matches(%v) = %v, want %v", tt.date, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventRegressorColumns(t *testing.T) {
+	c, err := NewCalendar(time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC), DailyFrequency, nil)
+	if err != nil {
+		t.Fatalf("NewCalendar() error = %v", err)
+	}
+	r, err := NewEventRegressor(c,
+		Event{Name: "christmas", Rules: []EventRule{{Month: time.December, Day: 25}}, Before: 1, After: 1},
+		Event{Name: "sale", Ranges: []DateRange{{Start: time.Date(2024, 12, 26, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 12, 28, 0, 0, 0, 0, time.UTC)}}},
+		Event{Name: "launch", Dates: []time.Time{time.Date(2024, 12, 21, 0, 0, 0, 0, time.UTC)}},
+	)
+	if err != nil {
+		t.Fatalf("NewEventRegressor() error = %v", err)
+	}
+	if want := []string{"christmas[-1]", "christmas", "christmas[+1]", "sale", "launch"}; !reflect.DeepEqual(r.Names(), want) {
+		t.Errorf("Names() = %v, want %v", r.Names(), want)
+	}
+
+	want := [][]float64{
+		{0, 0, 0, 0, 0}, // 12-20
+		{0, 0, 0, 0, 1}, // 12-21
+		{0, 0, 0, 0, 0}, // 12-22
+		{0, 0, 0, 0, 0}, // 12-23
+		{1, 0, 0, 0, 0}, // 12-24
+		{0, 1, 0, 0, 0}, // 12-25
+		{0, 0, 1, 1, 0}, // 12-26
+		{0, 0, 0, 1, 0}, // 12-27
+		{0, 0, 0, 1, 0}, // 12-28
+		{0, 0, 0, 0, 0}, // 12-29
+	}
+	for step, row := range want {
+		got := make([]float64, 5)
+		r.Evaluate(float64(step), got)
+		if !reflect.DeepEqual(got, row) {
+			t.Errorf("Evaluate(%d) = %v, want %v", step, got, row)
+		}
+	}
+}
+
+func TestNewEventRegressorErrors(t *testing.T) {
+	c, err := NewCalendar(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), DailyFrequency, nil)
+	if err != nil {
+		t.Fatalf("NewCalendar() error = %v", err)
+	}
+	day := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		events []Event
+	}{
+		{"No events", nil},
+		{"No name", []Event{{Dates: []time.Time{day}}}},
+		{"Duplicate name", []Event{{Name: "a", Dates: []time.Time{day}}, {Name: "a", Dates: []time.Time{day}}}},
+		{"No dates", []Event{{Name: "a"}}},
+		{"Negative window", []Event{{Name: "a", Dates: []time.Time{day}, Before: -1}}},
+		{"Reversed range", []Event{{Name: "a", Ranges: []DateRange{{Start: day, End: day.AddDate(0, 0, -1)}}}}},
+		{"Day and week", []Event{{Name: "a", Rules: []EventRule{{Day: 1, Week: 1}}}}},
+		{"Invalid day", []Event{{Name: "a", Rules: []EventRule{{Day: 32}}}}},
+		{"Invalid week", []Event{{Name: "a", Rules: []EventRule{{Week: 6}}}}},
+		{"Invalid month", []Event{{Name: "a", Rules: []EventRule{{Month: 13, Day: 1}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewEventRegressor(c, tt.events...); err == nil {
+				t.Error("NewEventRegressor() returned no error")
+			}
+		})
+	}
+	if _, err := NewEventRegressor(nil, Event{Name: "a", Dates: []time.Time{day}}); err == nil {
+		t.Error("NewEventRegressor() without a calendar returned no error")
+	}
+}
+
+// promotionData returns n daily rows from 2024-01-01 of a series that rises by 8 on the first
+// Friday of every month and falls by 3 the day after, with its event regressor. The series is
+// AR(1) around 20 when ar is set, and a noisy linear trend otherwise.
+func promotionData(t *testing.T, n int, ar bool) ([][]float64, *EventRegressor) {
+	t.Helper()
+	c, err := NewCalendar(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), DailyFrequency, nil)
+	if err != nil {
+		t.Fatalf("NewCalendar() error = %v", err)
+	}
+	promotion := EventRule{Weekday: time.Friday, Week: 1}
+	r, err := NewEventRegressor(c, Event{Name: "promotion", Rules: []EventRule{promotion}, After: 1})
+	if err != nil {
+		t.Fatalf("NewEventRegressor() error = %v", err)
+	}
+
+	rnd := rand.New(rand.NewSource(6))
+	data := make([][]float64, n)
+	for i := range data {
+		ts := c.Timestamp(i)
+		v := 100 + 0.5*float64(i) + 0.1*rnd.NormFloat64()
+		if ar {
+			v = 20 + 0.1*rnd.NormFloat64()
+			if i > 0 {
+				v = 10 + 0.5*data[i-1][0] + 0.1*rnd.NormFloat64()
+			}
+		}
+		if promotion.matches(ts) {
+			v += 8
+		}
+		if promotion.matches(ts.AddDate(0, 0, -1)) {
+			v -= 3
+		}
+		data[i] = []float64{v, float64(i)}
+	}
+	return data, r
+}
+
+func TestEventEffects(t *testing.T) {
+	lsData, lsEvents := promotionData(t, 200, false)
+	ls, err := NewLSPredictor(lsData, LSModelParameters{StepSize: 1, Regressors: []Regressor{lsEvents}})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	lsModel, err := ls.Fit()
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	arxData, arxEvents := promotionData(t, 200, true)
+	arx, err := NewLSARXPredictor(arxData, LSARXModelParameters{AutoregressiveLags: 1, ExternalInputLags: 1, Intercept: true, StepSize: 1, Regressors: []Regressor{arxEvents}})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	arxModel, err := arx.Fit(0)
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		summary *FitSummary
+		events  *EventRegressor
+	}{
+		"LS":    {lsModel.Summary(), lsEvents},
+		"LSARX": {arxModel.Summary(), arxEvents},
+	} {
+		t.Run(name, func(t *testing.T) {
+			effects, err := tc.events.Effects(tc.summary)
+			if err != nil {
+				t.Fatalf("Effects() error = %v", err)
+			}
+			if len(effects) != 2 || effects[0].Event != "promotion" || effects[0].Offset != 0 || effects[1].Offset != 1 {
+				t.Fatalf("Effects() = %+v, want the promotion and the day after", effects)
+			}
+			if !approxEqual(effects[0].Estimate, 8, 0.02) || !approxEqual(effects[1].Estimate, -3, 0.05) {
+				t.Errorf("effects = %v and %v, want about 8 and -3", effects[0].Estimate, effects[1].Estimate)
+			}
+			if !(effects[0].Lower < 8 && 8 < effects[0].Upper) {
+				t.Errorf("promotion confidence interval [%v, %v] does not hold 8", effects[0].Lower, effects[0].Upper)
+			}
+		})
+	}
+
+	// The LS forecast applies the effects at the future occurrences: 2024-08-02 is the first
+	// Friday of August, step 214.
+	forecast, err := lsModel.Predict(20)
+	if err != nil {
+		t.Fatalf("Predict() error = %v", err)
+	}
+	for _, row := range forecast {
+		trend := 100 + 0.5*row[0]
+		want := trend
+		switch row[0] {
+		case 214:
+			want += 8
+		case 215:
+			want -= 3
+		}
+		if !approxEqual(row[1], want, 0.005) {
+			t.Errorf("forecast at step %v = %v, want about %v", row[0], row[1], want)
+		}
+	}
+
+	if _, err := lsEvents.Effects(&FitSummary{}); err == nil {
+		t.Error("Effects() of a summary without the events returned no error")
+	}
+}
@@ -0,0 +1,52 @@
+package ar
+
+import "fmt"
+
+// OutputMode selects which rows Predict returns.
+type OutputMode int
+
+const (
+	// FullOutput returns the historical part followed by the forecast values of Forecast.
+	FullOutput OutputMode = iota
+	// ForecastOnlyOutput returns only the numToPredict forecast values, those of Forecast.
+	ForecastOnlyOutput
+)
+
+// String returns the name of the output mode.
+func (o OutputMode) String() string {
+	switch o {
+	case FullOutput:
+		return "full"
+	case ForecastOnlyOutput:
+		return "forecast-only"
+	default:
+		return fmt.Sprintf("OutputMode(%d)", int(o))
+	}
+}
+
+// ForecastResult separates the in-sample and out-of-sample parts of a prediction.
+// Every slice holds [time, value] pairs, like the result of Predict.
+type ForecastResult struct {
+	Fitted    [][]float64 // In-sample one-step-ahead fitted values, for every data point the model has enough history for.
+	Residuals [][]float64 // In-sample residuals (data value - fitted value), aligned with Fitted.
+	Forecast  [][]float64 // Out-of-sample forecast values, starting from the end of the observed data.
+}
+
+// newForecastResult builds a ForecastResult from the fitted values of the data points starting at
+// index first, and the forecast values for the extended time values past the end of the data.
+func newForecastResult(dataValues []float64, pl []float64, fitted []float64, first int, forecast []float64) *ForecastResult {
+	result := &ForecastResult{
+		Fitted:    make([][]float64, len(fitted)),
+		Residuals: make([][]float64, len(fitted)),
+		Forecast:  make([][]float64, len(forecast)),
+	}
+	for i, v := range fitted {
+		t := pl[first+i]
+		result.Fitted[i] = []float64{t, v}
+		result.Residuals[i] = []float64{t, dataValues[first+i] - v}
+	}
+	for i, v := range forecast {
+		result.Forecast[i] = []float64{pl[len(dataValues)+i], v}
+	}
+	return result
+}
@@ -0,0 +1,97 @@
+package ar
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// SeasonalPeriod is a seasonal period of a FourierRegressor with its number of harmonics.
+type SeasonalPeriod struct {
+	Period    float64 // Length of the season in time values, e.g. 7 or 365.25 for daily data.
+	Harmonics int     // K: number of sin/cos pairs, at most below Period/2.
+}
+
+// FourierRegressor is a Regressor of the Fourier terms sin(2 pi k t / P) and cos(2 pi k t / P),
+// k = 1 .. K, of every seasonal period P at the time values t. A few harmonics model a smooth
+// seasonal shape of any length, such as the annual season of daily data, with fewer coefficients
+// than lags or dummies, and several periods can be combined. It serves as exogenous regressors of
+// an LSARX model and as basis functions of an LS model, and the terms continue over the forecast
+// horizon.
+type FourierRegressor struct {
+	Periods []SeasonalPeriod // Seasonal periods, in column order.
+}
+
+// NewFourierRegressor creates a regressor of the Fourier terms of the seasonal periods.
+func NewFourierRegressor(periods ...SeasonalPeriod) (*FourierRegressor, error) {
+	if len(periods) == 0 {
+		return nil, fmt.Errorf("fourier regressor needs at least one seasonal period")
+	}
+	for _, p := range periods {
+		if !(p.Period > 0) || math.IsInf(p.Period, 0) {
+			return nil, fmt.Errorf("seasonal period must be a positive number, period: %v", p.Period)
+		}
+		// The harmonic of k = P/2 alternates with the sampling, and its sine is zero on integer time values.
+		if p.Harmonics <= 0 || 2*float64(p.Harmonics) >= p.Period {
+			return nil, fmt.Errorf("harmonics must be a positive integer below half the period, period: %v, harmonics: %d", p.Period, p.Harmonics)
+		}
+	}
+	return &FourierRegressor{Periods: periods}, nil
+}
+
+// Names returns the labels of the terms, e.g. "sin(2pi*1t/7)" and "cos(2pi*1t/7)" for the first
+// harmonic of a period of 7.
+func (r *FourierRegressor) Names() []string {
+	var names []string
+	for _, p := range r.Periods {
+		for k := 1; k <= p.Harmonics; k++ {
+			names = append(names, fmt.Sprintf("sin(2pi*%dt/%g)", k, p.Period), fmt.Sprintf("cos(2pi*%dt/%g)", k, p.Period))
+		}
+	}
+	return names
+}
+
+// Evaluate writes the terms at time value t to dst.
+func (r *FourierRegressor) Evaluate(t float64, dst []float64) {
+	for _, p := range r.Periods {
+		// The phase is reduced to one period first, so large time values keep their precision.
+		phase := 2 * math.Pi * math.Mod(t, p.Period) / p.Period
+		for k := 1; k <= p.Harmonics; k++ {
+			dst[0], dst[1] = math.Sincos(float64(k) * phase)
+			dst = dst[2:]
+		}
+	}
+}
+
+// seasonalPeriodJSON is the JSON encoding of SeasonalPeriod.
+type seasonalPeriodJSON struct {
+	Period    float64 `json:"period"`
+	Harmonics int     `json:"harmonics"`
+}
+
+// MarshalJSON encodes the seasonal periods of the regressor.
+func (r *FourierRegressor) MarshalJSON() ([]byte, error) {
+	periods := make([]seasonalPeriodJSON, len(r.Periods))
+	for i, p := range r.Periods {
+		periods[i] = seasonalPeriodJSON(p)
+	}
+	return json.Marshal(periods)
+}
+
+// UnmarshalJSON decodes a regressor encoded by MarshalJSON.
+func (r *FourierRegressor) UnmarshalJSON(data []byte) error {
+	var v []seasonalPeriodJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	periods := make([]SeasonalPeriod, len(v))
+	for i, p := range v {
+		periods[i] = SeasonalPeriod(p)
+	}
+	regressor, err := NewFourierRegressor(periods...)
+	if err != nil {
+		return err
+	}
+	*r = *regressor
+	return nil
+}
@@ -0,0 +1,135 @@
+package ar
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestFourierRegressorEvaluate(t *testing.T) {
+	r, err := NewFourierRegressor(SeasonalPeriod{Period: 7, Harmonics: 2}, SeasonalPeriod{Period: 365.25, Harmonics: 1})
+	if err != nil {
+		t.Fatalf("NewFourierRegressor() error = %v", err)
+	}
+	names := r.Names()
+	want := []string{"sin(2pi*1t/7)", "cos(2pi*1t/7)", "sin(2pi*2t/7)", "cos(2pi*2t/7)", "sin(2pi*1t/365.25)", "cos(2pi*1t/365.25)"}
+	if len(names) != len(want) {
+		t.Fatalf("Names() = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("Names()[%d] = %q, want %q", i, names[i], want[i])
+		}
+	}
+
+	for _, tv := range []float64{0, 3, 10.5, -4, 1e6 + 2} {
+		got := make([]float64, len(names))
+		r.Evaluate(tv, got)
+		expected := []float64{
+			math.Sin(2 * math.Pi * tv / 7), math.Cos(2 * math.Pi * tv / 7),
+			math.Sin(4 * math.Pi * tv / 7), math.Cos(4 * math.Pi * tv / 7),
+			math.Sin(2 * math.Pi * tv / 365.25), math.Cos(2 * math.Pi * tv / 365.25),
+		}
+		for i := range expected {
+			if math.Abs(got[i]-expected[i]) > 1e-9 {
+				t.Errorf("Evaluate(%v)[%d] = %v, want %v", tv, i, got[i], expected[i])
+			}
+		}
+	}
+}
+
+func TestNewFourierRegressorErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		periods []SeasonalPeriod
+	}{
+		{"No periods", nil},
+		{"Zero period", []SeasonalPeriod{{Period: 0, Harmonics: 1}}},
+		{"Infinite period", []SeasonalPeriod{{Period: math.Inf(1), Harmonics: 1}}},
+		{"No harmonics", []SeasonalPeriod{{Period: 7, Harmonics: 0}}},
+		{"Harmonics at half the period", []SeasonalPeriod{{Period: 12, Harmonics: 6}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewFourierRegressor(tt.periods...); err == nil {
+				t.Error("NewFourierRegressor() returned no error")
+			}
+		})
+	}
+}
+
+// seasonalValue returns the deterministic part of the series of fourierData at time value t: a
+// weekly season of two harmonics and a smooth season of period 91.3.
+func seasonalValue(t float64) float64 {
+	return 10 + 3*math.Sin(2*math.Pi*t/7) + 2*math.Cos(4*math.Pi*t/7) + 5*math.Sin(2*math.Pi*t/91.3)
+}
+
+// fourierData returns n daily rows of the seasonal values with AR(1) noise, and the Fourier
+// regressor of its seasons.
+func fourierData(t *testing.T, n int) ([][]float64, *FourierRegressor) {
+	t.Helper()
+	r, err := NewFourierRegressor(SeasonalPeriod{Period: 7, Harmonics: 2}, SeasonalPeriod{Period: 91.3, Harmonics: 1})
+	if err != nil {
+		t.Fatalf("NewFourierRegressor() error = %v", err)
+	}
+	rnd := rand.New(rand.NewSource(8))
+	data := make([][]float64, n)
+	noise := 0.0
+	for i := range data {
+		noise = 0.5*noise + 0.1*rnd.NormFloat64()
+		data[i] = []float64{seasonalValue(float64(i)) + noise, float64(i)}
+	}
+	return data, r
+}
+
+func TestFourierRegressorForecasts(t *testing.T) {
+	data, fourier := fourierData(t, 400)
+
+	arx, err := NewLSARXPredictor(data, LSARXModelParameters{
+		AutoregressiveLags: 1,
+		ExternalInputLags:  1,
+		Intercept:          true,
+		StepSize:           1,
+		OutputMode:         ForecastOnlyOutput,
+		Regressors:         []Regressor{fourier},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	ls, err := NewLSPredictor(data, LSModelParameters{StepSize: 1, OutputMode: ForecastOnlyOutput, Regressors: []Regressor{fourier}})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+
+	// Beyond a few steps the forecasts follow the seasons alone, over the next quarter.
+	for name, predictor := range map[string]Forecaster{"LSARX": arx, "LS": ls} {
+		t.Run(name, func(t *testing.T) {
+			predicted, err := predictor.Predict(90)
+			if err != nil {
+				t.Fatalf("Predict() error = %v", err)
+			}
+			for h, row := range predicted[5:] {
+				if want := seasonalValue(row[0]); math.Abs(row[1]-want) > 0.25 {
+					t.Fatalf("forecast at step %d = %v, want about %v", h+5, row[1], want)
+				}
+			}
+		})
+	}
+
+	model, err := arx.Fit(0)
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+	summary := model.Summary()
+	labels := make(map[string]float64)
+	for _, c := range summary.Coefficients {
+		labels[c.Label] = c.Estimate
+	}
+	// The AR(1) model of the seasonal series scales the terms by 1 - 0.5 B, so the intercept is halved.
+	if !approxEqual(labels["const"], 5, 0.1) {
+		t.Errorf("intercept = %v, want about 5", labels["const"])
+	}
+	if _, ok := labels["sin(2pi*1t/91.3)"]; !ok {
+		t.Errorf("summary labels %v have no Fourier terms", summary.Coefficients)
+	}
+}
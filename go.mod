@@ -2,4 +2,9 @@ module github.com/MagdielCAS/go-autoregression
 
 go 1.23.6
 
-require gonum.org/v1/gonum v0.15.1 // indirect
+require gonum.org/v1/gonum v0.15.1
+
+require (
+	golang.org/x/exp v0.0.0-20231110203233-9a3e6036ecaa // indirect
+	golang.org/x/tools v0.15.0 // indirect
+)
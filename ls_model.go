@@ -13,7 +13,9 @@ import (
 
 // LSModelParameters holds the configuration for the Autoregressive model.
 type LSModelParameters struct {
-	StepSize float64 // StepSize: the historic 'delta Time' in the original data to use.
+	StepSize   float64     // StepSize: the historic 'delta Time' in the original data to use.
+	OutputMode OutputMode  // OutputMode: which rows Predict returns, the history and the forecast by default.
+	Regressors []Regressor // Regressors: deterministic inputs computed from the time values, added to the basis functions, none by default.
 }
 
 // Predictor struct encapsulates the AR model, it will store the data and params to be used for the prediction.
@@ -29,12 +31,59 @@ func NewLSPredictor(data [][]float64, params LSModelParameters) (*LSPredictor, e
 		return nil, fmt.Errorf("step size must be a positive number, step size: %f", params.StepSize)
 	}
 
+	if params.OutputMode < FullOutput || params.OutputMode > ForecastOnlyOutput {
+		return nil, fmt.Errorf("unknown output mode: %d", params.OutputMode)
+	}
+
+	if err := checkRegressors(params.Regressors); err != nil {
+		return nil, err
+	}
+
 	return &LSPredictor{Data: data, Params: params}, nil
 }
 
 // Predict performs AR model prediction for the given number of steps in the future.
 // It returns the predicted data as a slice of [time, value] pairs or an error if prediction fails.
 func (p *LSPredictor) Predict(numToPredict int) ([][]float64, error) {
+	Pl, _, yAp, err := p.fit(numToPredict)
+	if err != nil {
+		return [][]float64{}, err
+	}
+
+	// Create the result matrix
+	first := 0
+	if p.Params.OutputMode == ForecastOnlyOutput {
+		first = len(p.Data)
+	}
+	result := make([][]float64, 0, len(Pl)-first)
+	for i := first; i < len(Pl); i++ {
+		result = append(result, []float64{Pl[i], yAp.At(i, 0)})
+	}
+
+	return result, nil
+}
+
+// Forecast performs AR model prediction for the given number of steps in the future, keeping the
+// in-sample fitted values and residuals apart from the out-of-sample forecast values.
+func (p *LSPredictor) Forecast(numToPredict int) (*ForecastResult, error) {
+	Pl, _, yAp, err := p.fit(numToPredict)
+	if err != nil {
+		return nil, err
+	}
+
+	dataValues := make([]float64, len(p.Data))
+	for i, row := range p.Data {
+		dataValues[i] = row[0]
+	}
+	values := mat.Col(nil, 0, yAp)
+
+	return newForecastResult(dataValues, Pl, values[:len(p.Data)], 0, values[len(p.Data):]), nil
+}
+
+// fit estimates the model on the historical data and evaluates it on the historical time values
+// extended by numToPredict steps. It returns the extended time values, the model coefficients and
+// the fitted curve.
+func (p *LSPredictor) fit(numToPredict int) ([]float64, *mat.Dense, *mat.Dense, error) {
 	timeValues := make([]float64, len(p.Data))
 	dataValues := make([]float64, len(p.Data))
 	for i, row := range p.Data {
@@ -47,22 +96,10 @@ func (p *LSPredictor) Predict(numToPredict int) ([][]float64, error) {
 	Pl := extendTimeValues(timeValues, numToPredict, p.Params.StepSize)
 
 	// Create A matrix
-	A := mat.NewDense(len(P), 4, nil)
-	for i := 0; i < len(P); i++ {
-		A.Set(i, 0, math.Pow(P[i], 2))
-		A.Set(i, 1, P[i])
-		A.Set(i, 2, 1)
-		A.Set(i, 3, math.Cos(P[i]))
-	}
+	A := constructBasisMatrix(P, p.Params.Regressors)
 
 	// Create Atest matrix
-	Atest := mat.NewDense(len(Pl), 4, nil)
-	for i := 0; i < len(Pl); i++ {
-		Atest.Set(i, 0, math.Pow(Pl[i], 2))
-		Atest.Set(i, 1, Pl[i])
-		Atest.Set(i, 2, 1)
-		Atest.Set(i, 3, math.Cos(Pl[i]))
-	}
+	Atest := constructBasisMatrix(Pl, p.Params.Regressors)
 
 	// Calculate theta (th) using pseudo-inverse  (equivalent of np.linalg.pinv)
 	At := A.T()
@@ -72,7 +109,7 @@ func (p *LSPredictor) Predict(numToPredict int) ([][]float64, error) {
 	var ATAInv mat.Dense
 	err := ATAInv.Inverse(&ATA) // (A' * A)^-1
 	if err != nil {
-		return [][]float64{}, fmt.Errorf("error inverting ATA matrix: %w", err)
+		return nil, nil, nil, fmt.Errorf("error inverting ATA matrix: %w", err)
 	}
 
 	var AtAInvAt mat.Dense
@@ -89,13 +126,36 @@ func (p *LSPredictor) Predict(numToPredict int) ([][]float64, error) {
 	var yAp mat.Dense
 	yAp.Mul(Atest, &th)
 
-	// Create the result matrix
-	result := make([][]float64, len(Pl))
-	for i := 0; i < len(Pl); i++ {
-		result[i] = []float64{Pl[i], yAp.At(i, 0)} //fixed row/col indexing
+	return Pl, &th, &yAp, nil
+}
+
+// lsBasisNames names the basis functions evaluated by constructBasisMatrix, in column order.
+var lsBasisNames = []string{"t^2", "t", "1", "cos(t)"}
+
+// basisNames returns the names of the basis functions of the model: lsBasisNames followed by the
+// columns of the regressors.
+func (p LSModelParameters) basisNames() []string {
+	names := append([]string(nil), lsBasisNames...)
+	for _, r := range p.Regressors {
+		names = append(names, r.Names()...)
 	}
+	return names
+}
 
-	return result, nil
+// constructBasisMatrix evaluates the basis functions [P^2, P, 1, cos(P)] and the regressors at every time value.
+func constructBasisMatrix(P []float64, regressors []Regressor) *mat.Dense {
+	widths, nx := regressorWidths(regressors)
+	A := mat.NewDense(len(P), len(lsBasisNames)+nx, nil)
+	for i := 0; i < len(P); i++ {
+		A.Set(i, 0, math.Pow(P[i], 2))
+		A.Set(i, 1, P[i])
+		A.Set(i, 2, 1)
+		A.Set(i, 3, math.Cos(P[i]))
+		if nx > 0 {
+			evaluateRegressors(regressors, widths, P[i], A.RawRowView(i)[len(lsBasisNames):])
+		}
+	}
+	return A
 }
 
 // --------------------------------------------------
@@ -197,7 +197,7 @@ func TestLSConstructPhiMatrix(t *testing.T) {
 			dataValues:    []float64{1, 2, 3, 4, 5},
 			timeValues:    []float64{10, 20, 30, 40, 50},
 			na:            2,
-			nb:            1,
+			nb:            2,
 			m:             2,
 			expectedRows:  3, // Number of rows in dataValues (length of series) - m
 			expectedCols:  4, // (na + nb + 1) = 2 + 1 + 1 = 4
@@ -209,7 +209,7 @@ func TestLSConstructPhiMatrix(t *testing.T) {
 			dataValues:    []float64{1, 2, 3, 4, 5},
 			timeValues:    []float64{10, 20, 30, 40, 50},
 			na:            3,
-			nb:            1,
+			nb:            2,
 			m:             3,
 			expectedRows:  2,
 			expectedCols:  5, // (na + nb + 1) = 3 + 1 + 1 = 5
@@ -221,7 +221,7 @@ func TestLSConstructPhiMatrix(t *testing.T) {
 			dataValues:    []float64{1, 2, 3, 4, 5},
 			timeValues:    []float64{10, 20, 30, 40, 50},
 			na:            1,
-			nb:            3,
+			nb:            4,
 			m:             3,
 			expectedRows:  2,
 			expectedCols:  5, // (na + nb + 1) = 1 + 3 + 1 = 5
@@ -233,7 +233,7 @@ func TestLSConstructPhiMatrix(t *testing.T) {
 			dataValues:    []float64{1, 2},
 			timeValues:    []float64{10, 20},
 			na:            1,
-			nb:            0,
+			nb:            1,
 			m:             1,
 			expectedRows:  1,
 			expectedCols:  2, // na + nb + 1 = 1 + 0 + 1 = 2
@@ -245,7 +245,7 @@ func TestLSConstructPhiMatrix(t *testing.T) {
 			dataValues:    []float64{1},
 			timeValues:    []float64{10},
 			na:            0,
-			nb:            0,
+			nb:            1,
 			m:             0,
 			expectedRows:  1,
 			expectedCols:  1, // na + nb + 1 = 0 + 0 + 1 = 1
@@ -258,7 +258,7 @@ func TestLSConstructPhiMatrix(t *testing.T) {
 			dataValues:    []float64{},
 			timeValues:    []float64{},
 			na:            2, // Example
-			nb:            1, // Example
+			nb:            2, // Example
 			m:             2, // Example
 			expectedRows:  0,
 			expectedCols:  4,
@@ -269,7 +269,7 @@ func TestLSConstructPhiMatrix(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			phi := constructPhiMatrix(tc.dataValues, tc.timeValues, tc.na, tc.nb, tc.m)
+			phi := constructPhiMatrix(tc.dataValues, tc.timeValues, arxStructure{na: tc.na, nb: tc.nb}, tc.m)
 
 			if (phi == nil) == tc.expectNonNil {
 				t.Errorf("constructPhiMatrix() = nil, expected not nil: %v", tc.expectNonNil)
@@ -401,7 +401,7 @@ func TestLSPerformPrediction(t *testing.T) {
 			thCols:          1,
 			m:               1,
 			na:              2,
-			nb:              1,
+			nb:              2,
 			expectedLength:  3,
 			expectedInitial: []float64{1, 2, 0},
 		},
@@ -414,7 +414,7 @@ func TestLSPerformPrediction(t *testing.T) {
 			thCols:          1,
 			m:               1,
 			na:              2,
-			nb:              1,
+			nb:              2,
 			expectedLength:  2,
 			expectedInitial: []float64{1, 2},
 		},
@@ -424,7 +424,7 @@ func TestLSPerformPrediction(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			th := mat.NewDense(tc.thRows, tc.thCols, tc.thData)
 
-			yAp := performPrediction(tc.dataValues, tc.pl, th, tc.m, tc.na, tc.nb)
+			yAp := performPrediction(tc.dataValues, tc.pl, th, tc.m, arxStructure{na: tc.na, nb: tc.nb})
 
 			if len(yAp) != tc.expectedLength {
 				t.Errorf("performPrediction() len(yAp) = %d, want %d", len(yAp), tc.expectedLength)
@@ -441,3 +441,63 @@ func TestLSPerformPrediction(t *testing.T) {
 		})
 	}
 }
+
+func TestLSPredictForecastOnly(t *testing.T) {
+	numToPredict := 5
+	full, err := NewLSPredictor(sampleData, LSModelParameters{StepSize: 25})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	only, err := NewLSPredictor(sampleData, LSModelParameters{StepSize: 25, OutputMode: ForecastOnlyOutput})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+
+	fullData, err := full.Predict(numToPredict)
+	if err != nil {
+		t.Fatalf("Predict() error = %v", err)
+	}
+	onlyData, err := only.Predict(numToPredict)
+	if err != nil {
+		t.Fatalf("Predict() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(onlyData, fullData[len(sampleData):]) {
+		t.Errorf("Predict() forecast only = %v, want %v", onlyData, fullData[len(sampleData):])
+	}
+}
+
+func TestLSForecast(t *testing.T) {
+	numToPredict := 5
+	predictor, err := NewLSPredictor(sampleData, LSModelParameters{StepSize: 25})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+
+	result, err := predictor.Forecast(numToPredict)
+	if err != nil {
+		t.Fatalf("Forecast() error = %v", err)
+	}
+	predictedData, err := predictor.Predict(numToPredict)
+	if err != nil {
+		t.Fatalf("Predict() error = %v", err)
+	}
+
+	if len(result.Fitted) != len(sampleData) || len(result.Residuals) != len(sampleData) {
+		t.Fatalf("Forecast() returned %d fitted values and %d residuals, want %d", len(result.Fitted), len(result.Residuals), len(sampleData))
+	}
+	if !reflect.DeepEqual(result.Forecast, predictedData[len(sampleData):]) {
+		t.Errorf("Forecast() forecast = %v, want %v", result.Forecast, predictedData[len(sampleData):])
+	}
+	for i, row := range sampleData {
+		if math.Abs(result.Fitted[i][1]+result.Residuals[i][1]-row[0]) > 1e-6 {
+			t.Errorf("Forecast() fitted + residual at %d = %f, want %f", i, result.Fitted[i][1]+result.Residuals[i][1], row[0])
+		}
+	}
+}
+
+func TestNewLSPredictorInvalidOutputMode(t *testing.T) {
+	if _, err := NewLSPredictor(sampleData, LSModelParameters{StepSize: 25, OutputMode: OutputMode(7)}); err == nil {
+		t.Errorf("NewLSPredictor() expected an error for an unknown output mode")
+	}
+}
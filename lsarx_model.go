@@ -10,11 +10,83 @@ import (
 	"gonum.org/v1/gonum/mat"
 )
 
+// PredictionStrategy selects how multi-step forecasts beyond the historical data are produced.
+type PredictionStrategy int
+
+const (
+	// RecursiveStrategy fits a single one-step-ahead model and feeds each prediction back in as a lag.
+	RecursiveStrategy PredictionStrategy = iota
+	// DirectStrategy fits a separate model for each horizon h, using only observed values as lags.
+	DirectStrategy
+	// DirRecStrategy fits a separate model for each horizon h whose autoregressive part also
+	// takes the forecasts of the previous horizons as lags.
+	DirRecStrategy
+)
+
+// String returns the name of the prediction strategy.
+func (s PredictionStrategy) String() string {
+	switch s {
+	case RecursiveStrategy:
+		return "recursive"
+	case DirectStrategy:
+		return "direct"
+	case DirRecStrategy:
+		return "dirrec"
+	default:
+		return fmt.Sprintf("PredictionStrategy(%d)", int(s))
+	}
+}
+
 // LSARXModelParameters holds the configuration for the Autoregressive model.
 type LSARXModelParameters struct {
-	AutoregressiveLags int     // na: Number of past data points to consider for the autoregressive component.
-	ExternalInputLags  int     // nb: Number of past external input values to consider.
-	StepSize           float64 // StepSize: the historic 'delta Time' in the original data to use.
+	AutoregressiveLags int                    // na: Number of past data points to consider for the autoregressive component.
+	ExternalInputLags  int                    // nb: Number of external input values to consider, none when 0.
+	InputDelay         int                    // nk: Dead time of the external input, which enters as the nb terms u[t-nk] .. u[t-nk-nb+1].
+	Intercept          bool                   // Intercept: whether the model has a constant term.
+	StepSize           float64                // StepSize: the historic 'delta Time' in the original data to use.
+	Strategy           PredictionStrategy     // Strategy: how to forecast beyond the historical data, recursive by default.
+	OutputMode         OutputMode             // OutputMode: which rows Predict returns, the history and the forecast by default.
+	LatestRegime       *ChangePointParameters // LatestRegime: when set, fit only on the data from the last change point detected with these parameters.
+	Regressors         []Regressor            // Regressors: deterministic inputs computed from the time values, such as calendar features, none by default.
+}
+
+// Regressor is a deterministic input of an LSARX or LS model, computed from the time value of
+// every row, so it is known over the forecast horizon as well, unlike the lagged data values. Its
+// coefficients follow the other coefficients of the model, in the order of its names.
+type Regressor interface {
+	// Names returns the labels of the columns of the regressor.
+	Names() []string
+	// Evaluate writes the columns of the regressor at time value t to dst, of the length of Names.
+	Evaluate(t float64, dst []float64)
+}
+
+// checkRegressors checks that every regressor has at least one column.
+func checkRegressors(regressors []Regressor) error {
+	for i, r := range regressors {
+		if r == nil || len(r.Names()) == 0 {
+			return fmt.Errorf("regressor %d has no columns", i)
+		}
+	}
+	return nil
+}
+
+// regressorWidths returns the number of columns of every regressor and their total.
+func regressorWidths(regressors []Regressor) ([]int, int) {
+	widths := make([]int, len(regressors))
+	n := 0
+	for i, r := range regressors {
+		widths[i] = len(r.Names())
+		n += widths[i]
+	}
+	return widths, n
+}
+
+// evaluateRegressors writes the columns of the regressors, of the given widths, at time value t to dst.
+func evaluateRegressors(regressors []Regressor, widths []int, t float64, dst []float64) {
+	for i, r := range regressors {
+		r.Evaluate(t, dst[:widths[i]])
+		dst = dst[widths[i]:]
+	}
 }
 
 // Predictor struct encapsulates the AR model, it will store the data and params to be used for the prediction.
@@ -30,26 +102,261 @@ func NewLSARXPredictor(data [][]float64, params LSARXModelParameters) (*LSARXPre
 		return nil, fmt.Errorf("lags must be positive integers, autoregressive lags: %d, external input lags: %d", params.AutoregressiveLags, params.ExternalInputLags)
 	}
 
+	if params.InputDelay < 0 {
+		return nil, fmt.Errorf("input delay must not be negative, input delay: %d", params.InputDelay)
+	}
+
 	if params.StepSize <= 0 {
 		return nil, fmt.Errorf("step size must be a positive number, step size: %f", params.StepSize)
 	}
 
+	if params.Strategy < RecursiveStrategy || params.Strategy > DirRecStrategy {
+		return nil, fmt.Errorf("unknown prediction strategy: %d", params.Strategy)
+	}
+
+	if params.OutputMode < FullOutput || params.OutputMode > ForecastOnlyOutput {
+		return nil, fmt.Errorf("unknown output mode: %d", params.OutputMode)
+	}
+
+	if err := checkRegressors(params.Regressors); err != nil {
+		return nil, err
+	}
+
 	return &LSARXPredictor{Data: data, Params: params}, nil
 }
 
+// arxStructure describes the regressors of an LSARX model: na autoregressive lags, the nb external
+// inputs u[t-nk] .. u[t-nk-nb+1], an optional constant term and the nx columns of the deterministic
+// regressors, in this column order.
+type arxStructure struct {
+	na         int
+	nb         int
+	nk         int
+	intercept  bool
+	regressors []Regressor
+	widths     []int // Number of columns of every regressor.
+	nx         int
+}
+
+// structure returns the regressors of the one-step-ahead model of the parameters.
+func (p LSARXModelParameters) structure() arxStructure {
+	widths, nx := regressorWidths(p.Regressors)
+	return p.structureWith(widths, nx)
+}
+
+// structureWith returns the structure of the parameters with the given widths of the regressors and
+// their total nx.
+func (p LSARXModelParameters) structureWith(widths []int, nx int) arxStructure {
+	return arxStructure{
+		na:         p.AutoregressiveLags,
+		nb:         p.ExternalInputLags,
+		nk:         p.InputDelay,
+		intercept:  p.Intercept,
+		regressors: p.Regressors,
+		widths:     widths,
+		nx:         nx,
+	}
+}
+
+// numParams returns the number of coefficients of the model.
+func (s arxStructure) numParams() int {
+	return s.regressorColumn() + s.nx
+}
+
+// regressorColumn returns the column of the first deterministic regressor.
+func (s arxStructure) regressorColumn() int {
+	if s.intercept {
+		return s.na + s.nb + 1
+	}
+	return s.na + s.nb
+}
+
+// sameColumns reports whether the structures have the same columns, by regressor name.
+func (s arxStructure) sameColumns(o arxStructure) bool {
+	if s.na != o.na || s.nb != o.nb || s.nk != o.nk || s.intercept != o.intercept || s.nx != o.nx {
+		return false
+	}
+	a, b := s.regressorNames(), o.regressorNames()
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// regressorNames returns the names of the deterministic regressor columns.
+func (s arxStructure) regressorNames() []string {
+	names := make([]string, 0, s.nx)
+	for _, r := range s.regressors {
+		names = append(names, r.Names()...)
+	}
+	return names
+}
+
+// maxLag returns the number of leading data points the one-step-ahead model uses only as lags.
+func (s arxStructure) maxLag() int {
+	return max(s.na, s.maxInputLag())
+}
+
+// maxInputLag returns the lag of the oldest input term, nk+nb-1, or 0 without input terms.
+func (s arxStructure) maxInputLag() int {
+	if s.nb == 0 {
+		return 0
+	}
+	return s.nk + s.nb - 1
+}
+
+// withLags returns the structure with na autoregressive lags, as used by the direct strategies.
+func (s arxStructure) withLags(na int) arxStructure {
+	s.na = na
+	return s
+}
+
+// predictAt evaluates the model with coefficients th at index t, with the autoregressive lags
+// starting at lagStart. Lags before the start of the series are left out. The columns of the
+// regressors are evaluated into x, of length nx.
+func (s arxStructure) predictAt(yAp []float64, pl []float64, th *mat.Dense, t int, lagStart int, x []float64) float64 {
+	sum := 0.0
+
+	// Autoregressive part
+	for j := 0; j < s.na; j++ {
+		if k := t - lagStart - j; k >= 0 {
+			sum -= yAp[k] * th.At(j, 0)
+		}
+	}
+
+	// External input part
+	for j := 0; j < s.nb; j++ {
+		if k := t - s.nk - j; k >= 0 {
+			sum += pl[k] * th.At(s.na+j, 0)
+		}
+	}
+
+	if s.intercept {
+		sum += th.At(s.na+s.nb, 0)
+	}
+
+	// Deterministic regressors, known at every time value
+	if s.nx > 0 {
+		evaluateRegressors(s.regressors, s.widths, pl[t], x)
+		for j, v := range x {
+			sum += v * th.At(s.regressorColumn()+j, 0)
+		}
+	}
+	return sum
+}
+
 // Predict performs AR model prediction for the given number of steps in the future.
 // It returns the predicted data as a slice of [time, value] pairs or an error if prediction fails.
+// The forecast values are those of Forecast, which start from the observed data; with FullOutput
+// they follow the historical predictions, which run free from the first data points.
 func (p *LSARXPredictor) Predict(numToPredict int) ([][]float64, error) {
-	na := p.Params.AutoregressiveLags
-	nb := p.Params.ExternalInputLags
+	if numToPredict < 0 {
+		return nil, fmt.Errorf("number of values to predict must not be negative, got: %d", numToPredict)
+	}
+	if p.Params.OutputMode == ForecastOnlyOutput {
+		result, err := p.Forecast(numToPredict)
+		if err != nil {
+			return nil, err
+		}
+		return result.Forecast, nil
+	}
+	if p.Params.LatestRegime != nil {
+		regime, _, err := p.latestRegime()
+		if err != nil {
+			return nil, err
+		}
+		return regime.Predict(numToPredict)
+	}
+	s := p.Params.structure()
+
+	dataValues, pl, th, m, err := p.fit(numToPredict)
+	if err != nil {
+		return nil, err
+	}
+
+	// 5. Perform prediction using the computed 'theta' and the extended time values.
+	//    The direct strategies reuse the one-step-ahead model over the historical part only and
+	//    fit one model per horizon for the future values.
+	var yAp []float64 // yAp stands for "Y Approximate"
+	switch p.Params.Strategy {
+	case DirectStrategy, DirRecStrategy:
+		yAp = performPrediction(dataValues, pl[:len(dataValues)], th, m, s)
+		forecast, err := performDirectPrediction(dataValues, pl, s, numToPredict, p.Params.Strategy == DirRecStrategy)
+		if err != nil {
+			return nil, err
+		}
+		yAp = append(yAp, forecast...)
+	default:
+		yAp = performPrediction(dataValues, pl[:len(dataValues)], th, m, s)
+		yAp = append(yAp, performPrediction(dataValues, pl, th, len(dataValues)-1, s)[len(dataValues):]...)
+	}
+
+	// 6. Combine Pl and yAp into the result
+	// Combine the extended time values (pl) and predicted data values (yAp) into the final result.
+	result := make([][]float64, 0, len(pl))
+	for i := range pl {
+		result = append(result, []float64{pl[i], yAp[i]})
+	}
+
+	return result, nil
+}
+
+// Forecast performs AR model prediction for the given number of steps in the future, keeping the
+// in-sample fitted values and residuals apart from the out-of-sample forecast values.
+// The fitted values are one-step-ahead predictions from the observed lags, and unlike the historical
+// part of Predict, the forecast values always start from the observed data.
+func (p *LSARXPredictor) Forecast(numToPredict int) (*ForecastResult, error) {
+	if numToPredict < 0 {
+		return nil, fmt.Errorf("number of values to predict must not be negative, got: %d", numToPredict)
+	}
+	if p.Params.LatestRegime != nil {
+		regime, _, err := p.latestRegime()
+		if err != nil {
+			return nil, err
+		}
+		return regime.Forecast(numToPredict)
+	}
+	s := p.Params.structure()
+
+	dataValues, pl, th, m, err := p.fit(numToPredict)
+	if err != nil {
+		return nil, err
+	}
+
+	phi := constructPhiMatrix(dataValues, pl[:len(dataValues)], s, m)
+	var fitted mat.Dense
+	fitted.Mul(phi, th)
+
+	var forecast []float64
+	switch p.Params.Strategy {
+	case DirectStrategy, DirRecStrategy:
+		forecast, err = performDirectPrediction(dataValues, pl, s, numToPredict, p.Params.Strategy == DirRecStrategy)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		// Starting the recursion at the end of the data keeps every observed value as a lag.
+		forecast = performPrediction(dataValues, pl, th, len(dataValues)-1, s)[len(dataValues):]
+	}
+
+	return newForecastResult(dataValues, pl, mat.Col(nil, 0, &fitted), m, forecast), nil
+}
+
+// fit estimates the one-step-ahead model on the historical data. It returns the historical data values,
+// the time values extended by numToPredict steps, the model coefficients and the number of leading
+// data points used only as lags.
+func (p *LSARXPredictor) fit(numToPredict int) ([]float64, []float64, *mat.Dense, int, error) {
+	s := p.Params.structure()
 	stepSize := p.Params.StepSize
 
-	// Ensure m is at least na to have enough history
-	m := max(na, nb)
+	// Ensure m covers the autoregressive and the delayed input lags to have enough history
+	m := s.maxLag()
 
 	// Check if we have enough data
 	if len(p.Data) <= m {
-		return nil, fmt.Errorf("not enough data points for prediction, need at least %d points", m+1)
+		return nil, nil, nil, 0, fmt.Errorf("not enough data points for prediction, need at least %d points", m+1)
 	}
 
 	// 1. Separate the input and output data from the historical dataset.
@@ -65,108 +372,161 @@ func (p *LSARXPredictor) Predict(numToPredict int) ([][]float64, error) {
 	pl := extendTimeValues(timeValues, numToPredict, stepSize)
 
 	// 3. Construct the 'phi' matrix, which contains lagged values of both data and time.
-	phi := constructPhiMatrix(dataValues, timeValues, na, nb, m)
+	phi := constructPhiMatrix(dataValues, timeValues, s, m)
 	if phi == nil {
-		return nil, fmt.Errorf("failed to construct phi matrix")
+		return nil, nil, nil, 0, fmt.Errorf("failed to construct phi matrix")
 	}
 
 	// 4. Calculate 'theta' (th), coefficients of AR model, use Least Squares to estimate the vector th.
 	th, err := calculateTheta(phi, dataValues)
 	if err != nil {
-		return nil, fmt.Errorf("failed to calculate theta: %v", err)
+		return nil, nil, nil, 0, fmt.Errorf("failed to calculate theta: %v", err)
 	}
 
-	// 5. Perform prediction using the computed 'theta' and the extended time values.
-	yAp := performPrediction(dataValues, pl, th, m, na, nb) // yAp stands for "Y Approximate"
-
-	// 6. Combine Pl and yAp into the result
-	// Combine the extended time values (pl) and predicted data values (yAp) into the final result.
-	result := make([][]float64, len(pl))
-	for i := range pl {
-		result[i] = []float64{pl[i], yAp[i]}
-	}
-
-	return result, nil
+	return dataValues, pl, th, m, nil
 }
 
 // extendTimeValues extends the time values array with projected future time values, using a linear projection.
 func extendTimeValues(timeValues []float64, numToPredict int, stepSize float64) []float64 {
 	pl := make([]float64, len(timeValues)+numToPredict)
 	copy(pl, timeValues)
-	lastTimeValue := timeValues[len(timeValues)-1]
+	projectTimeValues(pl, len(timeValues), stepSize)
+	return pl
+}
 
-	for i := 0; i < numToPredict; i++ {
-		pl[len(timeValues)+i] = lastTimeValue + float64(i+1)*stepSize
+// projectTimeValues fills pl past its first n time values with their linear projection.
+func projectTimeValues(pl []float64, n int, stepSize float64) {
+	lastTimeValue := pl[n-1]
+	for i := n; i < len(pl); i++ {
+		pl[i] = lastTimeValue + float64(i-n+1)*stepSize
 	}
-
-	return pl
 }
 
 // constructPhiMatrix constructs phi matrix, which contains lagged values of both data and time.
 // dataValues: Y
 // timeValues: P
-func constructPhiMatrix(dataValues []float64, timeValues []float64, na int, nb int, m int) *mat.Dense {
-	dim := na + nb + 1
+func constructPhiMatrix(dataValues []float64, timeValues []float64, s arxStructure, m int) *mat.Dense {
+	return constructLaggedPhiMatrix(dataValues, timeValues, s, 1, m)
+}
+
+// constructLaggedPhiMatrix constructs the phi matrix with the autoregressive lags starting at
+// lagStart instead of 1, so row t holds -Y[t-lagStart] .. -Y[t-lagStart-na+1], P[t-nk] .. P[t-nk-nb+1]
+// and 1 for the intercept.
+func constructLaggedPhiMatrix(dataValues []float64, timeValues []float64, s arxStructure, lagStart int, m int) *mat.Dense {
+	dim := s.numParams()
 	numRows := len(dataValues) - m // Adjust the number of rows to account for the lag
 	if numRows <= 0 {
 		return nil
 	}
-	phi := mat.NewDense(numRows, dim, nil)
+	phi := make([]float64, numRows*dim)
+	fillPhiMatrix(phi, dataValues, timeValues, s, lagStart, m)
+	return mat.NewDense(numRows, dim, phi)
+}
 
-	// Fill the phi matrix starting from index m
-	for i := 0; i < numRows; i++ {
-		row := make([]float64, dim)
+// fillPhiMatrix writes the rows of the phi matrix of constructLaggedPhiMatrix to phi, row-major.
+func fillPhiMatrix(phi []float64, dataValues []float64, timeValues []float64, s arxStructure, lagStart int, m int) {
+	dim := s.numParams()
+	for i := 0; i < len(dataValues)-m; i++ {
+		row := phi[i*dim : (i+1)*dim]
 		actualIndex := i + m // Actual index in the original data
 
 		// Add -Y values (negative past data values)
-		for j := 1; j <= na; j++ {
-			if actualIndex-j >= 0 {
-				row[j-1] = -dataValues[actualIndex-j]
+		for j := 1; j <= s.na; j++ {
+			row[j-1] = 0
+			if lag := j + lagStart - 1; actualIndex-lag >= 0 {
+				row[j-1] = -dataValues[actualIndex-lag]
 			}
 		}
 
-		// Add P values (past time/external input values)
-		for j := 0; j <= nb; j++ {
-			if actualIndex-j >= 0 {
-				row[na+j] = timeValues[actualIndex-j]
+		// Add P values (past time/external input values), delayed by nk
+		for j := 0; j < s.nb; j++ {
+			row[s.na+j] = 0
+			if lag := s.nk + j; actualIndex-lag >= 0 {
+				row[s.na+j] = timeValues[actualIndex-lag]
 			}
 		}
 
-		phi.SetRow(i, row)
-	}
+		// Add the constant term
+		if s.intercept {
+			row[s.na+s.nb] = 1
+		}
 
-	return phi
+		// Add the deterministic regressors at the time value of the row
+		if s.nx > 0 {
+			evaluateRegressors(s.regressors, s.widths, timeValues[actualIndex], row[s.regressorColumn():])
+		}
+	}
 }
 
 // performPrediction performs the prediction based on theta and the dataValues
-func performPrediction(dataValues []float64, pl []float64, th *mat.Dense, m int, na int, nb int) []float64 {
+func performPrediction(dataValues []float64, pl []float64, th *mat.Dense, m int, s arxStructure) []float64 {
 	yAp := make([]float64, len(pl)) // yAp stands for "Y Approximate"
 
 	// Initialize predicted output with historical data for first 'm+1' values
 	copy(yAp, dataValues) // Copy initial values from dataValues
 
 	// Start prediction from m+1 to ensure we have enough history
+	x := make([]float64, s.nx)
 	for i := m + 1; i < len(pl); i++ {
-		sum := 0.0
+		yAp[i] = s.predictAt(yAp, pl, th, i, 1, x)
+	}
 
-		// Autoregressive part
-		for j := 1; j <= na; j++ {
-			if i-j >= 0 {
-				sum -= yAp[i-j] * th.At(j-1, 0)
-			}
-		}
+	return yAp
+}
 
-		// External input part
-		for j := 0; j <= nb; j++ {
-			if i-j >= 0 {
-				sum += pl[i-j] * th.At(na+j, 0)
-			}
+// performDirectPrediction forecasts numToPredict values past the end of dataValues, fitting one
+// least-squares model per horizon h with fitDirectTheta.
+// pl must hold the historical time values followed by the extended ones.
+func performDirectPrediction(dataValues []float64, pl []float64, s arxStructure, numToPredict int, dirRec bool) ([]float64, error) {
+	n := len(dataValues)
+	yAp := make([]float64, n+numToPredict)
+	copy(yAp, dataValues)
+
+	x := make([]float64, s.nx)
+	for h := 1; h <= numToPredict; h++ {
+		th, err := fitDirectTheta(dataValues, pl[:n], s, h, dirRec)
+		if err != nil {
+			return nil, err
 		}
+		yAp[n+h-1] = predictDirectStep(yAp, pl, th, n+h-1, s, h, dirRec, x)
+	}
 
-		yAp[i] = sum
+	return yAp[n:], nil
+}
+
+// directLags returns the first autoregressive lag and the number of autoregressive lags of the
+// model for horizon h. With dirRec unset (direct strategy) the model regresses Y[t] on
+// Y[t-h] .. Y[t-h-na+1]; with dirRec set it regresses Y[t] on Y[t-1] .. Y[t-h-na+1], where the
+// lags that fall in the future are the forecasts of the previous horizons.
+func directLags(na int, h int, dirRec bool) (lagStart int, lags int) {
+	if dirRec {
+		return 1, na + h - 1
 	}
+	return h, na
+}
 
-	return yAp
+// fitDirectTheta calculates the coefficients of the model for horizon h, see directLags.
+func fitDirectTheta(dataValues []float64, timeValues []float64, s arxStructure, h int, dirRec bool) (*mat.Dense, error) {
+	lagStart, lags := directLags(s.na, h, dirRec)
+	sh := s.withLags(lags)
+	m := max(lagStart+lags-1, s.maxInputLag())
+
+	phi := constructLaggedPhiMatrix(dataValues, timeValues, sh, lagStart, m)
+	if phi == nil || len(dataValues)-m < sh.numParams() {
+		return nil, fmt.Errorf("not enough data points for horizon %d, need at least %d points", h, m+sh.numParams())
+	}
+
+	th, err := calculateTheta(phi, dataValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate theta for horizon %d: %v", h, err)
+	}
+	return th, nil
+}
+
+// predictDirectStep evaluates the model for horizon h, fitted by fitDirectTheta, at index t.
+func predictDirectStep(yAp []float64, pl []float64, th *mat.Dense, t int, s arxStructure, h int, dirRec bool, x []float64) float64 {
+	lagStart, lags := directLags(s.na, h, dirRec)
+	return s.withLags(lags).predictAt(yAp, pl, th, t, lagStart, x)
 }
 
 // calculateTheta calculates the 'theta' (th)  coefficients of AR mode.
@@ -200,6 +560,19 @@ func calculateTheta(phi *mat.Dense, dataValues []float64) (th *mat.Dense, err er
 	return th, nil
 }
 
+// calculateResiduals returns the one-step-ahead residuals Y - phi * th, where y holds the data
+// values aligned with the rows of phi.
+func calculateResiduals(phi *mat.Dense, th *mat.Dense, y []float64) []float64 {
+	var fitted mat.Dense
+	fitted.Mul(phi, th)
+
+	residuals := make([]float64, len(y))
+	for i := range residuals {
+		residuals[i] = y[i] - fitted.At(i, 0)
+	}
+	return residuals
+}
+
 // --------------------------------------------------
 // Example Usage (in a separate `main` package):
 // --------------------------------------------------
@@ -3,6 +3,7 @@ package ar
 import (
 	"fmt"
 	"math"
+	"math/rand"
 	"reflect"
 	"testing"
 
@@ -21,7 +22,7 @@ func TestNewLSARXPredictor(t *testing.T) {
 			data: [][]float64{{1, 1}, {2, 2}},
 			params: LSARXModelParameters{
 				AutoregressiveLags: 2,
-				ExternalInputLags:  1,
+				ExternalInputLags:  2,
 				StepSize:           1.0,
 			},
 			expectedErr: false,
@@ -31,7 +32,7 @@ func TestNewLSARXPredictor(t *testing.T) {
 			data: [][]float64{{1, 1}, {2, 2}},
 			params: LSARXModelParameters{
 				AutoregressiveLags: 0,
-				ExternalInputLags:  1,
+				ExternalInputLags:  2,
 				StepSize:           1.0,
 			},
 			expectedErr: true,
@@ -41,7 +42,7 @@ func TestNewLSARXPredictor(t *testing.T) {
 			data: [][]float64{{1, 1}, {2, 2}},
 			params: LSARXModelParameters{
 				AutoregressiveLags: -1,
-				ExternalInputLags:  1,
+				ExternalInputLags:  2,
 				StepSize:           1.0,
 			},
 			expectedErr: true,
@@ -61,7 +62,7 @@ func TestNewLSARXPredictor(t *testing.T) {
 			data: [][]float64{{1, 1}, {2, 2}},
 			params: LSARXModelParameters{
 				AutoregressiveLags: 1,
-				ExternalInputLags:  1,
+				ExternalInputLags:  2,
 				StepSize:           0.0,
 			},
 			expectedErr: true,
@@ -71,7 +72,7 @@ func TestNewLSARXPredictor(t *testing.T) {
 			data: [][]float64{{1, 1}, {2, 2}},
 			params: LSARXModelParameters{
 				AutoregressiveLags: 1,
-				ExternalInputLags:  1,
+				ExternalInputLags:  2,
 				StepSize:           -1.0,
 			},
 			expectedErr: true,
@@ -124,7 +125,7 @@ func TestPredict(t *testing.T) {
 
 	params := LSARXModelParameters{
 		AutoregressiveLags: 3,
-		ExternalInputLags:  3,
+		ExternalInputLags:  4,
 		StepSize:           25,
 	}
 
@@ -235,7 +236,7 @@ func TestConstructPhiMatrix(t *testing.T) {
 			dataValues:    []float64{1, 2, 3, 4, 5},
 			timeValues:    []float64{10, 20, 30, 40, 50},
 			na:            2,
-			nb:            1,
+			nb:            2,
 			m:             2,
 			expectedRows:  3, // Number of rows in dataValues (length of series) - m
 			expectedCols:  4, // (na + nb + 1) = 2 + 1 + 1 = 4
@@ -247,7 +248,7 @@ func TestConstructPhiMatrix(t *testing.T) {
 			dataValues:    []float64{1, 2, 3, 4, 5},
 			timeValues:    []float64{10, 20, 30, 40, 50},
 			na:            3,
-			nb:            1,
+			nb:            2,
 			m:             3,
 			expectedRows:  2,
 			expectedCols:  5, // (na + nb + 1) = 3 + 1 + 1 = 5
@@ -259,7 +260,7 @@ func TestConstructPhiMatrix(t *testing.T) {
 			dataValues:    []float64{1, 2, 3, 4, 5},
 			timeValues:    []float64{10, 20, 30, 40, 50},
 			na:            1,
-			nb:            3,
+			nb:            4,
 			m:             3,
 			expectedRows:  2,
 			expectedCols:  5, // (na + nb + 1) = 1 + 3 + 1 = 5
@@ -271,7 +272,7 @@ func TestConstructPhiMatrix(t *testing.T) {
 			dataValues:    []float64{1, 2},
 			timeValues:    []float64{10, 20},
 			na:            1,
-			nb:            0,
+			nb:            1,
 			m:             1,
 			expectedRows:  1,
 			expectedCols:  2, // na + nb + 1 = 1 + 0 + 1 = 2
@@ -283,7 +284,7 @@ func TestConstructPhiMatrix(t *testing.T) {
 			dataValues:    []float64{1},
 			timeValues:    []float64{10},
 			na:            0,
-			nb:            0,
+			nb:            1,
 			m:             0,
 			expectedRows:  1,
 			expectedCols:  1, // na + nb + 1 = 0 + 0 + 1 = 1
@@ -296,7 +297,7 @@ func TestConstructPhiMatrix(t *testing.T) {
 			dataValues:    []float64{},
 			timeValues:    []float64{},
 			na:            2, // Example
-			nb:            1, // Example
+			nb:            2, // Example
 			m:             2, // Example
 			expectedRows:  0,
 			expectedCols:  4,
@@ -307,7 +308,7 @@ func TestConstructPhiMatrix(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			phi := constructPhiMatrix(tc.dataValues, tc.timeValues, tc.na, tc.nb, tc.m)
+			phi := constructPhiMatrix(tc.dataValues, tc.timeValues, arxStructure{na: tc.na, nb: tc.nb}, tc.m)
 
 			if (phi == nil) == tc.expectNonNil {
 				t.Errorf("constructPhiMatrix() = nil, expected not nil: %v", tc.expectNonNil)
@@ -439,7 +440,7 @@ func TestPerformPrediction(t *testing.T) {
 			thCols:          1,
 			m:               1,
 			na:              2,
-			nb:              1,
+			nb:              2,
 			expectedLength:  3,
 			expectedInitial: []float64{1, 2, 0},
 		},
@@ -452,7 +453,7 @@ func TestPerformPrediction(t *testing.T) {
 			thCols:          1,
 			m:               1,
 			na:              2,
-			nb:              1,
+			nb:              2,
 			expectedLength:  2,
 			expectedInitial: []float64{1, 2},
 		},
@@ -462,7 +463,7 @@ func TestPerformPrediction(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			th := mat.NewDense(tc.thRows, tc.thCols, tc.thData)
 
-			yAp := performPrediction(tc.dataValues, tc.pl, th, tc.m, tc.na, tc.nb)
+			yAp := performPrediction(tc.dataValues, tc.pl, th, tc.m, arxStructure{na: tc.na, nb: tc.nb})
 
 			if len(yAp) != tc.expectedLength {
 				t.Errorf("performPrediction() len(yAp) = %d, want %d", len(yAp), tc.expectedLength)
@@ -479,3 +480,299 @@ func TestPerformPrediction(t *testing.T) {
 		})
 	}
 }
+
+func TestPredictStrategies(t *testing.T) {
+	// y[t] = 0.5*y[t-1] + 2*u[t] + e[t] with u[t] = t + sin(t) and a small noise e[t]. Future inputs are
+	// extrapolated linearly by the predictor, so the expected values follow the same extrapolation.
+	n, numToPredict := 80, 6
+	rnd := rand.New(rand.NewSource(1))
+	data := make([][]float64, n)
+	expected := make([]float64, n+numToPredict)
+	y := 1.0
+	for i := range expected {
+		u := float64(i) + math.Sin(float64(i))
+		if i >= n {
+			u = data[n-1][1] + float64(i-n+1)
+		}
+		if i > 0 {
+			y = 0.5*y + 2*u
+		}
+		if i < n {
+			y += 0.01 * rnd.NormFloat64()
+			data[i] = []float64{y, u}
+		}
+		expected[i] = y
+	}
+
+	for _, strategy := range []PredictionStrategy{RecursiveStrategy, DirectStrategy, DirRecStrategy} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			predictor, err := NewLSARXPredictor(data, LSARXModelParameters{
+				AutoregressiveLags: 1,
+				ExternalInputLags:  2,
+				StepSize:           1,
+				Strategy:           strategy,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create predictor: %v", err)
+			}
+
+			predictedData, err := predictor.Predict(numToPredict)
+			if err != nil {
+				t.Fatalf("Predict() error = %v", err)
+			}
+			if len(predictedData) != n+numToPredict {
+				t.Fatalf("Predict() returned %d values, want %d", len(predictedData), n+numToPredict)
+			}
+			for i := n; i < n+numToPredict; i++ {
+				if math.Abs(predictedData[i][1]-expected[i]) > 0.5 {
+					t.Errorf("Predict()[%d] = %f, want %f", i, predictedData[i][1], expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPredictDirectNotEnoughData(t *testing.T) {
+	data := [][]float64{{1, 0}, {2, 1}, {4, 2}, {3, 3}, {5, 4}, {6, 5}}
+	predictor, err := NewLSARXPredictor(data, LSARXModelParameters{
+		AutoregressiveLags: 1,
+		ExternalInputLags:  2,
+		StepSize:           1,
+		Strategy:           DirectStrategy,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+
+	if _, err := predictor.Predict(10); err == nil {
+		t.Errorf("Predict() expected an error for a horizon longer than the data allows")
+	}
+}
+
+func TestNewLSARXPredictorInvalidStrategy(t *testing.T) {
+	_, err := NewLSARXPredictor([][]float64{{1, 1}, {2, 2}}, LSARXModelParameters{
+		AutoregressiveLags: 1,
+		ExternalInputLags:  2,
+		StepSize:           1,
+		Strategy:           PredictionStrategy(42),
+	})
+	if err == nil {
+		t.Errorf("NewLSARXPredictor() expected an error for an unknown strategy")
+	}
+}
+
+func TestConstructLaggedPhiMatrix(t *testing.T) {
+	dataValues := []float64{1, 2, 3, 4, 5}
+	timeValues := []float64{10, 20, 30, 40, 50}
+
+	phi := constructLaggedPhiMatrix(dataValues, timeValues, arxStructure{na: 1, nb: 2}, 2, 2)
+	expected := mat.NewDense(3, 3, []float64{
+		-1, 30, 20,
+		-2, 40, 30,
+		-3, 50, 40,
+	})
+	if !mat.Equal(phi, expected) {
+		t.Errorf("constructLaggedPhiMatrix() = %v, want %v", mat.Formatted(phi), mat.Formatted(expected))
+	}
+}
+
+func TestPredictForecastOnly(t *testing.T) {
+	numToPredict := 5
+	data := delayedARXData(200)
+	for _, strategy := range []PredictionStrategy{RecursiveStrategy, DirectStrategy, DirRecStrategy} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			params := LSARXModelParameters{AutoregressiveLags: 2, ExternalInputLags: 2, StepSize: 1, Strategy: strategy, OutputMode: ForecastOnlyOutput}
+			predictor, err := NewLSARXPredictor(data, params)
+			if err != nil {
+				t.Fatalf("Failed to create predictor: %v", err)
+			}
+
+			// The forecast-only output, the forecast values and the fitted model agree.
+			predicted, err := predictor.Predict(numToPredict)
+			if err != nil {
+				t.Fatalf("Predict() error = %v", err)
+			}
+			result, err := predictor.Forecast(numToPredict)
+			if err != nil {
+				t.Fatalf("Forecast() error = %v", err)
+			}
+			model, err := predictor.Fit(numToPredict)
+			if err != nil {
+				t.Fatalf("Fit() error = %v", err)
+			}
+			fitted, err := model.Predict(numToPredict)
+			if err != nil {
+				t.Fatalf("Predict() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(predicted, result.Forecast) {
+				t.Errorf("Predict() forecast only = %v, want %v", predicted, result.Forecast)
+			}
+			// The full output ends with the same forecast.
+			full := params
+			full.OutputMode = FullOutput
+			fullPredictor, err := NewLSARXPredictor(data, full)
+			if err != nil {
+				t.Fatalf("Failed to create predictor: %v", err)
+			}
+			all, err := fullPredictor.Predict(numToPredict)
+			if err != nil {
+				t.Fatalf("Predict() error = %v", err)
+			}
+			if tail := all[len(data):]; !reflect.DeepEqual(tail, result.Forecast) {
+				t.Errorf("Predict() full output ends with %v, want %v", tail, result.Forecast)
+			}
+			if len(fitted) != numToPredict {
+				t.Fatalf("model Predict() returned %d rows, want %d", len(fitted), numToPredict)
+			}
+			for i := range fitted {
+				if fitted[i][0] != predicted[i][0] || !approxEqual(fitted[i][1], predicted[i][1], 1e-9) {
+					t.Errorf("model Predict() row %d = %v, want %v", i, fitted[i], predicted[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPredictNegativeSteps(t *testing.T) {
+	data := delayedARXData(50)
+	for _, mode := range []OutputMode{FullOutput, ForecastOnlyOutput} {
+		predictor, err := NewLSARXPredictor(data, LSARXModelParameters{AutoregressiveLags: 2, ExternalInputLags: 2, StepSize: 1, OutputMode: mode})
+		if err != nil {
+			t.Fatalf("Failed to create predictor: %v", err)
+		}
+		if _, err := predictor.Predict(-1); err == nil {
+			t.Errorf("Predict(-1) with output mode %v returned no error", mode)
+		}
+		if _, err := predictor.Forecast(-1); err == nil {
+			t.Errorf("Forecast(-1) with output mode %v returned no error", mode)
+		}
+	}
+}
+
+func TestForecast(t *testing.T) {
+	numToPredict := 5
+	params := LSARXModelParameters{AutoregressiveLags: 3, ExternalInputLags: 3, StepSize: 25}
+	m := max(params.AutoregressiveLags, params.ExternalInputLags)
+
+	for _, strategy := range []PredictionStrategy{RecursiveStrategy, DirectStrategy, DirRecStrategy} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			params.Strategy = strategy
+			predictor, err := NewLSARXPredictor(sampleData, params)
+			if err != nil {
+				t.Fatalf("Failed to create predictor: %v", err)
+			}
+
+			result, err := predictor.Forecast(numToPredict)
+			if err != nil {
+				t.Fatalf("Forecast() error = %v", err)
+			}
+
+			if len(result.Fitted) != len(sampleData)-m || len(result.Residuals) != len(sampleData)-m {
+				t.Fatalf("Forecast() returned %d fitted values and %d residuals, want %d", len(result.Fitted), len(result.Residuals), len(sampleData)-m)
+			}
+			if len(result.Forecast) != numToPredict {
+				t.Fatalf("Forecast() returned %d forecast values, want %d", len(result.Forecast), numToPredict)
+			}
+			for i, row := range result.Fitted {
+				if row[0] != sampleData[m+i][1] {
+					t.Errorf("Forecast() fitted time at %d = %f, want %f", i, row[0], sampleData[m+i][1])
+				}
+				if math.Abs(row[1]+result.Residuals[i][1]-sampleData[m+i][0]) > 1e-6 {
+					t.Errorf("Forecast() fitted + residual at %d = %f, want %f", i, row[1]+result.Residuals[i][1], sampleData[m+i][0])
+				}
+			}
+			expectedTime := sampleData[len(sampleData)-1][1] + params.StepSize
+			if result.Forecast[0][0] != expectedTime {
+				t.Errorf("Forecast() first forecast time = %f, want %f", result.Forecast[0][0], expectedTime)
+			}
+		})
+	}
+}
+
+// delayedARXData simulates y[t] = 0.5*y[t-1] + 2*u[t-2] + 3 + e[t] with a random input u.
+func delayedARXData(n int) [][]float64 {
+	rnd := rand.New(rand.NewSource(9))
+	data := make([][]float64, n)
+	for i := range data {
+		data[i] = []float64{0, rnd.NormFloat64()}
+		if i >= 2 {
+			data[i][0] = 0.5*data[i-1][0] + 2*data[i-2][1] + 3 + 0.05*rnd.NormFloat64()
+		}
+	}
+	return data
+}
+
+func TestConstructPhiMatrixInputDelayAndIntercept(t *testing.T) {
+	dataValues := []float64{1, 2, 3, 4, 5}
+	timeValues := []float64{10, 20, 30, 40, 50}
+
+	phi := constructPhiMatrix(dataValues, timeValues, arxStructure{na: 1, nb: 2, nk: 2, intercept: true}, 3)
+	expected := mat.NewDense(2, 4, []float64{
+		-3, 20, 10, 1,
+		-4, 30, 20, 1,
+	})
+	if !mat.Equal(phi, expected) {
+		t.Errorf("constructPhiMatrix() = %v, want %v", mat.Formatted(phi), mat.Formatted(expected))
+	}
+}
+
+func TestLSARXInputDelayAndIntercept(t *testing.T) {
+	data := delayedARXData(300)
+	params := LSARXModelParameters{AutoregressiveLags: 1, ExternalInputLags: 1, InputDelay: 2, Intercept: true, StepSize: 1}
+
+	predictor, err := NewLSARXPredictor(data, params)
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	model, err := predictor.Fit(0)
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	want := []float64{-0.5, 2, 3}
+	for i, w := range want {
+		if !approxEqual(model.Theta[i], w, 0.05) {
+			t.Errorf("Theta = %v, want %v", model.Theta, want)
+			break
+		}
+	}
+	if len(model.History) != 2 {
+		t.Errorf("Fit() kept %d history rows, want 2", len(model.History))
+	}
+
+	s := model.Summary()
+	labels := []string{"y[t-1]", "u[t-2]", "const"}
+	for i, c := range s.Coefficients {
+		if c.Label != labels[i] {
+			t.Errorf("Summary() label %d = %q, want %q", i, c.Label, labels[i])
+		}
+	}
+
+	result, err := predictor.Forecast(3)
+	if err != nil {
+		t.Fatalf("Forecast() error = %v", err)
+	}
+	predicted, err := model.Predict(3)
+	if err != nil {
+		t.Fatalf("Predict() error = %v", err)
+	}
+	withVariance, _, err := model.PredictWithVariance(3)
+	if err != nil {
+		t.Fatalf("PredictWithVariance() error = %v", err)
+	}
+	// The first two forecasts only use observed inputs.
+	n := len(data)
+	if want := -model.Theta[0]*data[n-1][0] + model.Theta[1]*data[n-2][1] + model.Theta[2]; !approxEqual(result.Forecast[0][1], want, 1e-9) {
+		t.Errorf("Forecast()[0] = %v, want %v", result.Forecast[0][1], want)
+	}
+	for i := range predicted {
+		if !reflect.DeepEqual(predicted[i], result.Forecast[i]) || !approxEqual(withVariance[i][1], predicted[i][1], 1e-9) {
+			t.Errorf("forecast %d: Predict() = %v, Forecast() = %v, PredictWithVariance() = %v", i, predicted[i], result.Forecast[i], withVariance[i])
+		}
+	}
+
+	if _, err := NewLSARXPredictor(data, LSARXModelParameters{AutoregressiveLags: 1, InputDelay: -1, StepSize: 1}); err == nil {
+		t.Error("NewLSARXPredictor() with a negative input delay returned no error")
+	}
+}
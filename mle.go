@@ -0,0 +1,344 @@
+package ar
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/diff/fd"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/optimize"
+)
+
+// Optimizer selects the numerical method used to maximize a likelihood.
+type Optimizer int
+
+const (
+	// NelderMead is the derivative-free Nelder-Mead simplex method.
+	NelderMead Optimizer = iota
+	// BFGS is the Broyden-Fletcher-Goldfarb-Shanno quasi-Newton method, with numerical gradients.
+	BFGS
+	// LBFGS is the limited-memory BFGS method, with numerical gradients.
+	LBFGS
+)
+
+// String returns the name of the optimizer.
+func (o Optimizer) String() string {
+	switch o {
+	case NelderMead:
+		return "nelder-mead"
+	case BFGS:
+		return "bfgs"
+	case LBFGS:
+		return "lbfgs"
+	default:
+		return fmt.Sprintf("Optimizer(%d)", int(o))
+	}
+}
+
+// ParseOptimizer returns the optimizer with the given name, as returned by String.
+func ParseOptimizer(name string) (Optimizer, error) {
+	for o := NelderMead; o <= LBFGS; o++ {
+		if o.String() == name {
+			return o, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown optimizer: %q", name)
+}
+
+// method returns the gonum method of the optimizer.
+func (o Optimizer) method() (optimize.Method, error) {
+	switch o {
+	case NelderMead:
+		return &optimize.NelderMead{}, nil
+	case BFGS:
+		return &optimize.BFGS{}, nil
+	case LBFGS:
+		return &optimize.LBFGS{}, nil
+	default:
+		return nil, fmt.Errorf("unknown optimizer: %d", o)
+	}
+}
+
+// Bounds restricts a parameter to the interval (Lower, Upper). Infinite limits leave that side open.
+type Bounds struct {
+	Lower float64
+	Upper float64
+}
+
+// Unbounded returns bounds that do not restrict the parameter.
+func Unbounded() Bounds {
+	return Bounds{Lower: math.Inf(-1), Upper: math.Inf(1)}
+}
+
+// constrain maps an unconstrained value into the bounds.
+func (b Bounds) constrain(x float64) float64 {
+	lower, upper := !math.IsInf(b.Lower, -1), !math.IsInf(b.Upper, 1)
+	switch {
+	case lower && upper:
+		return b.Lower + (b.Upper-b.Lower)/(1+math.Exp(-x))
+	case lower:
+		return b.Lower + math.Exp(x)
+	case upper:
+		return b.Upper - math.Exp(x)
+	default:
+		return x
+	}
+}
+
+// unconstrain is the inverse of constrain. Values on or past a limit are moved just inside it.
+func (b Bounds) unconstrain(v float64) float64 {
+	lower, upper := !math.IsInf(b.Lower, -1), !math.IsInf(b.Upper, 1)
+	switch {
+	case lower && upper:
+		p := math.Min(math.Max((v-b.Lower)/(b.Upper-b.Lower), 1e-8), 1-1e-8)
+		return math.Log(p / (1 - p))
+	case lower:
+		return math.Log(math.Max(v-b.Lower, 1e-8))
+	case upper:
+		return math.Log(math.Max(b.Upper-v, 1e-8))
+	default:
+		return v
+	}
+}
+
+// ParameterBlock is a run of Length consecutive parameters starting at index Start.
+type ParameterBlock struct {
+	Start  int
+	Length int
+}
+
+// stationaryFromPartial maps partial autocorrelations in (-1, 1) to the coefficients a of a
+// stationary polynomial 1 + a1 z + .. + ak z^k, with the sign convention of the ARX models.
+func stationaryFromPartial(partial []float64) []float64 {
+	phi := make([]float64, len(partial))
+	prev := make([]float64, len(partial))
+	for k, r := range partial {
+		copy(prev, phi)
+		for j := 0; j < k; j++ {
+			phi[j] = prev[j] - r*prev[k-1-j]
+		}
+		phi[k] = r
+	}
+	a := make([]float64, len(phi))
+	for j := range phi {
+		a[j] = -phi[j]
+	}
+	return a
+}
+
+// partialFromStationary is the inverse of stationaryFromPartial. Partial autocorrelations of
+// non-stationary coefficients are clamped into (-1, 1).
+func partialFromStationary(a []float64) []float64 {
+	k := len(a)
+	phi := make([]float64, k)
+	for j := range a {
+		phi[j] = -a[j]
+	}
+	partial := make([]float64, k)
+	for ; k > 0; k-- {
+		r := math.Min(math.Max(phi[k-1], -1+1e-8), 1-1e-8)
+		partial[k-1] = r
+		prev := make([]float64, k-1)
+		for j := range prev {
+			prev[j] = (phi[j] + r*phi[k-2-j]) / (1 - r*r)
+		}
+		copy(phi, prev)
+	}
+	return partial
+}
+
+// MLEProblem describes a maximum likelihood estimation problem.
+type MLEProblem struct {
+	// LogLikelihood returns the log-likelihood of the parameters. NaN or -Inf mark infeasible parameters.
+	LogLikelihood func(params []float64) float64
+	// Initial holds the starting values of the parameters.
+	Initial []float64
+	// Bounds holds the bounds of every parameter, nil for none.
+	Bounds []Bounds
+	// Stationary lists blocks of parameters that are the coefficients a of a polynomial
+	// 1 + a1 z + .. + ak z^k constrained to have its roots outside the unit circle, such as the
+	// autoregressive part of the ARX models. Stationary parameters must not also be bounded.
+	Stationary []ParameterBlock
+}
+
+// MLEParameters holds the settings of maximum likelihood estimation.
+type MLEParameters struct {
+	Optimizer     Optimizer // Optimization method.
+	MaxIterations int       // Maximum number of optimizer iterations, 0 for the gonum default.
+}
+
+// MLEResult holds the outcome of maximum likelihood estimation.
+type MLEResult struct {
+	Params        []float64 // Estimated parameters.
+	StdErrors     []float64 // Standard errors from the inverse Hessian, NaN if it is not positive definite.
+	LogLikelihood float64   // Log-likelihood at the estimate.
+	Iterations    int       // Number of optimizer iterations.
+	Converged     bool      // Whether the optimizer reported convergence.
+}
+
+// mleTransform maps between the unconstrained optimizer space and the constrained parameters.
+type mleTransform struct {
+	bounds     []Bounds
+	stationary []ParameterBlock
+}
+
+func (t mleTransform) constrain(x []float64) []float64 {
+	params := make([]float64, len(x))
+	for i, v := range x {
+		if t.bounds != nil {
+			params[i] = t.bounds[i].constrain(v)
+		} else {
+			params[i] = v
+		}
+	}
+	for _, b := range t.stationary {
+		partial := make([]float64, b.Length)
+		for j := range partial {
+			partial[j] = math.Tanh(x[b.Start+j])
+		}
+		copy(params[b.Start:], stationaryFromPartial(partial))
+	}
+	return params
+}
+
+func (t mleTransform) unconstrain(params []float64) []float64 {
+	x := make([]float64, len(params))
+	for i, v := range params {
+		if t.bounds != nil {
+			x[i] = t.bounds[i].unconstrain(v)
+		} else {
+			x[i] = v
+		}
+	}
+	for _, b := range t.stationary {
+		for j, r := range partialFromStationary(params[b.Start : b.Start+b.Length]) {
+			x[b.Start+j] = math.Atanh(r)
+		}
+	}
+	return x
+}
+
+// validate checks the problem definition.
+func (p MLEProblem) validate() error {
+	if p.LogLikelihood == nil {
+		return fmt.Errorf("log-likelihood function must be defined")
+	}
+	n := len(p.Initial)
+	if n == 0 {
+		return fmt.Errorf("initial parameters must not be empty")
+	}
+	if p.Bounds != nil && len(p.Bounds) != n {
+		return fmt.Errorf("bounds have %d entries, expected %d", len(p.Bounds), n)
+	}
+	for i, b := range p.Bounds {
+		if !(b.Lower < b.Upper) {
+			return fmt.Errorf("bounds of parameter %d are empty: (%v, %v)", i, b.Lower, b.Upper)
+		}
+	}
+	for _, b := range p.Stationary {
+		if b.Start < 0 || b.Length <= 0 || b.Start+b.Length > n {
+			return fmt.Errorf("stationary block [%d, %d) is outside the %d parameters", b.Start, b.Start+b.Length, n)
+		}
+		for i := b.Start; p.Bounds != nil && i < b.Start+b.Length; i++ {
+			if !math.IsInf(p.Bounds[i].Lower, -1) || !math.IsInf(p.Bounds[i].Upper, 1) {
+				return fmt.Errorf("parameter %d is both bounded and stationary", i)
+			}
+		}
+	}
+	return nil
+}
+
+// MaximizeLikelihood estimates the parameters that maximize the log-likelihood of the problem.
+// The optimizer works on unconstrained values that are mapped into the bounds and stationary
+// regions, and the standard errors come from the numerical Hessian of the log-likelihood at
+// the estimate.
+func MaximizeLikelihood(problem MLEProblem, params MLEParameters) (*MLEResult, error) {
+	if err := problem.validate(); err != nil {
+		return nil, err
+	}
+	method, err := params.Optimizer.method()
+	if err != nil {
+		return nil, err
+	}
+	if params.MaxIterations < 0 {
+		return nil, fmt.Errorf("maximum iterations must not be negative, got: %d", params.MaxIterations)
+	}
+
+	transform := mleTransform{bounds: problem.Bounds, stationary: problem.Stationary}
+	negLogLik := func(x []float64) float64 {
+		ll := problem.LogLikelihood(transform.constrain(x))
+		if math.IsNaN(ll) || math.IsInf(ll, 0) {
+			return math.Inf(1)
+		}
+		return -ll
+	}
+	opt := optimize.Problem{Func: negLogLik}
+	if params.Optimizer != NelderMead {
+		opt.Grad = func(grad, x []float64) {
+			fd.Gradient(grad, negLogLik, x, nil)
+		}
+	}
+
+	x0 := transform.unconstrain(problem.Initial)
+	if math.IsInf(negLogLik(x0), 1) {
+		return nil, fmt.Errorf("log-likelihood is not finite at the initial parameters")
+	}
+	optResult, err := optimize.Minimize(opt, x0, &optimize.Settings{MajorIterations: params.MaxIterations}, method)
+	if optResult == nil || math.IsInf(optResult.F, 1) || math.IsNaN(optResult.F) {
+		if err == nil {
+			err = fmt.Errorf("no finite log-likelihood found")
+		}
+		return nil, fmt.Errorf("likelihood maximization failed: %w", err)
+	}
+
+	estimate := transform.constrain(optResult.X)
+	return &MLEResult{
+		Params:        estimate,
+		StdErrors:     hessianStdErrors(problem.LogLikelihood, estimate),
+		LogLikelihood: -optResult.F,
+		Iterations:    optResult.Stats.MajorIterations,
+		Converged:     err == nil && optResult.Status != optimize.IterationLimit,
+	}, nil
+}
+
+// hessianStdErrors returns the square roots of the diagonal of the inverse of the negative Hessian
+// of the log-likelihood, or NaN for every parameter if it is not positive definite.
+func hessianStdErrors(logLikelihood func([]float64) float64, estimate []float64) []float64 {
+	n := len(estimate)
+	hess := mat.NewSymDense(n, nil)
+	fd.Hessian(hess, func(x []float64) float64 { return -logLikelihood(x) }, estimate, nil)
+
+	stdErrors := make([]float64, n)
+	var chol mat.Cholesky
+	if ok := chol.Factorize(hess); !ok {
+		for i := range stdErrors {
+			stdErrors[i] = math.NaN()
+		}
+		return stdErrors
+	}
+	var cov mat.SymDense
+	if err := chol.InverseTo(&cov); err != nil {
+		for i := range stdErrors {
+			stdErrors[i] = math.NaN()
+		}
+		return stdErrors
+	}
+	for i := range stdErrors {
+		stdErrors[i] = math.Sqrt(cov.At(i, i))
+	}
+	return stdErrors
+}
+
+// ConditionalGaussianLogLikelihood returns the Gaussian log-likelihood of independent residuals
+// with variance sigma2, conditional on the initial values that produced them.
+func ConditionalGaussianLogLikelihood(residuals []float64, sigma2 float64) float64 {
+	if sigma2 <= 0 {
+		return math.Inf(-1)
+	}
+	sse := 0.0
+	for _, r := range residuals {
+		sse += r * r
+	}
+	n := float64(len(residuals))
+	return -0.5 * (n*math.Log(2*math.Pi*sigma2) + sse/sigma2)
+}
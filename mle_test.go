@@ -0,0 +1,102 @@
+package ar
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestMaximizeLikelihoodNormal(t *testing.T) {
+	rnd := rand.New(rand.NewSource(3))
+	samples := make([]float64, 400)
+	for i := range samples {
+		samples[i] = 2 + 1.5*rnd.NormFloat64()
+	}
+	mean, variance := 0.0, 0.0
+	for _, s := range samples {
+		mean += s
+	}
+	mean /= float64(len(samples))
+	for _, s := range samples {
+		variance += (s - mean) * (s - mean)
+	}
+	variance /= float64(len(samples))
+
+	problem := MLEProblem{
+		LogLikelihood: func(params []float64) float64 {
+			residuals := make([]float64, len(samples))
+			for i, s := range samples {
+				residuals[i] = s - params[0]
+			}
+			return ConditionalGaussianLogLikelihood(residuals, params[1])
+		},
+		Initial: []float64{0, 1},
+		Bounds:  []Bounds{Unbounded(), {Lower: 0, Upper: math.Inf(1)}},
+	}
+
+	for _, optimizer := range []Optimizer{NelderMead, BFGS, LBFGS} {
+		t.Run(optimizer.String(), func(t *testing.T) {
+			result, err := MaximizeLikelihood(problem, MLEParameters{Optimizer: optimizer})
+			if err != nil {
+				t.Fatalf("MaximizeLikelihood() error = %v", err)
+			}
+			if !approxEqual(result.Params[0], mean, 1e-3) || !approxEqual(result.Params[1], variance, 1e-3) {
+				t.Errorf("Params = %v, want [%v %v]", result.Params, mean, variance)
+			}
+			// The standard error of the mean is sqrt(variance / n).
+			if want := math.Sqrt(variance / float64(len(samples))); !approxEqual(result.StdErrors[0], want, 1e-3) {
+				t.Errorf("StdErrors[0] = %v, want %v", result.StdErrors[0], want)
+			}
+		})
+	}
+}
+
+func TestMaximizeLikelihoodValidation(t *testing.T) {
+	logLikelihood := func(params []float64) float64 { return -params[0] * params[0] }
+	tests := []struct {
+		name    string
+		problem MLEProblem
+		params  MLEParameters
+	}{
+		{"No likelihood", MLEProblem{Initial: []float64{1}}, MLEParameters{}},
+		{"No parameters", MLEProblem{LogLikelihood: logLikelihood}, MLEParameters{}},
+		{"Bounds length", MLEProblem{LogLikelihood: logLikelihood, Initial: []float64{1}, Bounds: []Bounds{}}, MLEParameters{}},
+		{"Empty bounds", MLEProblem{LogLikelihood: logLikelihood, Initial: []float64{1}, Bounds: []Bounds{{Lower: 1, Upper: 1}}}, MLEParameters{}},
+		{"Stationary block", MLEProblem{LogLikelihood: logLikelihood, Initial: []float64{1}, Stationary: []ParameterBlock{{Start: 0, Length: 2}}}, MLEParameters{}},
+		{"Unknown optimizer", MLEProblem{LogLikelihood: logLikelihood, Initial: []float64{1}}, MLEParameters{Optimizer: Optimizer(9)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := MaximizeLikelihood(tt.problem, tt.params); err == nil {
+				t.Error("MaximizeLikelihood() returned no error")
+			}
+		})
+	}
+}
+
+func TestStationaryTransform(t *testing.T) {
+	partial := []float64{0.5, -0.3, 0.8}
+	a := stationaryFromPartial(partial)
+	back := partialFromStationary(a)
+	for i := range partial {
+		if !approxEqual(back[i], partial[i], 1e-12) {
+			t.Errorf("partialFromStationary(stationaryFromPartial(%v)) = %v", partial, back)
+		}
+	}
+
+	// An AR(1) with a1 = -0.5 has partial autocorrelation 0.5.
+	if got := stationaryFromPartial([]float64{0.5}); got[0] != -0.5 {
+		t.Errorf("stationaryFromPartial([0.5]) = %v, want [-0.5]", got)
+	}
+}
+
+func TestParseOptimizer(t *testing.T) {
+	for o := NelderMead; o <= LBFGS; o++ {
+		if got, err := ParseOptimizer(o.String()); err != nil || got != o {
+			t.Errorf("ParseOptimizer(%q) = %v, %v", o.String(), got, err)
+		}
+	}
+	if _, err := ParseOptimizer("newton"); err == nil {
+		t.Error("ParseOptimizer(\"newton\") returned no error")
+	}
+}
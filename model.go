@@ -0,0 +1,242 @@
+package ar
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// TrainingMetadata describes the data a model was fitted on and the least-squares fit, see Summary.
+type TrainingMetadata struct {
+	NumObservations int       // Number of historical data points the model was fitted on.
+	TrainedAt       time.Time // Time the model was fitted, in UTC.
+	Sigma2          float64   // Residual variance of the in-sample one-step-ahead fit.
+	NumResiduals    int       // Number of observations in the regression, after the initial lags.
+	SSE             float64   // Sum of squared residuals of the fit.
+	TSS             float64   // Total sum of squares of the regression targets around their mean.
+	StdErrors       []float64 // Standard error of every coefficient, nil if the regressors are collinear.
+	ChangePoints    []int     // Training data rows that start a new regime, when the model was fitted on the latest one only.
+}
+
+// newTrainingMetadata builds the training metadata of the least-squares fit y = X th.
+func newTrainingMetadata(numObservations int, X *mat.Dense, th *mat.Dense, y []float64) TrainingMetadata {
+	n, k := X.Dims()
+	md := TrainingMetadata{NumObservations: numObservations, TrainedAt: time.Now().UTC(), NumResiduals: n}
+
+	mean := 0.0
+	for _, v := range y {
+		mean += v
+	}
+	mean /= float64(n)
+	for i, r := range calculateResiduals(X, th, y) {
+		md.SSE += r * r
+		md.TSS += (y[i] - mean) * (y[i] - mean)
+	}
+	if n-k <= 0 {
+		return md
+	}
+	md.Sigma2 = md.SSE / float64(n-k)
+
+	var xtx mat.Dense
+	xtx.Mul(X.T(), X)
+	md.setStdErrors(&xtx)
+	return md
+}
+
+// setStdErrors sets the standard errors of the coefficients from the normal matrix X' X of the fit
+// and Sigma2, leaving them nil if the regressors are collinear.
+func (md *TrainingMetadata) setStdErrors(xtx mat.Matrix) {
+	k, _ := xtx.Dims()
+	var inv mat.Dense
+	if err := inv.Inverse(xtx); err != nil {
+		return
+	}
+	md.StdErrors = make([]float64, k)
+	for j := range md.StdErrors {
+		md.StdErrors[j] = math.Sqrt(md.Sigma2 * inv.At(j, j))
+		if math.IsNaN(md.StdErrors[j]) || math.IsInf(md.StdErrors[j], 0) {
+			md.StdErrors = nil
+			break
+		}
+	}
+}
+
+// LSModel is a fitted LSPredictor. It keeps the estimated coefficients of the basis functions,
+// so it can forecast without the historical data.
+type LSModel struct {
+	Params   LSModelParameters // Model parameters.
+	Basis    []string          // Names of the basis functions, in coefficient order.
+	Theta    []float64         // Estimated coefficient of every basis function.
+	LastTime float64           // Last historical time value, the forecast starts one StepSize after it.
+	Metadata TrainingMetadata  // Training metadata.
+}
+
+// Fit estimates the model on the historical data and returns the fitted model.
+func (p *LSPredictor) Fit() (*LSModel, error) {
+	if len(p.Data) == 0 {
+		return nil, fmt.Errorf("not enough data points for prediction, need at least 1 point")
+	}
+
+	Pl, th, _, err := p.fit(0)
+	if err != nil {
+		return nil, err
+	}
+
+	dataValues := make([]float64, len(p.Data))
+	for i, row := range p.Data {
+		dataValues[i] = row[0]
+	}
+	theta := mat.Col(nil, 0, th)
+
+	return &LSModel{
+		Params:   p.Params,
+		Basis:    p.Params.basisNames(),
+		Theta:    theta,
+		LastTime: Pl[len(Pl)-1],
+		Metadata: newTrainingMetadata(len(p.Data), constructBasisMatrix(Pl, p.Params.Regressors), th, dataValues),
+	}, nil
+}
+
+// Predict forecasts the given number of steps past the end of the training data.
+// It returns the forecast as a slice of [time, value] pairs.
+func (m *LSModel) Predict(numToPredict int) ([][]float64, error) {
+	if numToPredict < 0 {
+		return nil, fmt.Errorf("number of values to predict must not be negative, got: %d", numToPredict)
+	}
+	if k := len(m.Params.basisNames()); len(m.Theta) != k {
+		return nil, fmt.Errorf("model has %d coefficients, expected %d", len(m.Theta), k)
+	}
+	if numToPredict == 0 {
+		return [][]float64{}, nil
+	}
+
+	times := extendTimeValues([]float64{m.LastTime}, numToPredict, m.Params.StepSize)[1:]
+	var yAp mat.Dense
+	yAp.Mul(constructBasisMatrix(times, m.Params.Regressors), mat.NewDense(len(m.Theta), 1, m.Theta))
+
+	result := make([][]float64, numToPredict)
+	for i, t := range times {
+		result[i] = []float64{t, yAp.At(i, 0)}
+	}
+	return result, nil
+}
+
+// LSARXModel is a fitted LSARXPredictor. It keeps the estimated coefficients together with the
+// tail of the training data the recursion starts from, so it can forecast without the rest of it.
+type LSARXModel struct {
+	Params        LSARXModelParameters // Model parameters.
+	Theta         []float64            // Estimated coefficients [a1 .. a_na, b0 .. b_nb] of the one-step-ahead model.
+	HorizonThetas [][]float64          // Coefficients of the model for every horizon, for the direct strategies only.
+	History       [][]float64          // Last max(na, nk+nb-1) rows of the training data: each row is [data_value, time_value].
+	Metadata      TrainingMetadata     // Training metadata.
+}
+
+// Fit estimates the model on the historical data and returns the fitted model.
+// The direct strategies fit one model per horizon step, up to maxHorizon, which also limits
+// how far the fitted model can forecast; the recursive strategy ignores maxHorizon.
+// With Params.LatestRegime set, the model is fitted on the latest regime only and its metadata
+// lists the detected change points.
+func (p *LSARXPredictor) Fit(maxHorizon int) (*LSARXModel, error) {
+	if p.Params.LatestRegime != nil {
+		regime, changePoints, err := p.latestRegime()
+		if err != nil {
+			return nil, err
+		}
+		model, err := regime.Fit(maxHorizon)
+		if err != nil {
+			return nil, err
+		}
+		model.Params = p.Params
+		model.Metadata.ChangePoints = changePoints
+		return model, nil
+	}
+	s := p.Params.structure()
+
+	dataValues, pl, th, m, err := p.fit(0)
+	if err != nil {
+		return nil, err
+	}
+
+	phi := constructPhiMatrix(dataValues, pl, s, m)
+	theta := mat.Col(nil, 0, th)
+
+	model := &LSARXModel{
+		Params:   p.Params,
+		Theta:    theta,
+		History:  make([][]float64, m),
+		Metadata: newTrainingMetadata(len(dataValues), phi, th, dataValues[m:]),
+	}
+	for i := range model.History {
+		model.History[i] = []float64{dataValues[len(dataValues)-m+i], pl[len(pl)-m+i]}
+	}
+
+	if p.Params.Strategy != RecursiveStrategy {
+		if maxHorizon <= 0 {
+			return nil, fmt.Errorf("max horizon must be a positive integer for the %s strategy, max horizon: %d", p.Params.Strategy, maxHorizon)
+		}
+		dirRec := p.Params.Strategy == DirRecStrategy
+		for h := 1; h <= maxHorizon; h++ {
+			thh, err := fitDirectTheta(dataValues, pl, s, h, dirRec)
+			if err != nil {
+				return nil, err
+			}
+			model.HorizonThetas = append(model.HorizonThetas, mat.Col(nil, 0, thh))
+		}
+	}
+
+	return model, nil
+}
+
+// Predict forecasts the given number of steps past the end of the training data.
+// It returns the forecast as a slice of [time, value] pairs, equal to the forecast of LSARXPredictor.Forecast.
+func (m *LSARXModel) Predict(numToPredict int) ([][]float64, error) {
+	s := m.Params.structure()
+
+	if numToPredict < 0 {
+		return nil, fmt.Errorf("number of values to predict must not be negative, got: %d", numToPredict)
+	}
+	if len(m.Theta) != s.numParams() {
+		return nil, fmt.Errorf("model has %d coefficients, expected %d", len(m.Theta), s.numParams())
+	}
+	if len(m.History) != s.maxLag() {
+		return nil, fmt.Errorf("model has %d history rows, expected %d", len(m.History), s.maxLag())
+	}
+	if m.Params.Strategy != RecursiveStrategy && numToPredict > len(m.HorizonThetas) {
+		return nil, fmt.Errorf("model was fitted for %d horizon steps, cannot predict %d", len(m.HorizonThetas), numToPredict)
+	}
+
+	n := len(m.History)
+	dataValues := make([]float64, n)
+	timeValues := make([]float64, n)
+	for i, row := range m.History {
+		dataValues[i] = row[0]
+		timeValues[i] = row[1]
+	}
+	pl := extendTimeValues(timeValues, numToPredict, m.Params.StepSize)
+
+	var yAp []float64
+	if m.Params.Strategy == RecursiveStrategy {
+		th := mat.NewDense(len(m.Theta), 1, m.Theta)
+		yAp = performPrediction(dataValues, pl, th, n-1, s)
+	} else {
+		dirRec := m.Params.Strategy == DirRecStrategy
+		yAp = make([]float64, len(pl))
+		copy(yAp, dataValues)
+		x := make([]float64, s.nx)
+		for h := 1; h <= numToPredict; h++ {
+			thh := m.HorizonThetas[h-1]
+			if _, lags := directLags(s.na, h, dirRec); len(thh) != s.withLags(lags).numParams() {
+				return nil, fmt.Errorf("model has %d coefficients for horizon %d, expected %d", len(thh), h, s.withLags(lags).numParams())
+			}
+			yAp[n+h-1] = predictDirectStep(yAp, pl, mat.NewDense(len(thh), 1, thh), n+h-1, s, h, dirRec, x)
+		}
+	}
+
+	result := make([][]float64, numToPredict)
+	for i := range result {
+		result[i] = []float64{pl[n+i], yAp[n+i]}
+	}
+	return result, nil
+}
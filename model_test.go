@@ -0,0 +1,109 @@
+package ar
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLSFit(t *testing.T) {
+	numToPredict := 5
+	predictor, err := NewLSPredictor(sampleData, LSModelParameters{StepSize: 25})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+
+	model, err := predictor.Fit()
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+	if len(model.Theta) != len(model.Basis) || model.Metadata.NumObservations != len(sampleData) || model.Metadata.Sigma2 <= 0 {
+		t.Errorf("Fit() returned an inconsistent model: %+v", model)
+	}
+
+	predicted, err := model.Predict(numToPredict)
+	if err != nil {
+		t.Fatalf("Predict() error = %v", err)
+	}
+	result, err := predictor.Forecast(numToPredict)
+	if err != nil {
+		t.Fatalf("Forecast() error = %v", err)
+	}
+	for i := range predicted {
+		if predicted[i][0] != result.Forecast[i][0] || !approxEqual(predicted[i][1], result.Forecast[i][1], 1e-6) {
+			t.Errorf("Predict()[%d] = %v, want %v", i, predicted[i], result.Forecast[i])
+		}
+	}
+}
+
+func TestLSARXFit(t *testing.T) {
+	numToPredict := 5
+
+	for _, strategy := range []PredictionStrategy{RecursiveStrategy, DirectStrategy, DirRecStrategy} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			predictor, err := NewLSARXPredictor(sampleData, LSARXModelParameters{
+				AutoregressiveLags: 3,
+				ExternalInputLags:  3,
+				StepSize:           25,
+				Strategy:           strategy,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create predictor: %v", err)
+			}
+
+			model, err := predictor.Fit(numToPredict)
+			if err != nil {
+				t.Fatalf("Fit() error = %v", err)
+			}
+			if len(model.History) != 3 || len(model.Theta) != 6 {
+				t.Errorf("Fit() returned %d history rows and %d coefficients, want 3 and 6", len(model.History), len(model.Theta))
+			}
+
+			predicted, err := model.Predict(numToPredict)
+			if err != nil {
+				t.Fatalf("Predict() error = %v", err)
+			}
+			result, err := predictor.Forecast(numToPredict)
+			if err != nil {
+				t.Fatalf("Forecast() error = %v", err)
+			}
+			if !reflect.DeepEqual(predicted, result.Forecast) {
+				t.Errorf("Predict() = %v, want %v", predicted, result.Forecast)
+			}
+
+			if strategy != RecursiveStrategy {
+				if _, err := model.Predict(numToPredict + 1); err == nil {
+					t.Errorf("Predict() expected an error past the fitted horizon")
+				}
+			}
+		})
+	}
+}
+
+func TestLSARXFitDirectRequiresHorizon(t *testing.T) {
+	predictor, err := NewLSARXPredictor(sampleData, LSARXModelParameters{
+		AutoregressiveLags: 1,
+		ExternalInputLags:  2,
+		StepSize:           25,
+		Strategy:           DirectStrategy,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+
+	if _, err := predictor.Fit(0); err == nil {
+		t.Errorf("Fit() expected an error without a max horizon")
+	}
+}
+
+// approxEqual reports whether a and b differ by at most tol, relative to their magnitude when it exceeds 1.
+func approxEqual(a, b, tol float64) bool {
+	d := a - b
+	if d < 0 {
+		d = -d
+	}
+	scale := 1.0
+	if a > scale || -a > scale {
+		scale = max(a, -a)
+	}
+	return d <= tol*scale
+}
@@ -0,0 +1,630 @@
+package ar
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// NARXBasis selects the nonlinear expansion of the lagged values of a NARX model.
+type NARXBasis int
+
+const (
+	// PolynomialBasis expands the lagged values into every monomial up to a total degree,
+	// cross-terms included, such as y[t-1]^2 and y[t-1]*u[t-1].
+	PolynomialBasis NARXBasis = iota
+	// RadialBasis adds Gaussian radial basis functions of the scaled lagged values, centered by
+	// k-means on the training data, to the constant and the linear terms.
+	RadialBasis
+)
+
+// String returns the name of the NARX basis.
+func (b NARXBasis) String() string {
+	switch b {
+	case PolynomialBasis:
+		return "polynomial"
+	case RadialBasis:
+		return "rbf"
+	default:
+		return fmt.Sprintf("NARXBasis(%d)", int(b))
+	}
+}
+
+// ParseNARXBasis returns the NARX basis with the given name, as returned by String.
+func ParseNARXBasis(name string) (NARXBasis, error) {
+	for b := PolynomialBasis; b <= RadialBasis; b++ {
+		if b.String() == name {
+			return b, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown NARX basis: %q", name)
+}
+
+// NARXModelParameters holds the configuration of a NARX model. The lagged values are the na
+// autoregressive lags y[t-1] .. y[t-na] and the external inputs u[t-nk] .. u[t-nk-nb+1], as for
+// LSARX, and the model is linear in the terms of their expansion.
+type NARXModelParameters struct {
+	AutoregressiveLags int        // na: Number of past data points to consider for the autoregressive component.
+	ExternalInputLags  int        // nb: Number of past external input values to consider.
+	InputDelay         int        // nk: Dead time of the external input, which enters as u[t-nk] .. u[t-nk-nb+1].
+	StepSize           float64    // StepSize: the historic 'delta Time' in the original data to use.
+	Basis              NARXBasis  // Basis: nonlinear expansion of the lagged values, polynomial by default.
+	Degree             int        // Degree: largest total degree of the polynomial terms, 2 when zero.
+	Centers            int        // Centers: number of radial basis functions, 10 when zero.
+	Width              float64    // Width: standard deviation of the radial basis functions in scaled lagged values, from the spread of the centers when zero.
+	Ridge              float64    // Ridge: L2 penalty on the coefficients of the terms other than the constant, 0 for ordinary least squares.
+	MaxTerms           int        // MaxTerms: when positive, forward regression keeps at most this many terms.
+	ERRTolerance       float64    // ERRTolerance: when positive, forward regression stops once the unexplained share of the output energy is below it.
+	OutputMode         OutputMode // OutputMode: which rows Predict returns, the history and the forecast by default.
+}
+
+// NARXPredictor fits a nonlinear ARX model: a linear combination of nonlinear terms of the lagged
+// values, estimated by regularized least squares. With MaxTerms or ERRTolerance set, the terms are
+// selected by forward regression with the error reduction ratio of orthogonal least squares
+// (OLS-ERR), which keeps the model sparse.
+type NARXPredictor struct {
+	Data   [][]float64         // Historical data: each row is [data_value, time_value].
+	Params NARXModelParameters // Model parameters.
+}
+
+// NewNARXPredictor creates a NARX predictor with the given data and parameters.
+func NewNARXPredictor(data [][]float64, params NARXModelParameters) (*NARXPredictor, error) {
+	if params.AutoregressiveLags <= 0 || params.ExternalInputLags < 0 {
+		return nil, fmt.Errorf("lags must be positive integers, autoregressive lags: %d, external input lags: %d", params.AutoregressiveLags, params.ExternalInputLags)
+	}
+	if params.InputDelay < 0 {
+		return nil, fmt.Errorf("input delay must not be negative, input delay: %d", params.InputDelay)
+	}
+	if params.StepSize <= 0 {
+		return nil, fmt.Errorf("step size must be a positive number, step size: %f", params.StepSize)
+	}
+	if params.Basis < PolynomialBasis || params.Basis > RadialBasis {
+		return nil, fmt.Errorf("unknown NARX basis: %d", params.Basis)
+	}
+	if params.Degree < 0 || params.Centers < 0 || params.MaxTerms < 0 {
+		return nil, fmt.Errorf("degree, centers and max terms must not be negative, degree: %d, centers: %d, max terms: %d", params.Degree, params.Centers, params.MaxTerms)
+	}
+	if params.Width < 0 || params.Ridge < 0 || params.ERRTolerance < 0 || params.ERRTolerance >= 1 {
+		return nil, fmt.Errorf("width and ridge must not be negative and the ERR tolerance must be in [0, 1), width: %v, ridge: %v, ERR tolerance: %v", params.Width, params.Ridge, params.ERRTolerance)
+	}
+	if params.OutputMode < FullOutput || params.OutputMode > ForecastOnlyOutput {
+		return nil, fmt.Errorf("unknown output mode: %d", params.OutputMode)
+	}
+	if err := checkColumns(data, 2, "data"); err != nil {
+		return nil, err
+	}
+	return &NARXPredictor{Data: data, Params: params}, nil
+}
+
+// structure returns the lag structure of the parameters.
+func (p NARXModelParameters) structure() arxStructure {
+	return arxStructure{na: p.AutoregressiveLags, nb: p.ExternalInputLags, nk: p.InputDelay}
+}
+
+// NARXTerm is a term of a NARX model: the product of the scaled lagged values raised to Exponents
+// or, with a Center, the Gaussian radial basis function of the scaled lagged values around it.
+type NARXTerm struct {
+	Exponents []int     // Power of every lagged value [y[t-1] .. y[t-na], u[t-nk] .. u[t-nk-nb+1]], for a monomial.
+	Center    []float64 // Center of a radial basis function in scaled lagged values, nil for a monomial.
+}
+
+// isConstant reports whether the term is the constant monomial.
+func (term NARXTerm) isConstant() bool {
+	if term.Center != nil {
+		return false
+	}
+	for _, e := range term.Exponents {
+		if e != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// evaluate returns the term at the lagged values x divided by their scales, with radial basis
+// functions of the width.
+func (term NARXTerm) evaluate(x []float64, scale []float64, width float64) float64 {
+	if term.Center != nil {
+		d2 := 0.0
+		for j, c := range term.Center {
+			d := x[j]/scale[j] - c
+			d2 += d * d
+		}
+		return math.Exp(-d2 / (2 * width * width))
+	}
+	v := 1.0
+	for j, e := range term.Exponents {
+		for range e {
+			v *= x[j] / scale[j]
+		}
+	}
+	return v
+}
+
+// label returns the label of the term, with index i among the radial basis functions.
+func (term NARXTerm) label(names []string, i int) string {
+	if term.Center != nil {
+		return fmt.Sprintf("rbf[%d]", i)
+	}
+	var factors []string
+	for j, e := range term.Exponents {
+		switch {
+		case e == 1:
+			factors = append(factors, names[j])
+		case e > 1:
+			factors = append(factors, fmt.Sprintf("%s^%d", names[j], e))
+		}
+	}
+	if len(factors) == 0 {
+		return "const"
+	}
+	return strings.Join(factors, "*")
+}
+
+// narxLagNames returns the labels of the lagged values of the structure.
+func narxLagNames(s arxStructure) []string {
+	names := make([]string, 0, s.na+s.nb)
+	for j := 1; j <= s.na; j++ {
+		names = append(names, fmt.Sprintf("y[t-%d]", j))
+	}
+	for j := s.nk; j < s.nk+s.nb; j++ {
+		if j == 0 {
+			names = append(names, "u[t]")
+		} else {
+			names = append(names, fmt.Sprintf("u[t-%d]", j))
+		}
+	}
+	return names
+}
+
+// narxLags writes the lagged values of index t of the series y and u to x.
+func narxLags(s arxStructure, y []float64, u []float64, t int, x []float64) {
+	for j := 0; j < s.na; j++ {
+		x[j] = y[t-1-j]
+	}
+	for j := 0; j < s.nb; j++ {
+		x[s.na+j] = u[t-s.nk-j]
+	}
+}
+
+// monomials returns the exponents of every monomial of d variables up to the total degree, by
+// increasing degree: the constant, the linear terms, then the products.
+func monomials(d int, degree int) [][]int {
+	var terms [][]int
+	var extend func(exponents []int, from int, remaining int)
+	extend = func(exponents []int, from int, remaining int) {
+		if remaining == 0 {
+			terms = append(terms, append([]int(nil), exponents...))
+			return
+		}
+		for j := from; j < d; j++ {
+			exponents[j]++
+			extend(exponents, j, remaining-1)
+			exponents[j]--
+		}
+	}
+	for k := 0; k <= degree; k++ {
+		extend(make([]int, d), 0, k)
+	}
+	return terms
+}
+
+// squaredDistance returns the squared Euclidean distance between a and b.
+func squaredDistance(a, b []float64) float64 {
+	s := 0.0
+	for j := range a {
+		s += (a[j] - b[j]) * (a[j] - b[j])
+	}
+	return s
+}
+
+// kMeansCenters returns k centers of the rows by Lloyd's algorithm, started from the row closest
+// to the mean followed by the rows farthest from the centers chosen before.
+func kMeansCenters(rows [][]float64, k int) [][]float64 {
+	d := len(rows[0])
+
+	mean := make([]float64, d)
+	for _, r := range rows {
+		for j, v := range r {
+			mean[j] += v / float64(len(rows))
+		}
+	}
+	nearest := make([]float64, len(rows))
+	first := 0
+	for i, r := range rows {
+		if squaredDistance(r, mean) < squaredDistance(rows[first], mean) {
+			first = i
+		}
+	}
+	centers := [][]float64{append([]float64(nil), rows[first]...)}
+	for i, r := range rows {
+		nearest[i] = squaredDistance(r, centers[0])
+	}
+	for len(centers) < k {
+		far := 0
+		for i := range rows {
+			if nearest[i] > nearest[far] {
+				far = i
+			}
+		}
+		centers = append(centers, append([]float64(nil), rows[far]...))
+		for i, r := range rows {
+			nearest[i] = math.Min(nearest[i], squaredDistance(r, centers[len(centers)-1]))
+		}
+	}
+
+	assignment := make([]int, len(rows))
+	for range 50 {
+		changed := false
+		for i, r := range rows {
+			best := 0
+			for c := range centers {
+				if squaredDistance(r, centers[c]) < squaredDistance(r, centers[best]) {
+					best = c
+				}
+			}
+			if best != assignment[i] {
+				assignment[i], changed = best, true
+			}
+		}
+		counts := make([]int, k)
+		sums := make([][]float64, k)
+		for c := range sums {
+			sums[c] = make([]float64, d)
+		}
+		for i, r := range rows {
+			counts[assignment[i]]++
+			for j, v := range r {
+				sums[assignment[i]][j] += v
+			}
+		}
+		for c := range centers {
+			if counts[c] > 0 {
+				for j := range centers[c] {
+					centers[c][j] = sums[c][j] / float64(counts[c])
+				}
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	return centers
+}
+
+// forwardRegression selects columns of the candidate matrix by orthogonal least squares: every
+// step orthogonalizes the remaining candidates against the selected ones and keeps the one with the
+// largest error reduction ratio ERR = (w'y)^2 / (w'w y'y), the share of the output energy it
+// explains. It stops after maxTerms columns, when positive, or once the unexplained share
+// 1 - sum(ERR) is below tolerance, when positive, or when every candidate is collinear with the
+// selection. It returns the selected columns and their ERR, in selection order.
+func forwardRegression(candidates *mat.Dense, y []float64, maxTerms int, tolerance float64) ([]int, []float64) {
+	rows, k := candidates.Dims()
+	if maxTerms <= 0 || maxTerms > k {
+		maxTerms = k
+	}
+	yv := mat.NewVecDense(rows, y)
+	energy := mat.Dot(yv, yv)
+	if energy == 0 {
+		return nil, nil
+	}
+
+	var selected []int
+	var ratios []float64
+	var basis []*mat.VecDense
+	taken := make([]bool, k)
+	explained := 0.0
+	w := mat.NewVecDense(rows, nil)
+	for len(selected) < maxTerms {
+		best, bestERR := -1, 0.0
+		var bestW *mat.VecDense
+		for j := 0; j < k; j++ {
+			if taken[j] {
+				continue
+			}
+			p := candidates.ColView(j)
+			w.CopyVec(p)
+			for _, q := range basis {
+				w.AddScaledVec(w, -mat.Dot(q, p)/mat.Dot(q, q), q)
+			}
+			ww := mat.Dot(w, w)
+			if ww <= 1e-10*mat.Dot(p, p) {
+				continue
+			}
+			g := mat.Dot(w, yv)
+			if err := g * g / (ww * energy); best < 0 || err > bestERR {
+				best, bestERR = j, err
+				bestW = mat.VecDenseCopyOf(w)
+			}
+		}
+		if best < 0 {
+			break
+		}
+		taken[best] = true
+		selected = append(selected, best)
+		ratios = append(ratios, bestERR)
+		basis = append(basis, bestW)
+		explained += bestERR
+		if tolerance > 0 && 1-explained < tolerance {
+			break
+		}
+	}
+	return selected, ratios
+}
+
+// NARXModel is a fitted NARXPredictor. It keeps the selected terms and their coefficients together
+// with the tail of the training data the recursion starts from.
+type NARXModel struct {
+	Params   NARXModelParameters // Model parameters.
+	Terms    []NARXTerm          // Terms of the model, in coefficient order.
+	Theta    []float64           // Estimated coefficient of every term.
+	Scale    []float64           // Standard deviation of every lagged value in the training data, which scales them for the terms.
+	Width    float64             // Width of the radial basis functions, in scaled lagged values.
+	ERR      []float64           // Error reduction ratio of every term, nil without forward regression.
+	History  [][]float64         // Last max(na, nk+nb-1) rows of the training data: each row is [data_value, time_value].
+	Metadata TrainingMetadata    // Training metadata.
+}
+
+// Fit expands the lagged values of the data, selects the terms and estimates their coefficients.
+func (p *NARXPredictor) Fit() (*NARXModel, error) {
+	s := p.Params.structure()
+	m := s.maxLag()
+	n := len(p.Data) - m
+	if n <= 0 {
+		return nil, fmt.Errorf("not enough data points for prediction, need at least %d points", m+1)
+	}
+	y := make([]float64, len(p.Data))
+	u := make([]float64, len(p.Data))
+	for i, row := range p.Data {
+		y[i], u[i] = row[0], row[1]
+	}
+
+	// Lagged values of every regression row and their scales.
+	d := s.na + s.nb
+	lags := make([][]float64, n)
+	scale := make([]float64, d)
+	for i := range lags {
+		lags[i] = make([]float64, d)
+		narxLags(s, y, u, m+i, lags[i])
+	}
+	for j := range scale {
+		mean, sq := 0.0, 0.0
+		for _, x := range lags {
+			mean += x[j] / float64(n)
+		}
+		for _, x := range lags {
+			sq += (x[j] - mean) * (x[j] - mean)
+		}
+		if scale[j] = math.Sqrt(sq / float64(n)); scale[j] == 0 {
+			scale[j] = 1
+		}
+	}
+
+	model := &NARXModel{Params: p.Params, Scale: scale}
+	switch p.Params.Basis {
+	case PolynomialBasis:
+		degree := p.Params.Degree
+		if degree == 0 {
+			degree = 2
+		}
+		for _, e := range monomials(d, degree) {
+			model.Terms = append(model.Terms, NARXTerm{Exponents: e})
+		}
+	case RadialBasis:
+		k := p.Params.Centers
+		if k == 0 {
+			k = 10
+		}
+		if k > n {
+			return nil, fmt.Errorf("not enough data points for %d radial basis functions, need at least %d points", k, m+k)
+		}
+		for _, e := range monomials(d, 1) {
+			model.Terms = append(model.Terms, NARXTerm{Exponents: e})
+		}
+		scaled := make([][]float64, n)
+		for i, x := range lags {
+			scaled[i] = make([]float64, d)
+			for j, v := range x {
+				scaled[i][j] = v / scale[j]
+			}
+		}
+		centers := kMeansCenters(scaled, k)
+		for _, c := range centers {
+			model.Terms = append(model.Terms, NARXTerm{Center: c})
+		}
+		// Without a width, use the largest distance between centers over sqrt(2k).
+		if model.Width = p.Params.Width; model.Width == 0 {
+			for a := range centers {
+				for b := a + 1; b < k; b++ {
+					model.Width = math.Max(model.Width, math.Sqrt(squaredDistance(centers[a], centers[b])))
+				}
+			}
+			if model.Width /= math.Sqrt(2 * float64(k)); model.Width == 0 {
+				model.Width = 1
+			}
+		}
+	}
+
+	candidates := mat.NewDense(n, len(model.Terms), nil)
+	for i, x := range lags {
+		for j, term := range model.Terms {
+			candidates.Set(i, j, term.evaluate(x, scale, model.Width))
+		}
+	}
+	X := candidates
+	if p.Params.MaxTerms > 0 || p.Params.ERRTolerance > 0 {
+		selected, ratios := forwardRegression(candidates, y[m:], p.Params.MaxTerms, p.Params.ERRTolerance)
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("forward regression selected no terms")
+		}
+		terms := make([]NARXTerm, len(selected))
+		X = mat.NewDense(n, len(selected), nil)
+		for c, j := range selected {
+			terms[c] = model.Terms[j]
+			X.SetCol(c, mat.Col(nil, j, candidates))
+		}
+		model.Terms, model.ERR = terms, ratios
+	}
+
+	th, err := ridgeTheta(X, y[m:], model.Terms, p.Params.Ridge)
+	if err != nil {
+		return nil, err
+	}
+	model.Theta = mat.Col(nil, 0, th)
+	model.History = copyRows(p.Data[len(p.Data)-m:])
+	model.Metadata = newTrainingMetadata(len(p.Data), X, th, y[m:])
+	return model, nil
+}
+
+// ridgeTheta solves the regularized normal equations (X'X + ridge D) th = X'y, where D is the
+// identity without the constant term, which is not penalized.
+func ridgeTheta(X *mat.Dense, y []float64, terms []NARXTerm, ridge float64) (*mat.Dense, error) {
+	rows, _ := X.Dims()
+	var a mat.Dense
+	a.Mul(X.T(), X)
+	for j, term := range terms {
+		if !term.isConstant() {
+			a.Set(j, j, a.At(j, j)+ridge)
+		}
+	}
+	var b, th mat.Dense
+	b.Mul(X.T(), mat.NewDense(rows, 1, y))
+	if err := th.Solve(&a, &b); err != nil {
+		return nil, fmt.Errorf("failed to calculate theta: %v", err)
+	}
+	return &th, nil
+}
+
+// predictAt returns the model at index t of the series y and u.
+func (m *NARXModel) predictAt(s arxStructure, y []float64, u []float64, t int, x []float64) float64 {
+	narxLags(s, y, u, t, x)
+	v := 0.0
+	for j, term := range m.Terms {
+		v += m.Theta[j] * term.evaluate(x, m.Scale, m.Width)
+	}
+	return v
+}
+
+// forecast extends the series y, whose first n values are known, over the time values u by
+// feeding every prediction back in as a lag.
+func (m *NARXModel) forecast(y []float64, u []float64, n int) {
+	s := m.Params.structure()
+	x := make([]float64, s.na+s.nb)
+	for t := n; t < len(u); t++ {
+		y[t] = m.predictAt(s, y, u, t, x)
+	}
+}
+
+// Predict forecasts the given number of steps past the end of the training data.
+// It returns the forecast as a slice of [time, value] pairs.
+func (m *NARXModel) Predict(numToPredict int) ([][]float64, error) {
+	if numToPredict < 0 {
+		return nil, fmt.Errorf("number of values to predict must not be negative, got: %d", numToPredict)
+	}
+	if len(m.Theta) != len(m.Terms) {
+		return nil, fmt.Errorf("model has %d coefficients, expected %d", len(m.Theta), len(m.Terms))
+	}
+	if s := m.Params.structure(); len(m.History) != s.maxLag() {
+		return nil, fmt.Errorf("model has %d history rows, expected %d", len(m.History), s.maxLag())
+	}
+
+	n := len(m.History)
+	y := make([]float64, n+numToPredict)
+	timeValues := make([]float64, n)
+	for i, row := range m.History {
+		y[i], timeValues[i] = row[0], row[1]
+	}
+	u := extendTimeValues(timeValues, numToPredict, m.Params.StepSize)
+	m.forecast(y, u, n)
+
+	result := make([][]float64, numToPredict)
+	for i := range result {
+		result[i] = []float64{u[n+i], y[n+i]}
+	}
+	return result, nil
+}
+
+// Summary returns the regression summary of the least-squares fit of the model. The coefficients
+// are those of the terms of the scaled lagged values, and with a ridge penalty, the inference
+// ignores the bias it introduces.
+func (m *NARXModel) Summary() *FitSummary {
+	s := m.Params.structure()
+	names := narxLagNames(s)
+	labels := make([]string, len(m.Terms))
+	rbf := 0
+	for j, term := range m.Terms {
+		labels[j] = term.label(names, rbf)
+		if term.Center != nil {
+			rbf++
+		}
+	}
+	model := fmt.Sprintf("NARX model: na = %d, nb = %d, nk = %d, %s basis, %d terms", s.na, s.nb, s.nk, m.Params.Basis, len(m.Terms))
+	return newFitSummary(model, labels, m.Theta, m.Metadata)
+}
+
+// Predict fits the model and returns its predictions as a slice of [time, value] pairs. The
+// forecast values are those of Forecast, which start from the observed data; with FullOutput they
+// follow the first max(na, nk+nb-1) observed values and the historical predictions, which run free
+// from them, as for LSARX.
+func (p *NARXPredictor) Predict(numToPredict int) ([][]float64, error) {
+	if numToPredict < 0 {
+		return nil, fmt.Errorf("number of values to predict must not be negative, got: %d", numToPredict)
+	}
+	model, err := p.Fit()
+	if err != nil {
+		return nil, err
+	}
+	result := p.forecast(model, numToPredict)
+	if p.Params.OutputMode == ForecastOnlyOutput {
+		return result.Forecast, nil
+	}
+
+	m := p.Params.structure().maxLag()
+	y := make([]float64, len(p.Data))
+	timeValues := make([]float64, len(p.Data))
+	for i, row := range p.Data {
+		y[i], timeValues[i] = row[0], row[1]
+	}
+	model.forecast(y, timeValues, m)
+	rows := make([][]float64, len(p.Data), len(p.Data)+numToPredict)
+	for i := range rows {
+		rows[i] = []float64{timeValues[i], y[i]}
+	}
+	return append(rows, result.Forecast...), nil
+}
+
+// Forecast fits the model and returns the in-sample one-step-ahead fitted values and residuals
+// apart from the out-of-sample forecast values.
+func (p *NARXPredictor) Forecast(numToPredict int) (*ForecastResult, error) {
+	if numToPredict < 0 {
+		return nil, fmt.Errorf("number of values to predict must not be negative, got: %d", numToPredict)
+	}
+	model, err := p.Fit()
+	if err != nil {
+		return nil, err
+	}
+	return p.forecast(model, numToPredict), nil
+}
+
+// forecast returns the one-step-ahead fitted values of the model over the data and its forecast of
+// numToPredict values past them.
+func (p *NARXPredictor) forecast(model *NARXModel, numToPredict int) *ForecastResult {
+	s := p.Params.structure()
+	m := s.maxLag()
+
+	y := make([]float64, len(p.Data)+numToPredict)
+	timeValues := make([]float64, len(p.Data))
+	for i, row := range p.Data {
+		y[i], timeValues[i] = row[0], row[1]
+	}
+	u := extendTimeValues(timeValues, numToPredict, p.Params.StepSize)
+	fitted := make([]float64, len(p.Data)-m)
+	x := make([]float64, s.na+s.nb)
+	for i := range fitted {
+		fitted[i] = model.predictAt(s, y, u, m+i, x)
+	}
+	model.forecast(y, u, len(p.Data))
+	return newForecastResult(y[:len(p.Data)], u, fitted, m, y[len(p.Data):])
+}
@@ -0,0 +1,233 @@
+package ar
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// narxData returns n rows of the nonlinear system
+// y[t] = 1 + 0.8 y[t-1] - 0.2 y[t-2] - 0.15 y[t-1]^2 + 0.1 y[t-1] y[t-2] + e[t], with noise of
+// standard deviation sd, around its fixed point 2.
+func narxData(n int, sd float64) [][]float64 {
+	rnd := rand.New(rand.NewSource(9))
+	data := make([][]float64, n)
+	y1, y2 := 2.0, 2.0
+	for i := range data {
+		y := 1 + 0.8*y1 - 0.2*y2 - 0.15*y1*y1 + 0.1*y1*y2 + sd*rnd.NormFloat64()
+		data[i] = []float64{y, float64(i)}
+		y1, y2 = y, y1
+	}
+	return data
+}
+
+// narxInputData returns n rows [y, u] of the system
+// y[t] = 0.5 y[t-1] + u[t-1] - 0.3 u[t-1]^2 + 0.2 y[t-1] u[t-1] + e[t], driven by an input u
+// uniform in [-1, 1], with noise of standard deviation 0.05.
+func narxInputData(n int) [][]float64 {
+	rnd := rand.New(rand.NewSource(10))
+	data := make([][]float64, n)
+	y1, u1 := 0.0, 0.0
+	for i := range data {
+		y := 0.5*y1 + u1 - 0.3*u1*u1 + 0.2*y1*u1 + 0.05*rnd.NormFloat64()
+		u := 2*rnd.Float64() - 1
+		data[i] = []float64{y, u}
+		y1, u1 = y, u
+	}
+	return data
+}
+
+func TestMonomials(t *testing.T) {
+	want := [][]int{{0, 0}, {1, 0}, {0, 1}, {2, 0}, {1, 1}, {0, 2}}
+	if got := monomials(2, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("monomials(2, 2) = %v, want %v", got, want)
+	}
+	// The number of monomials of d variables up to degree k is (d+k)!/(d!k!).
+	if got := len(monomials(4, 3)); got != 35 {
+		t.Errorf("len(monomials(4, 3)) = %d, want 35", got)
+	}
+}
+
+func TestNARXTermLabel(t *testing.T) {
+	names := narxLagNames(arxStructure{na: 2, nb: 2, nk: 0})
+	if want := []string{"y[t-1]", "y[t-2]", "u[t]", "u[t-1]"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("narxLagNames() = %v, want %v", names, want)
+	}
+	tests := []struct {
+		term NARXTerm
+		want string
+	}{
+		{NARXTerm{Exponents: []int{0, 0, 0, 0}}, "const"},
+		{NARXTerm{Exponents: []int{1, 0, 0, 0}}, "y[t-1]"},
+		{NARXTerm{Exponents: []int{2, 0, 0, 1}}, "y[t-1]^2*u[t-1]"},
+		{NARXTerm{Center: []float64{0, 0, 0, 0}}, "rbf[3]"},
+	}
+	for _, tt := range tests {
+		if got := tt.term.label(names, 3); got != tt.want {
+			t.Errorf("label() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestNARXPolynomialRecovery(t *testing.T) {
+	data := narxInputData(500)
+	tests := []struct {
+		name      string
+		maxTerms  int
+		tolerance float64
+		numTerms  int
+	}{
+		{"All terms", 0, 0, 10},
+		{"Max terms", 4, 0, 4},
+		{"ERR tolerance", 0, 0.01, 4},
+	}
+	want := map[string]float64{"y[t-1]": 0.5, "u[t-1]": 1, "u[t-1]^2": -0.3, "y[t-1]*u[t-1]": 0.2}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewNARXPredictor(data, NARXModelParameters{
+				AutoregressiveLags: 1,
+				ExternalInputLags:  2,
+				StepSize:           1,
+				Basis:              PolynomialBasis,
+				MaxTerms:           tt.maxTerms,
+				ERRTolerance:       tt.tolerance,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create predictor: %v", err)
+			}
+			model, err := p.Fit()
+			if err != nil {
+				t.Fatalf("Fit() error = %v", err)
+			}
+			summary := model.Summary()
+			if len(summary.Coefficients) != tt.numTerms {
+				t.Fatalf("model has %d terms %v, want %d", len(summary.Coefficients), summary.Coefficients, tt.numTerms)
+			}
+			// The coefficients are those of the scaled lagged values.
+			estimates := make(map[string]float64)
+			for j, c := range summary.Coefficients {
+				estimates[c.Label] = c.Estimate
+				for k, e := range model.Terms[j].Exponents {
+					estimates[c.Label] /= math.Pow(model.Scale[k], float64(e))
+				}
+			}
+			for label, w := range want {
+				if got, ok := estimates[label]; !ok || math.Abs(got-w) > 0.02 {
+					t.Errorf("coefficient of %s = %v, want about %v", label, got, w)
+				}
+			}
+			if (tt.maxTerms > 0 || tt.tolerance > 0) != (model.ERR != nil) {
+				t.Errorf("ERR = %v with forward regression %v", model.ERR, tt.maxTerms > 0 || tt.tolerance > 0)
+			}
+		})
+	}
+}
+
+func TestNARXRadialBasisForecast(t *testing.T) {
+	data := narxData(600, 0.3)
+	p, err := NewNARXPredictor(data, NARXModelParameters{
+		AutoregressiveLags: 2,
+		ExternalInputLags:  1,
+		StepSize:           1,
+		Basis:              RadialBasis,
+		Centers:            12,
+		Ridge:              1e-3,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	result, err := p.Forecast(30)
+	if err != nil {
+		t.Fatalf("Forecast() error = %v", err)
+	}
+	sse := 0.0
+	for _, r := range result.Residuals {
+		sse += r[1] * r[1]
+	}
+	// The one-step-ahead residuals are about the noise.
+	if sd := math.Sqrt(sse / float64(len(result.Residuals))); sd > 0.33 {
+		t.Errorf("residual standard deviation = %v, want about 0.3", sd)
+	}
+	// Without noise, the system settles at its fixed point.
+	for _, row := range result.Forecast[20:] {
+		if math.Abs(row[1]-2) > 0.2 {
+			t.Errorf("forecast at %v = %v, want about 2", row[0], row[1])
+		}
+	}
+
+	predicted, err := p.Predict(30)
+	if err != nil {
+		t.Fatalf("Predict() error = %v", err)
+	}
+	if len(predicted) != len(data)+30 {
+		t.Fatalf("Predict() returned %d rows, want %d", len(predicted), len(data)+30)
+	}
+	// The full output runs free over the history from the first two observed values.
+	if !reflect.DeepEqual(predicted[:2], [][]float64{{data[0][1], data[0][0]}, {data[1][1], data[1][0]}}) {
+		t.Errorf("Predict() starts with %v, want the observed values", predicted[:2])
+	}
+	if predicted[2][1] != result.Fitted[0][1] || predicted[3][1] == result.Fitted[1][1] {
+		t.Errorf("Predict() history %v, want the free run from the one-step fitted value %v", predicted[2:4], result.Fitted[0:2])
+	}
+	if !reflect.DeepEqual(predicted[len(data):], result.Forecast) {
+		t.Error("Predict() ends with other values than the Forecast")
+	}
+	model, err := p.Fit()
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+	forecast, err := model.Predict(30)
+	if err != nil {
+		t.Fatalf("Predict() error = %v", err)
+	}
+	if !reflect.DeepEqual(forecast, predicted[len(data):]) {
+		t.Error("model forecast differs from the predictor forecast")
+	}
+}
+
+func TestNewNARXPredictorErrors(t *testing.T) {
+	data := narxData(50, 0.3)
+	valid := NARXModelParameters{AutoregressiveLags: 1, StepSize: 1}
+	tests := []struct {
+		name   string
+		modify func(p *NARXModelParameters)
+	}{
+		{"No autoregressive lags", func(p *NARXModelParameters) { p.AutoregressiveLags = 0 }},
+		{"Negative input delay", func(p *NARXModelParameters) { p.InputDelay = -1 }},
+		{"Zero step size", func(p *NARXModelParameters) { p.StepSize = 0 }},
+		{"Unknown basis", func(p *NARXModelParameters) { p.Basis = 5 }},
+		{"Negative degree", func(p *NARXModelParameters) { p.Degree = -1 }},
+		{"Negative ridge", func(p *NARXModelParameters) { p.Ridge = -1 }},
+		{"ERR tolerance of one", func(p *NARXModelParameters) { p.ERRTolerance = 1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := valid
+			tt.modify(&params)
+			if _, err := NewNARXPredictor(data, params); err == nil {
+				t.Error("NewNARXPredictor() returned no error")
+			}
+		})
+	}
+
+	p, err := NewNARXPredictor(data[:20], NARXModelParameters{AutoregressiveLags: 1, StepSize: 1, Basis: RadialBasis, Centers: 30})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	if _, err := p.Fit(); err == nil {
+		t.Error("Fit() with more centers than rows returned no error")
+	}
+}
+
+func TestParseNARXBasis(t *testing.T) {
+	for _, b := range []NARXBasis{PolynomialBasis, RadialBasis} {
+		got, err := ParseNARXBasis(b.String())
+		if err != nil || got != b {
+			t.Errorf("ParseNARXBasis(%q) = %v, %v, want %v", b.String(), got, err, b)
+		}
+	}
+	if _, err := ParseNARXBasis("spline"); err == nil {
+		t.Error("ParseNARXBasis() of an unknown basis returned no error")
+	}
+}
@@ -0,0 +1,153 @@
+package ar
+
+import (
+	"fmt"
+	"math"
+)
+
+// InformationCriterion selects the criterion used to compare model orders.
+type InformationCriterion int
+
+const (
+	// AIC is the Akaike information criterion, N*ln(SSE/N) + 2k.
+	AIC InformationCriterion = iota
+	// BIC is the Bayesian information criterion, N*ln(SSE/N) + k*ln(N).
+	BIC
+	// AICc is the Akaike information criterion corrected for small samples, AIC + 2k(k+1)/(N-k-1).
+	AICc
+)
+
+// String returns the name of the information criterion.
+func (c InformationCriterion) String() string {
+	switch c {
+	case AIC:
+		return "aic"
+	case BIC:
+		return "bic"
+	case AICc:
+		return "aicc"
+	default:
+		return fmt.Sprintf("InformationCriterion(%d)", int(c))
+	}
+}
+
+// ParseInformationCriterion returns the information criterion with the given name, as returned by String.
+func ParseInformationCriterion(name string) (InformationCriterion, error) {
+	for c := AIC; c <= AICc; c++ {
+		if c.String() == name {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown information criterion: %q", name)
+}
+
+// score evaluates the criterion for a least-squares fit of k coefficients on n residuals.
+func (c InformationCriterion) score(sse float64, n int, k int) float64 {
+	N := float64(n)
+	base := N * math.Log(sse/N)
+	switch c {
+	case BIC:
+		return base + float64(k)*math.Log(N)
+	case AICc:
+		if n-k-1 <= 0 {
+			return math.Inf(1)
+		}
+		return base + 2*float64(k) + 2*float64(k)*float64(k+1)/float64(n-k-1)
+	default:
+		return base + 2*float64(k)
+	}
+}
+
+// OrderSelectionParameters holds the search space of the LSARX order selection.
+type OrderSelectionParameters struct {
+	MaxAutoregressiveLags int                  // Largest na to try, starting from 1.
+	MaxExternalInputLags  int                  // Largest nb to try, starting from 0.
+	MaxInputDelay         int                  // Largest nk to try, starting from 0.
+	Intercept             bool                 // Whether every candidate has a constant term.
+	Criterion             InformationCriterion // Criterion used to rank the candidate orders.
+}
+
+// OrderScore holds the criterion value of one candidate order.
+type OrderScore struct {
+	AutoregressiveLags int     // na of the candidate.
+	ExternalInputLags  int     // nb of the candidate.
+	InputDelay         int     // nk of the candidate.
+	Score              float64 // Criterion value, lower is better.
+}
+
+// OrderSelectionResult holds the outcome of the LSARX order selection.
+type OrderSelectionResult struct {
+	AutoregressiveLags int          // na of the best candidate.
+	ExternalInputLags  int          // nb of the best candidate.
+	InputDelay         int          // nk of the best candidate.
+	Scores             []OrderScore // Criterion value of every evaluated candidate, in search order.
+}
+
+// SelectOrder fits an LSARX model for every na in 1..MaxAutoregressiveLags, nb in
+// 0..MaxExternalInputLags and nk in 0..MaxInputDelay and returns the orders with the lowest
+// information criterion. Every candidate is evaluated on the same data points, the ones past
+// the largest lag, so their criteria are comparable. The candidates without input terms are
+// evaluated once, with nk 0. Candidates that cannot be fitted or whose
+// criterion is not finite are skipped, and an error is returned only when no candidate is left.
+func SelectOrder(data [][]float64, params OrderSelectionParameters) (*OrderSelectionResult, error) {
+	if params.MaxAutoregressiveLags <= 0 || params.MaxExternalInputLags < 0 {
+		return nil, fmt.Errorf("lags must be positive integers, autoregressive lags: %d, external input lags: %d", params.MaxAutoregressiveLags, params.MaxExternalInputLags)
+	}
+	if params.MaxInputDelay < 0 {
+		return nil, fmt.Errorf("input delay must not be negative, input delay: %d", params.MaxInputDelay)
+	}
+	if params.Criterion < AIC || params.Criterion > AICc {
+		return nil, fmt.Errorf("unknown information criterion: %d", params.Criterion)
+	}
+
+	largest := arxStructure{na: params.MaxAutoregressiveLags, nb: params.MaxExternalInputLags, nk: params.MaxInputDelay, intercept: params.Intercept}
+	m := largest.maxLag()
+	maxCols := largest.numParams()
+	if len(data)-m <= maxCols {
+		return nil, fmt.Errorf("not enough data points for order selection, need at least %d points", m+maxCols+1)
+	}
+
+	timeValues := make([]float64, len(data))
+	dataValues := make([]float64, len(data))
+	for i, row := range data {
+		dataValues[i] = row[0]
+		timeValues[i] = row[1]
+	}
+
+	result := &OrderSelectionResult{}
+	best := math.Inf(1)
+	for na := 1; na <= params.MaxAutoregressiveLags; na++ {
+		for nb := 0; nb <= params.MaxExternalInputLags; nb++ {
+			for nk := 0; nk <= params.MaxInputDelay; nk++ {
+				if nb == 0 && nk > 0 {
+					break // Without input terms the delay makes no difference.
+				}
+				s := arxStructure{na: na, nb: nb, nk: nk, intercept: params.Intercept}
+				phi := constructPhiMatrix(dataValues, timeValues, s, m)
+				th, err := calculateTheta(phi, dataValues)
+				if err != nil {
+					continue
+				}
+				sse := 0.0
+				for _, r := range calculateResiduals(phi, th, dataValues[m:]) {
+					sse += r * r
+				}
+
+				score := params.Criterion.score(sse, len(data)-m, s.numParams())
+				if math.IsNaN(score) || math.IsInf(score, 0) {
+					continue
+				}
+				result.Scores = append(result.Scores, OrderScore{AutoregressiveLags: na, ExternalInputLags: nb, InputDelay: nk, Score: score})
+				if score < best {
+					best = score
+					result.AutoregressiveLags, result.ExternalInputLags, result.InputDelay = na, nb, nk
+				}
+			}
+		}
+	}
+
+	if math.IsInf(best, 1) {
+		return nil, fmt.Errorf("no candidate order could be evaluated")
+	}
+	return result, nil
+}
@@ -0,0 +1,135 @@
+package ar
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSelectOrder(t *testing.T) {
+	// y[t] = 1.2*y[t-1] - 0.5*y[t-2] + 0.8*u[t] - 0.3*u[t-1] + e[t]
+	rnd := rand.New(rand.NewSource(3))
+	n := 400
+	data := make([][]float64, n)
+	y := []float64{0, 0}
+	u := []float64{0, 0}
+	for i := 0; i < n; i++ {
+		ui := math.Sin(float64(i)/3) + rnd.NormFloat64()
+		yi := 1.2*y[len(y)-1] - 0.5*y[len(y)-2] + 0.8*ui - 0.3*u[len(u)-1] + 0.05*rnd.NormFloat64()
+		y = append(y, yi)
+		u = append(u, ui)
+		data[i] = []float64{yi, ui}
+	}
+
+	for _, criterion := range []InformationCriterion{AIC, BIC, AICc} {
+		t.Run(criterion.String(), func(t *testing.T) {
+			result, err := SelectOrder(data, OrderSelectionParameters{
+				MaxAutoregressiveLags: 4,
+				MaxExternalInputLags:  3,
+				Criterion:             criterion,
+			})
+			if err != nil {
+				t.Fatalf("SelectOrder() error = %v", err)
+			}
+			if len(result.Scores) != 16 {
+				t.Errorf("SelectOrder() returned %d scores, want 16", len(result.Scores))
+			}
+			if criterion == BIC && (result.AutoregressiveLags != 2 || result.ExternalInputLags != 2) {
+				t.Errorf("SelectOrder() = na %d, nb %d, want na 2, nb 2", result.AutoregressiveLags, result.ExternalInputLags)
+			}
+			best := OrderScore{Score: math.Inf(1)}
+			for _, s := range result.Scores {
+				if s.Score < best.Score {
+					best = s
+				}
+			}
+			if best.AutoregressiveLags != result.AutoregressiveLags || best.ExternalInputLags != result.ExternalInputLags {
+				t.Errorf("SelectOrder() = na %d, nb %d, lowest score has na %d, nb %d", result.AutoregressiveLags, result.ExternalInputLags, best.AutoregressiveLags, best.ExternalInputLags)
+			}
+		})
+	}
+}
+
+func TestSelectOrderInputDelay(t *testing.T) {
+	// y[t] = 0.5*y[t-1] + 2*u[t-2] + 3 + e[t]
+	rnd := rand.New(rand.NewSource(9))
+	data := make([][]float64, 300)
+	for i := range data {
+		data[i] = []float64{0, rnd.NormFloat64()}
+		if i >= 2 {
+			data[i][0] = 0.5*data[i-1][0] + 2*data[i-2][1] + 3 + 0.05*rnd.NormFloat64()
+		}
+	}
+
+	result, err := SelectOrder(data, OrderSelectionParameters{
+		MaxAutoregressiveLags: 2,
+		MaxExternalInputLags:  1,
+		MaxInputDelay:         3,
+		Intercept:             true,
+		Criterion:             BIC,
+	})
+	if err != nil {
+		t.Fatalf("SelectOrder() error = %v", err)
+	}
+	if len(result.Scores) != 10 {
+		t.Errorf("SelectOrder() returned %d scores, want 10", len(result.Scores))
+	}
+	if result.AutoregressiveLags != 1 || result.ExternalInputLags != 1 || result.InputDelay != 2 {
+		t.Errorf("SelectOrder() = na %d, nb %d, nk %d, want na 1, nb 1, nk 2", result.AutoregressiveLags, result.ExternalInputLags, result.InputDelay)
+	}
+}
+
+func TestSelectOrderTimeInput(t *testing.T) {
+	// With regularly sampled time values as the input, the input lags of the larger candidates are
+	// collinear; the search still ranks the candidates.
+	data := make([][]float64, 100)
+	for i := range data {
+		data[i] = []float64{10 + math.Sin(float64(i)/2), float64(i)}
+	}
+	result, err := SelectOrder(data, OrderSelectionParameters{MaxAutoregressiveLags: 3, MaxExternalInputLags: 3, Criterion: AIC})
+	if err != nil {
+		t.Fatalf("SelectOrder() error = %v", err)
+	}
+	if len(result.Scores) == 0 {
+		t.Error("SelectOrder() returned no scores")
+	}
+
+	// No candidate can be evaluated on data with a missing value.
+	data[50][0] = math.NaN()
+	if _, err := SelectOrder(data, OrderSelectionParameters{MaxAutoregressiveLags: 3, MaxExternalInputLags: 3, Criterion: AIC}); err == nil {
+		t.Error("SelectOrder() on data with a NaN value returned no error")
+	}
+}
+
+func TestSelectOrderInvalidParameters(t *testing.T) {
+	testCases := []struct {
+		name   string
+		params OrderSelectionParameters
+	}{
+		{name: "Zero autoregressive lags", params: OrderSelectionParameters{MaxAutoregressiveLags: 0, MaxExternalInputLags: 1}},
+		{name: "Negative external input lags", params: OrderSelectionParameters{MaxAutoregressiveLags: 1, MaxExternalInputLags: -1}},
+		{name: "Negative input delay", params: OrderSelectionParameters{MaxAutoregressiveLags: 1, MaxInputDelay: -1}},
+		{name: "Unknown criterion", params: OrderSelectionParameters{MaxAutoregressiveLags: 1, Criterion: InformationCriterion(9)}},
+		{name: "Not enough data", params: OrderSelectionParameters{MaxAutoregressiveLags: 40, MaxExternalInputLags: 40}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := SelectOrder(sampleData, tc.params); err == nil {
+				t.Errorf("SelectOrder() expected an error")
+			}
+		})
+	}
+}
+
+func TestParseInformationCriterion(t *testing.T) {
+	for _, c := range []InformationCriterion{AIC, BIC, AICc} {
+		parsed, err := ParseInformationCriterion(c.String())
+		if err != nil || parsed != c {
+			t.Errorf("ParseInformationCriterion(%q) = %v, %v, want %v", c.String(), parsed, err, c)
+		}
+	}
+	if _, err := ParseInformationCriterion("hqic"); err == nil {
+		t.Errorf("ParseInformationCriterion() expected an error for an unknown criterion")
+	}
+}
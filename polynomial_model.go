@@ -0,0 +1,359 @@
+package ar
+
+import (
+	"fmt"
+	"math"
+)
+
+// PolynomialStructure selects the system-identification model structure of a PolynomialPredictor,
+// the special cases of A(q) y[t] = B(q)/F(q) u[t-nk] + C(q)/D(q) e[t] with
+//
+//	A(q) = 1 + a1 q^-1 + .. + a_na q^-na    B(q) = b0 + b1 q^-1 + .. + b_nb q^-nb
+//	C(q) = 1 + c1 q^-1 + .. + c_nc q^-nc    D(q) = 1 + d1 q^-1 + .. + d_nd q^-nd
+//	F(q) = 1 + f1 q^-1 + .. + f_nf q^-nf
+//
+// where q^-1 is the backward shift operator, so B(q) u[t-nk] = b0 u[t-nk] + .. + b_nb u[t-nk-nb],
+// the nb+1 input terms of an LSARX model with ExternalInputLags nb+1.
+type PolynomialStructure int
+
+const (
+	// ARMAXStructure is A(q) y[t] = B(q) u[t-nk] + C(q) e[t], an ARX model with coloured noise.
+	ARMAXStructure PolynomialStructure = iota
+	// OutputErrorStructure is y[t] = B(q)/F(q) u[t-nk] + e[t], with white measurement noise.
+	OutputErrorStructure
+	// BoxJenkinsStructure is y[t] = B(q)/F(q) u[t-nk] + C(q)/D(q) e[t], with independent
+	// dynamics for the input and the noise.
+	BoxJenkinsStructure
+)
+
+// String returns the name of the model structure.
+func (s PolynomialStructure) String() string {
+	switch s {
+	case ARMAXStructure:
+		return "armax"
+	case OutputErrorStructure:
+		return "oe"
+	case BoxJenkinsStructure:
+		return "bj"
+	default:
+		return fmt.Sprintf("PolynomialStructure(%d)", int(s))
+	}
+}
+
+// ParsePolynomialStructure returns the model structure with the given name, as returned by String.
+func ParsePolynomialStructure(name string) (PolynomialStructure, error) {
+	for s := ARMAXStructure; s <= BoxJenkinsStructure; s++ {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown model structure: %q", name)
+}
+
+// PolynomialModelParameters holds the configuration of a PolynomialPredictor. The lags of the
+// polynomials a structure does not have must be zero.
+type PolynomialModelParameters struct {
+	Structure            PolynomialStructure // Model structure.
+	AutoregressiveLags   int                 // na: Order of A(q), ARMAX only.
+	ExternalInputLags    int                 // nb: Order of B(q), which has nb+1 coefficients.
+	InputDelay           int                 // nk: Dead time of the external input.
+	NoiseLags            int                 // nc: Order of C(q), ARMAX and Box-Jenkins only.
+	NoiseDenominatorLags int                 // nd: Order of D(q), Box-Jenkins only.
+	InputDenominatorLags int                 // nf: Order of F(q), output-error and Box-Jenkins only.
+	Optimizer            Optimizer           // Optimization method of the prediction-error minimization.
+	MaxIterations        int                 // Maximum number of optimizer iterations, 0 for the default.
+}
+
+// PolynomialPredictor holds the data and parameters of a polynomial model fitted by
+// prediction-error minimization.
+type PolynomialPredictor struct {
+	Data   [][]float64               // Historical data: each row is [data_value, input_value].
+	Params PolynomialModelParameters // Model parameters.
+}
+
+// NewPolynomialPredictor creates a new PolynomialPredictor instance.
+// It performs basic validation of the parameters.
+func NewPolynomialPredictor(data [][]float64, params PolynomialModelParameters) (*PolynomialPredictor, error) {
+	na, nb, nk := params.AutoregressiveLags, params.ExternalInputLags, params.InputDelay
+	nc, nd, nf := params.NoiseLags, params.NoiseDenominatorLags, params.InputDenominatorLags
+	if na < 0 || nb < 0 || nk < 0 || nc < 0 || nd < 0 || nf < 0 {
+		return nil, fmt.Errorf("lags must not be negative, na: %d, nb: %d, nk: %d, nc: %d, nd: %d, nf: %d", na, nb, nk, nc, nd, nf)
+	}
+	switch params.Structure {
+	case ARMAXStructure:
+		if nd != 0 || nf != 0 {
+			return nil, fmt.Errorf("armax models have no D or F polynomial, nd: %d, nf: %d", nd, nf)
+		}
+	case OutputErrorStructure:
+		if na != 0 || nc != 0 || nd != 0 {
+			return nil, fmt.Errorf("oe models have no A, C or D polynomial, na: %d, nc: %d, nd: %d", na, nc, nd)
+		}
+	case BoxJenkinsStructure:
+		if na != 0 {
+			return nil, fmt.Errorf("bj models have no A polynomial, na: %d", na)
+		}
+	default:
+		return nil, fmt.Errorf("unknown model structure: %d", params.Structure)
+	}
+	if _, err := params.Optimizer.method(); err != nil {
+		return nil, err
+	}
+
+	p := &PolynomialPredictor{Data: data, Params: params}
+	if m, k := p.Params.maxLag(), p.Params.numParams(); len(data) <= m+k {
+		return nil, fmt.Errorf("not enough data points for the %s model, need at least %d points", params.Structure, m+k+1)
+	}
+	return p, nil
+}
+
+// numParams returns the number of coefficients of the model.
+func (p PolynomialModelParameters) numParams() int {
+	return p.AutoregressiveLags + p.ExternalInputLags + 1 + p.NoiseLags + p.NoiseDenominatorLags + p.InputDenominatorLags
+}
+
+// maxLag returns the number of leading data points the prediction errors need as lags.
+func (p PolynomialModelParameters) maxLag() int {
+	return max(p.AutoregressiveLags, p.InputDelay+p.ExternalInputLags, p.NoiseLags, p.NoiseDenominatorLags, p.InputDenominatorLags)
+}
+
+// PolynomialModel is a fitted PolynomialPredictor. The polynomials are stored without their
+// leading 1, except B which has no leading 1.
+type PolynomialModel struct {
+	Params    PolynomialModelParameters // Model parameters.
+	A         []float64                 // a1 .. a_na.
+	B         []float64                 // b0 .. b_nb.
+	C         []float64                 // c1 .. c_nc.
+	D         []float64                 // d1 .. d_nd.
+	F         []float64                 // f1 .. f_nf.
+	StdErrors []float64                 // Standard errors of the coefficients, in the order A, B, C, D, F.
+	Sigma2    float64                   // Variance of the one-step prediction errors.
+	Loss      float64                   // Mean squared one-step prediction error, the minimized criterion.
+	Converged bool                      // Whether the optimizer reported convergence.
+}
+
+// newPolynomialModel splits the coefficient vector [A, B, C, D, F] into a model.
+func newPolynomialModel(params PolynomialModelParameters, theta []float64) *PolynomialModel {
+	m := &PolynomialModel{Params: params}
+	next := func(n int) []float64 {
+		v := append([]float64{}, theta[:n]...)
+		theta = theta[n:]
+		return v
+	}
+	m.A = next(params.AutoregressiveLags)
+	m.B = next(params.ExternalInputLags + 1)
+	m.C = next(params.NoiseLags)
+	m.D = next(params.NoiseDenominatorLags)
+	m.F = next(params.InputDenominatorLags)
+	return m
+}
+
+// Fit estimates the model by prediction-error minimization: it minimizes the mean squared
+// one-step prediction error with the configured optimizer, keeping C(q) and F(q) stable so the
+// predictor is. The standard errors come from the Hessian of the concentrated Gaussian likelihood.
+func (p *PolynomialPredictor) Fit() (*PolynomialModel, error) {
+	params := p.Params
+	y := make([]float64, len(p.Data))
+	u := make([]float64, len(p.Data))
+	for i, row := range p.Data {
+		y[i], u[i] = row[0], row[1]
+	}
+	start := params.maxLag()
+	n := float64(len(y) - start)
+
+	loss := func(theta []float64) float64 {
+		e := newPolynomialModel(params, theta).predictionErrors(y, u)
+		sse := 0.0
+		for _, v := range e[start:] {
+			sse += v * v
+		}
+		return sse / n
+	}
+
+	initial, err := p.initialParams(y, u)
+	if err != nil {
+		return nil, err
+	}
+	problem := MLEProblem{
+		// The Gaussian log-likelihood with the noise variance concentrated out.
+		LogLikelihood: func(theta []float64) float64 {
+			return -n / 2 * (math.Log(2*math.Pi*loss(theta)) + 1)
+		},
+		Initial: initial,
+	}
+	offset := params.AutoregressiveLags + params.ExternalInputLags + 1
+	if nc := params.NoiseLags; nc > 0 {
+		problem.Stationary = append(problem.Stationary, ParameterBlock{Start: offset, Length: nc})
+	}
+	offset += params.NoiseLags + params.NoiseDenominatorLags
+	if nf := params.InputDenominatorLags; nf > 0 {
+		problem.Stationary = append(problem.Stationary, ParameterBlock{Start: offset, Length: nf})
+	}
+
+	result, err := MaximizeLikelihood(problem, MLEParameters{Optimizer: params.Optimizer, MaxIterations: params.MaxIterations})
+	if err != nil {
+		return nil, err
+	}
+
+	model := newPolynomialModel(params, result.Params)
+	model.Loss = loss(result.Params)
+	model.Sigma2 = model.Loss * n / (n - float64(len(result.Params)))
+	model.StdErrors = result.StdErrors
+	model.Converged = result.Converged
+	return model, nil
+}
+
+// initialParams returns the starting values of the optimization: a least-squares ARX fit for
+// ARMAX models, a least-squares FIR fit of B(q) otherwise, and zero for the other polynomials.
+func (p *PolynomialPredictor) initialParams(y []float64, u []float64) ([]float64, error) {
+	params := p.Params
+	s := arxStructure{na: params.AutoregressiveLags, nb: params.ExternalInputLags + 1, nk: params.InputDelay}
+	th, err := calculateTheta(constructPhiMatrix(y, u, s, s.maxLag()), y)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate initial parameters: %w", err)
+	}
+	theta := make([]float64, params.numParams())
+	copy(theta, th.RawMatrix().Data)
+	return theta, nil
+}
+
+// filteredInput returns w = B(q)/F(q) u[t-nk], with the values before the start of u taken as zero.
+func (m *PolynomialModel) filteredInput(u []float64) []float64 {
+	nk := m.Params.InputDelay
+	w := make([]float64, len(u))
+	for t := range w {
+		sum := 0.0
+		for j, b := range m.B {
+			if k := t - nk - j; k >= 0 {
+				sum += b * u[k]
+			}
+		}
+		for j, f := range m.F {
+			if k := t - 1 - j; k >= 0 {
+				sum -= f * w[k]
+			}
+		}
+		w[t] = sum
+	}
+	return w
+}
+
+// noiseTerm returns v[t] = A(q) y[t] - w[t], the part of the output the input does not explain.
+func (m *PolynomialModel) noiseTerm(y []float64, w []float64, t int) float64 {
+	v := y[t] - w[t]
+	for j, a := range m.A {
+		if k := t - 1 - j; k >= 0 {
+			v += a * y[k]
+		}
+	}
+	return v
+}
+
+// predictionErrors returns the one-step prediction errors e = D(q)/C(q) (A(q) y - B(q)/F(q) u),
+// with the values before the start of the series taken as zero.
+func (m *PolynomialModel) predictionErrors(y []float64, u []float64) []float64 {
+	w := m.filteredInput(u)
+	v := make([]float64, len(y))
+	e := make([]float64, len(y))
+	for t := range y {
+		v[t] = m.noiseTerm(y, w, t)
+		sum := v[t]
+		for j, d := range m.D {
+			if k := t - 1 - j; k >= 0 {
+				sum += d * v[k]
+			}
+		}
+		for j, c := range m.C {
+			if k := t - 1 - j; k >= 0 {
+				sum -= c * e[k]
+			}
+		}
+		e[t] = sum
+	}
+	return e
+}
+
+// Simulate runs the model in free run over data rows of [data_value, input_value]: the output is
+// the noise-free response to the input signal alone, y = B(q)/(A(q) F(q)) u, starting from rest.
+// The fit is scored from row max(na, nk+nb, nc, nd, nf).
+func (m *PolynomialModel) Simulate(data [][]float64) (*ValidationResult, error) {
+	start := m.Params.maxLag()
+	y, u, err := splitValidationData(data, start)
+	if err != nil {
+		return nil, err
+	}
+	return newValidationResult(y, m.simulate(u), start), nil
+}
+
+// simulate returns the noise-free response of the model to the input signal, starting from rest.
+func (m *PolynomialModel) simulate(inputs []float64) []float64 {
+	w := m.filteredInput(inputs)
+	y := make([]float64, len(inputs))
+	for t := range y {
+		sum := w[t]
+		for j, a := range m.A {
+			if k := t - 1 - j; k >= 0 {
+				sum -= a * y[k]
+			}
+		}
+		y[t] = sum
+	}
+	return y
+}
+
+// PredictK returns the k-step-ahead predictions of the output for data rows of
+// [data_value, input_value]: value t is the prediction of y[t] from the outputs up to t-k and the
+// inputs up to t. The noise of the last k steps is replaced by its expectation, zero, so k = 1
+// gives the one-step predictor and large k approach Simulate. The fit is scored from row
+// max(na, nk+nb, nc, nd, nf).
+func (m *PolynomialModel) PredictK(data [][]float64, k int) (*ValidationResult, error) {
+	if k <= 0 {
+		return nil, fmt.Errorf("prediction horizon must be a positive integer, k: %d", k)
+	}
+	start := m.Params.maxLag()
+	y, u, err := splitValidationData(data, start)
+	if err != nil {
+		return nil, err
+	}
+
+	w := m.filteredInput(u)
+	e := m.predictionErrors(y, u)
+	v := make([]float64, len(y))
+	for t := range v {
+		v[t] = m.noiseTerm(y, w, t)
+	}
+
+	predictions := make([]float64, len(y))
+	yHat := make([]float64, len(y))
+	vHat := make([]float64, len(y))
+	for t := range predictions {
+		// Run the model from origin t-k with the outputs and noise known up to the origin.
+		origin := t - k
+		copy(yHat[max(origin-len(m.A)+1, 0):], y[max(origin-len(m.A)+1, 0):max(origin+1, 0)])
+		copy(vHat[max(origin-len(m.D)+1, 0):], v[max(origin-len(m.D)+1, 0):max(origin+1, 0)])
+		for s := max(origin+1, 0); s <= t; s++ {
+			// C(q) noise with e[s] = 0 past the origin: v = C(q)/D(q) e.
+			sum := 0.0
+			for j, c := range m.C {
+				if i := s - 1 - j; i >= 0 && i <= origin {
+					sum += c * e[i]
+				}
+			}
+			for j, d := range m.D {
+				if i := s - 1 - j; i >= 0 {
+					sum -= d * vHat[i]
+				}
+			}
+			vHat[s] = sum
+
+			pred := w[s] + vHat[s]
+			for j, a := range m.A {
+				if i := s - 1 - j; i >= 0 {
+					pred -= a * yHat[i]
+				}
+			}
+			yHat[s] = pred
+		}
+		predictions[t] = yHat[t]
+	}
+	return newValidationResult(y, predictions, start), nil
+}
@@ -0,0 +1,211 @@
+package ar
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// simulatePolynomial simulates A(q) y[t] = B(q)/F(q) u[t-nk] + C(q)/D(q) e[t] with a white noise
+// input and noise of the given standard deviation.
+func simulatePolynomial(n int, m *PolynomialModel, noise float64) [][]float64 {
+	rnd := rand.New(rand.NewSource(5))
+	u := make([]float64, n)
+	e := make([]float64, n)
+	for t := range u {
+		u[t] = rnd.NormFloat64()
+		e[t] = noise * rnd.NormFloat64()
+	}
+	w := m.filteredInput(u)
+	v := make([]float64, n)
+	y := make([]float64, n)
+	data := make([][]float64, n)
+	for t := range y {
+		v[t] = e[t]
+		for j, c := range m.C {
+			if k := t - 1 - j; k >= 0 {
+				v[t] += c * e[k]
+			}
+		}
+		for j, d := range m.D {
+			if k := t - 1 - j; k >= 0 {
+				v[t] -= d * v[k]
+			}
+		}
+		y[t] = w[t] + v[t]
+		for j, a := range m.A {
+			if k := t - 1 - j; k >= 0 {
+				y[t] -= a * y[k]
+			}
+		}
+		data[t] = []float64{y[t], u[t]}
+	}
+	return data
+}
+
+func TestPolynomialFit(t *testing.T) {
+	tests := []struct {
+		name   string
+		params PolynomialModelParameters
+		truth  []float64 // Coefficients in the order A, B, C, D, F.
+	}{
+		{
+			"armax",
+			PolynomialModelParameters{Structure: ARMAXStructure, AutoregressiveLags: 1, ExternalInputLags: 1, InputDelay: 1, NoiseLags: 1, Optimizer: BFGS},
+			[]float64{-0.7, 1, 0.5, 0.4},
+		},
+		{
+			"oe",
+			PolynomialModelParameters{Structure: OutputErrorStructure, ExternalInputLags: 0, InputDelay: 1, InputDenominatorLags: 1, Optimizer: BFGS},
+			[]float64{1, -0.8},
+		},
+		{
+			"bj",
+			PolynomialModelParameters{Structure: BoxJenkinsStructure, ExternalInputLags: 0, InputDelay: 2, NoiseLags: 1, NoiseDenominatorLags: 1, InputDenominatorLags: 1, Optimizer: NelderMead},
+			[]float64{2, 0.3, -0.6, -0.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := simulatePolynomial(2000, newPolynomialModel(tt.params, tt.truth), 0.5)
+			predictor, err := NewPolynomialPredictor(data, tt.params)
+			if err != nil {
+				t.Fatalf("Failed to create predictor: %v", err)
+			}
+			model, err := predictor.Fit()
+			if err != nil {
+				t.Fatalf("Fit() error = %v", err)
+			}
+
+			var got []float64
+			for _, p := range [][]float64{model.A, model.B, model.C, model.D, model.F} {
+				got = append(got, p...)
+			}
+			for i := range tt.truth {
+				if !approxEqual(got[i], tt.truth[i], 0.1) {
+					t.Errorf("coefficients = %v, want %v", got, tt.truth)
+					break
+				}
+			}
+			if !approxEqual(model.Sigma2, 0.25, 0.03) {
+				t.Errorf("Sigma2 = %v, want 0.25", model.Sigma2)
+			}
+			if len(model.StdErrors) != len(tt.truth) {
+				t.Errorf("len(StdErrors) = %d, want %d", len(model.StdErrors), len(tt.truth))
+			}
+			for i, se := range model.StdErrors {
+				if !(se > 0 && se < 0.1) {
+					t.Errorf("StdErrors[%d] = %v, want in (0, 0.1)", i, se)
+				}
+			}
+		})
+	}
+}
+
+func TestPolynomialPredictK(t *testing.T) {
+	armax := newPolynomialModel(PolynomialModelParameters{Structure: ARMAXStructure, AutoregressiveLags: 1, ExternalInputLags: 1, InputDelay: 1, NoiseLags: 1}, []float64{-0.7, 1, 0.5, 0.4})
+	data := simulatePolynomial(300, armax, 0.5)
+	y := make([]float64, len(data))
+	u := make([]float64, len(data))
+	for i, row := range data {
+		y[i], u[i] = row[0], row[1]
+	}
+
+	// The one-step predictions leave the prediction errors.
+	oneStepResult, err := armax.PredictK(data, 1)
+	if err != nil {
+		t.Fatalf("PredictK() error = %v", err)
+	}
+	oneStep := oneStepResult.Output
+	e := armax.predictionErrors(y, u)
+	for i := range oneStep {
+		if !approxEqual(y[i]-oneStep[i], e[i], 1e-9) {
+			t.Fatalf("y - PredictK(1) = %v at %d, want prediction error %v", y[i]-oneStep[i], i, e[i])
+		}
+	}
+
+	// Far enough ahead, the prediction is the simulation from rest.
+	simResult, err := armax.Simulate(data)
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+	sim := simResult.Output
+	longRange, err := armax.PredictK(data, len(data))
+	if err != nil {
+		t.Fatalf("PredictK() error = %v", err)
+	}
+	for i := range sim {
+		if !approxEqual(longRange.Output[i], sim[i], 1e-9) {
+			t.Fatalf("PredictK(%d) = %v at %d, want simulation %v", len(data), longRange.Output[i], i, sim[i])
+		}
+	}
+
+	// The prediction error variance grows with the horizon towards the simulation error.
+	mse := func(pred []float64) float64 {
+		sum := 0.0
+		for i := 50; i < len(y); i++ {
+			sum += (y[i] - pred[i]) * (y[i] - pred[i])
+		}
+		return sum / float64(len(y)-50)
+	}
+	fiveStep, err := armax.PredictK(data, 5)
+	if err != nil {
+		t.Fatalf("PredictK() error = %v", err)
+	}
+	if !(mse(oneStep) < mse(fiveStep.Output) && mse(fiveStep.Output) < mse(sim)) {
+		t.Errorf("MSE of k = 1, 5 and simulation = %v, %v, %v, want increasing", mse(oneStep), mse(fiveStep.Output), mse(sim))
+	}
+	if !(oneStepResult.Fit > fiveStep.Fit && fiveStep.Fit > simResult.Fit) {
+		t.Errorf("Fit of k = 1, 5 and simulation = %v, %v, %v, want decreasing", oneStepResult.Fit, fiveStep.Fit, simResult.Fit)
+	}
+
+	// The output-error model has white noise, so every horizon predicts the simulation.
+	oe := newPolynomialModel(PolynomialModelParameters{Structure: OutputErrorStructure, InputDelay: 1, InputDenominatorLags: 1}, []float64{1, -0.8})
+	oePred, err := oe.PredictK(data, 1)
+	if err != nil {
+		t.Fatalf("PredictK() error = %v", err)
+	}
+	for i, want := range oe.simulate(u) {
+		if !approxEqual(oePred.Output[i], want, 1e-9) {
+			t.Fatalf("oe PredictK(1) = %v at %d, want simulation %v", oePred.Output[i], i, want)
+		}
+	}
+
+	if _, err := armax.PredictK(data, 0); err == nil {
+		t.Error("PredictK(0) returned no error")
+	}
+}
+
+func TestNewPolynomialPredictor(t *testing.T) {
+	data := make([][]float64, 20)
+	for i := range data {
+		data[i] = []float64{math.Sin(float64(i)), float64(i)}
+	}
+	tests := []struct {
+		name   string
+		data   [][]float64
+		params PolynomialModelParameters
+	}{
+		{"Negative lags", data, PolynomialModelParameters{NoiseLags: -1}},
+		{"ARMAX with F", data, PolynomialModelParameters{Structure: ARMAXStructure, InputDenominatorLags: 1}},
+		{"OE with A", data, PolynomialModelParameters{Structure: OutputErrorStructure, AutoregressiveLags: 1}},
+		{"OE with C", data, PolynomialModelParameters{Structure: OutputErrorStructure, NoiseLags: 1}},
+		{"BJ with A", data, PolynomialModelParameters{Structure: BoxJenkinsStructure, AutoregressiveLags: 1}},
+		{"Unknown structure", data, PolynomialModelParameters{Structure: PolynomialStructure(7)}},
+		{"Unknown optimizer", data, PolynomialModelParameters{Optimizer: Optimizer(7)}},
+		{"Not enough data", data[:4], PolynomialModelParameters{Structure: ARMAXStructure, AutoregressiveLags: 2, ExternalInputLags: 1, NoiseLags: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPolynomialPredictor(tt.data, tt.params); err == nil {
+				t.Error("NewPolynomialPredictor() returned no error")
+			}
+		})
+	}
+
+	for _, s := range []PolynomialStructure{ARMAXStructure, OutputErrorStructure, BoxJenkinsStructure} {
+		if got, err := ParsePolynomialStructure(s.String()); err != nil || got != s {
+			t.Errorf("ParsePolynomialStructure(%q) = %v, %v, want %v", s.String(), got, err, s)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package ar
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// ReconciliationMethod selects how Reconcile makes the forecasts of a hierarchy coherent.
+type ReconciliationMethod int
+
+const (
+	// BottomUp sums the forecasts of the bottom nodes up the hierarchy.
+	BottomUp ReconciliationMethod = iota
+	// TopDown splits the forecast of the root between the bottom nodes by their historical
+	// proportions, the averages of their histories over the sum of these averages.
+	TopDown
+	// OLSReconciliation projects the forecasts of every node on the coherent forecasts by ordinary
+	// least squares.
+	OLSReconciliation
+	// MinTReconciliation projects the forecasts of every node on the coherent forecasts by generalized
+	// least squares with the shrunk covariance of the one-step-ahead residuals of the nodes, which
+	// minimizes the trace of the covariance of the reconciled forecast errors.
+	MinTReconciliation
+)
+
+// String returns the name of the reconciliation method.
+func (r ReconciliationMethod) String() string {
+	switch r {
+	case BottomUp:
+		return "bottom-up"
+	case TopDown:
+		return "top-down"
+	case OLSReconciliation:
+		return "ols"
+	case MinTReconciliation:
+		return "mint"
+	default:
+		return fmt.Sprintf("ReconciliationMethod(%d)", int(r))
+	}
+}
+
+// ParseReconciliationMethod returns the reconciliation method with the given name, as returned by String.
+func ParseReconciliationMethod(name string) (ReconciliationMethod, error) {
+	for r := BottomUp; r <= MinTReconciliation; r++ {
+		if r.String() == name {
+			return r, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown reconciliation method: %q", name)
+}
+
+// HierarchyNode is a node of a hierarchy, whose value is the sum of the values of its children.
+type HierarchyNode struct {
+	Name   string // Name of the node.
+	Parent string // Name of the parent node, empty for a root.
+}
+
+// Hierarchy is the aggregation structure of a set of series: every node that has children is
+// the sum of them, and the bottom nodes, which have none, determine every other node.
+type Hierarchy struct {
+	Nodes  []string   // Names of every node, in definition order.
+	Bottom []string   // Names of the bottom nodes, in definition order.
+	Roots  []string   // Names of the nodes without a parent, in definition order.
+	S      *mat.Dense // Summing matrix: S[i][j] is 1 if bottom node j is node i or one of its descendants.
+}
+
+// NewHierarchy builds the hierarchy of the given nodes. Node names must be unique and every parent
+// must be a node of the hierarchy.
+func NewHierarchy(nodes []HierarchyNode) (*Hierarchy, error) {
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("hierarchy needs at least one node")
+	}
+	index := make(map[string]int, len(nodes))
+	for i, node := range nodes {
+		if _, ok := index[node.Name]; ok {
+			return nil, fmt.Errorf("duplicate hierarchy node: %q", node.Name)
+		}
+		index[node.Name] = i
+	}
+	parent := make([]int, len(nodes))
+	hasChildren := make([]bool, len(nodes))
+	for i, node := range nodes {
+		parent[i] = -1
+		if node.Parent == "" {
+			continue
+		}
+		p, ok := index[node.Parent]
+		if !ok {
+			return nil, fmt.Errorf("unknown parent %q of hierarchy node %q", node.Parent, node.Name)
+		}
+		parent[i] = p
+		hasChildren[p] = true
+	}
+
+	for i, node := range nodes {
+		steps := 0
+		for a := parent[i]; a >= 0; a = parent[a] {
+			if steps++; steps > len(nodes) {
+				return nil, fmt.Errorf("hierarchy has a cycle above node %q", node.Name)
+			}
+		}
+	}
+
+	h := &Hierarchy{Nodes: make([]string, len(nodes))}
+	for i, node := range nodes {
+		h.Nodes[i] = node.Name
+		if !hasChildren[i] {
+			h.Bottom = append(h.Bottom, node.Name)
+		}
+		if parent[i] < 0 {
+			h.Roots = append(h.Roots, node.Name)
+		}
+	}
+
+	h.S = mat.NewDense(len(nodes), len(h.Bottom), nil)
+	for j, name := range h.Bottom {
+		for i := index[name]; i >= 0; i = parent[i] {
+			h.S.Set(i, j, 1)
+		}
+	}
+	return h, nil
+}
+
+// ReconciliationParameters holds the configuration of Reconcile.
+type ReconciliationParameters struct {
+	Method    ReconciliationMethod // Method: how the forecasts are reconciled, bottom-up by default.
+	History   map[string][]float64 // History: historical values of every bottom node, for the top-down proportions.
+	Residuals map[string][]float64 // Residuals: in-sample one-step-ahead residuals of every node, all of the same length, for MinT.
+}
+
+// Reconcile returns coherent forecasts of every node of the hierarchy from the base forecasts of
+// its nodes, keyed by node name, all with the same number of steps. Bottom-up needs the forecasts
+// of the bottom nodes, top-down the forecast of the single root, and OLS and MinT the forecasts of
+// every node.
+func Reconcile(h *Hierarchy, forecasts map[string][]float64, params ReconciliationParameters) (map[string][]float64, error) {
+	var required []string
+	switch params.Method {
+	case BottomUp:
+		required = h.Bottom
+	case TopDown:
+		if len(h.Roots) != 1 {
+			return nil, fmt.Errorf("top-down reconciliation needs a single root, roots: %v", h.Roots)
+		}
+		required = h.Roots
+	case OLSReconciliation, MinTReconciliation:
+		required = h.Nodes
+	default:
+		return nil, fmt.Errorf("unknown reconciliation method: %d", params.Method)
+	}
+	steps := -1
+	for _, name := range required {
+		f, ok := forecasts[name]
+		if !ok {
+			return nil, fmt.Errorf("missing base forecast of hierarchy node %q", name)
+		}
+		if steps >= 0 && len(f) != steps {
+			return nil, fmt.Errorf("base forecast of hierarchy node %q has %d steps, expected %d", name, len(f), steps)
+		}
+		steps = len(f)
+	}
+	if steps == 0 {
+		return nil, fmt.Errorf("base forecasts have no steps")
+	}
+	baseMatrix := func(names []string) *mat.Dense {
+		base := mat.NewDense(len(names), steps, nil)
+		for i, name := range names {
+			base.SetRow(i, forecasts[name])
+		}
+		return base
+	}
+
+	// Every method finds the forecasts of the bottom nodes, which the summing matrix aggregates.
+	var bottom mat.Dense
+	switch params.Method {
+	case BottomUp:
+		bottom.CloneFrom(baseMatrix(h.Bottom))
+	case TopDown:
+		proportions, err := topDownProportions(h, params.History)
+		if err != nil {
+			return nil, err
+		}
+		bottom.Outer(1, mat.NewVecDense(len(proportions), proportions), baseMatrix(h.Roots).RowView(0))
+	default:
+		// The generalized least-squares bottom forecasts are (S' W^-1 S)^-1 S' W^-1 base.
+		var wInvS mat.Dense
+		if params.Method == MinTReconciliation {
+			w, err := shrunkResidualCovariance(h, params.Residuals)
+			if err != nil {
+				return nil, err
+			}
+			if err := wInvS.Solve(w, h.S); err != nil {
+				return nil, fmt.Errorf("residual covariance is singular: %v", err)
+			}
+		} else {
+			wInvS.CloneFrom(h.S)
+		}
+		var a, b mat.Dense
+		a.Mul(wInvS.T(), h.S)
+		b.Mul(wInvS.T(), baseMatrix(h.Nodes))
+		if err := bottom.Solve(&a, &b); err != nil {
+			return nil, fmt.Errorf("failed to reconcile forecasts: %v", err)
+		}
+	}
+
+	var coherent mat.Dense
+	coherent.Mul(h.S, &bottom)
+	result := make(map[string][]float64, len(h.Nodes))
+	for i, name := range h.Nodes {
+		result[name] = mat.Row(nil, i, &coherent)
+	}
+	return result, nil
+}
+
+// topDownProportions returns the share of every bottom node in the root: the average of its
+// history over the sum of the averages of the bottom nodes.
+func topDownProportions(h *Hierarchy, history map[string][]float64) ([]float64, error) {
+	proportions := make([]float64, len(h.Bottom))
+	total := 0.0
+	for j, name := range h.Bottom {
+		values := history[name]
+		if len(values) == 0 {
+			return nil, fmt.Errorf("missing history of hierarchy node %q for top-down proportions", name)
+		}
+		for _, v := range values {
+			proportions[j] += v
+		}
+		proportions[j] /= float64(len(values))
+		total += proportions[j]
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("historical averages of the bottom nodes sum to zero")
+	}
+	for j := range proportions {
+		proportions[j] /= total
+	}
+	return proportions, nil
+}
+
+// shrunkResidualCovariance returns the covariance of the residuals of every node shrunk towards
+// its diagonal, with the shrinkage intensity of Schäfer and Strimmer (2005) on the correlations,
+// as used by MinT. The residuals are taken as having zero mean.
+func shrunkResidualCovariance(h *Hierarchy, residuals map[string][]float64) (*mat.SymDense, error) {
+	k := len(h.Nodes)
+	n := -1
+	for _, name := range h.Nodes {
+		r, ok := residuals[name]
+		if !ok {
+			return nil, fmt.Errorf("missing residuals of hierarchy node %q", name)
+		}
+		if n >= 0 && len(r) != n {
+			return nil, fmt.Errorf("residuals of hierarchy node %q have %d values, expected %d", name, len(r), n)
+		}
+		n = len(r)
+	}
+	if n < 2 {
+		return nil, fmt.Errorf("not enough residuals for MinT, need at least 2 per node")
+	}
+
+	cov := mat.NewSymDense(k, nil)
+	for i, a := range h.Nodes {
+		for j := i; j < k; j++ {
+			sum := 0.0
+			for t, v := range residuals[a] {
+				sum += v * residuals[h.Nodes[j]][t]
+			}
+			cov.SetSym(i, j, sum/float64(n))
+		}
+	}
+	sd := make([]float64, k)
+	for i := range sd {
+		sd[i] = math.Sqrt(cov.At(i, i))
+		if sd[i] == 0 {
+			return nil, fmt.Errorf("residuals of hierarchy node %q are all zero", h.Nodes[i])
+		}
+	}
+
+	// The intensity is the sum of the estimated variances of the correlations over the sum of
+	// their squares, off the diagonal.
+	num, den := 0.0, 0.0
+	for i := range k {
+		for j := i + 1; j < k; j++ {
+			ri, rj := residuals[h.Nodes[i]], residuals[h.Nodes[j]]
+			sum, sumSq := 0.0, 0.0
+			for t := range ri {
+				w := ri[t] / sd[i] * rj[t] / sd[j]
+				sum += w
+				sumSq += w * w
+			}
+			num += (sumSq - sum*sum/float64(n)) / float64(n*(n-1))
+			corr := cov.At(i, j) / (sd[i] * sd[j])
+			den += corr * corr
+		}
+	}
+	lambda := 1.0
+	if den > 0 {
+		lambda = math.Max(0, math.Min(1, num/den))
+	}
+	for i := range k {
+		for j := i + 1; j < k; j++ {
+			cov.SetSym(i, j, (1-lambda)*cov.At(i, j))
+		}
+	}
+	return cov, nil
+}
@@ -0,0 +1,180 @@
+package ar
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// testHierarchy is Total = A + B, with A = A1 + A2.
+func testHierarchy(t *testing.T) *Hierarchy {
+	t.Helper()
+	h, err := NewHierarchy([]HierarchyNode{{"Total", ""}, {"A", "Total"}, {"B", "Total"}, {"A1", "A"}, {"A2", "A"}})
+	if err != nil {
+		t.Fatalf("NewHierarchy() error = %v", err)
+	}
+	return h
+}
+
+// checkCoherent checks that every parent forecast of the test hierarchy is the sum of its children.
+func checkCoherent(t *testing.T, f map[string][]float64) {
+	t.Helper()
+	for i := range f["Total"] {
+		if !approxEqual(f["Total"][i], f["A"][i]+f["B"][i], 1e-9) || !approxEqual(f["A"][i], f["A1"][i]+f["A2"][i], 1e-9) {
+			t.Errorf("forecasts %v are not coherent at step %d", f, i)
+		}
+	}
+}
+
+func TestNewHierarchy(t *testing.T) {
+	h := testHierarchy(t)
+	if len(h.Bottom) != 3 || h.Bottom[0] != "B" || h.Bottom[1] != "A1" || len(h.Roots) != 1 || h.Roots[0] != "Total" {
+		t.Errorf("bottom nodes = %v and roots = %v, want [B A1 A2] and [Total]", h.Bottom, h.Roots)
+	}
+	// Columns are B, A1, A2.
+	want := [][]float64{{1, 1, 1}, {0, 1, 1}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	for i, row := range want {
+		for j, v := range row {
+			if h.S.At(i, j) != v {
+				t.Fatalf("S[%d][%d] = %v, want %v", i, j, h.S.At(i, j), v)
+			}
+		}
+	}
+
+	tests := []struct {
+		name  string
+		nodes []HierarchyNode
+	}{
+		{"No nodes", nil},
+		{"Duplicate", []HierarchyNode{{"A", ""}, {"A", ""}}},
+		{"Unknown parent", []HierarchyNode{{"A", "B"}}},
+		{"Cycle", []HierarchyNode{{"Total", ""}, {"A", "B"}, {"B", "A"}, {"C", "A"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewHierarchy(tt.nodes); err == nil {
+				t.Error("NewHierarchy() returned no error")
+			}
+		})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	h := testHierarchy(t)
+	base := map[string][]float64{
+		"Total": {20, 21},
+		"A":     {9, 12},
+		"B":     {8, 7},
+		"A1":    {3, 5},
+		"A2":    {4, 6},
+	}
+
+	got, err := Reconcile(h, base, ReconciliationParameters{Method: BottomUp})
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	checkCoherent(t, got)
+	if got["Total"][0] != 15 || got["A"][1] != 11 || got["B"][1] != 7 {
+		t.Errorf("bottom-up forecasts = %v", got)
+	}
+
+	history := map[string][]float64{"A1": {1, 3}, "A2": {2, 2}, "B": {1, 1}}
+	got, err = Reconcile(h, base, ReconciliationParameters{Method: TopDown, History: history})
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	checkCoherent(t, got)
+	// The proportions of A1, A2 and B are 2/5, 2/5 and 1/5.
+	if !approxEqual(got["A1"][0], 8, 1e-12) || !approxEqual(got["B"][1], 4.2, 1e-12) || got["Total"][1] != 21 {
+		t.Errorf("top-down forecasts = %v", got)
+	}
+
+	got, err = Reconcile(h, base, ReconciliationParameters{Method: OLSReconciliation})
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	checkCoherent(t, got)
+
+	// The reconciled forecasts of Total = A + B alone minimize the squared adjustment:
+	// Total 29/3, A 13/3 and B 16/3 for base forecasts 10, 4 and 5.
+	simple, err := NewHierarchy([]HierarchyNode{{"Total", ""}, {"A", "Total"}, {"B", "Total"}})
+	if err != nil {
+		t.Fatalf("NewHierarchy() error = %v", err)
+	}
+	got, err = Reconcile(simple, map[string][]float64{"Total": {10}, "A": {4}, "B": {5}}, ReconciliationParameters{Method: OLSReconciliation})
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if !approxEqual(got["Total"][0], 29.0/3, 1e-12) || !approxEqual(got["A"][0], 13.0/3, 1e-12) || !approxEqual(got["B"][0], 16.0/3, 1e-12) {
+		t.Errorf("OLS forecasts = %v, want Total 29/3, A 13/3 and B 16/3", got)
+	}
+}
+
+func TestReconcileMinT(t *testing.T) {
+	h := testHierarchy(t)
+	rnd := rand.New(rand.NewSource(5))
+	residuals := make(map[string][]float64)
+	for _, name := range h.Nodes {
+		residuals[name] = make([]float64, 100)
+	}
+	for i := range 100 {
+		// The forecasts of B are far more accurate than the others.
+		a1, a2, b := rnd.NormFloat64(), rnd.NormFloat64(), 0.01*rnd.NormFloat64()
+		residuals["A1"][i], residuals["A2"][i], residuals["B"][i] = a1, a2, b
+		residuals["A"][i] = a1 + a2 + rnd.NormFloat64()
+		residuals["Total"][i] = a1 + a2 + b + rnd.NormFloat64()
+	}
+	base := map[string][]float64{"Total": {20}, "A": {9}, "B": {8}, "A1": {3}, "A2": {4}}
+
+	got, err := Reconcile(h, base, ReconciliationParameters{Method: MinTReconciliation, Residuals: residuals})
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	checkCoherent(t, got)
+	if !approxEqual(got["B"][0], 8, 0.01) {
+		t.Errorf("MinT forecast of B = %v, want about its accurate base forecast 8", got["B"][0])
+	}
+
+	// Coherent base forecasts are left unchanged.
+	coherent := map[string][]float64{"Total": {15}, "A": {7}, "B": {8}, "A1": {3}, "A2": {4}}
+	got, err = Reconcile(h, coherent, ReconciliationParameters{Method: MinTReconciliation, Residuals: residuals})
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	for name, f := range coherent {
+		if !approxEqual(got[name][0], f[0], 1e-9) {
+			t.Errorf("MinT forecast of %s = %v, want the coherent base forecast %v", name, got[name][0], f[0])
+		}
+	}
+}
+
+func TestReconcileErrors(t *testing.T) {
+	h := testHierarchy(t)
+	base := map[string][]float64{"Total": {20}, "A": {9}, "B": {8}, "A1": {3}, "A2": {4}}
+	tests := []struct {
+		name      string
+		forecasts map[string][]float64
+		params    ReconciliationParameters
+	}{
+		{"Missing forecast", map[string][]float64{"Total": {20}}, ReconciliationParameters{Method: OLSReconciliation}},
+		{"Different steps", map[string][]float64{"B": {1}, "A1": {1, 2}, "A2": {1}}, ReconciliationParameters{}},
+		{"No steps", map[string][]float64{"B": {}, "A1": {}, "A2": {}}, ReconciliationParameters{}},
+		{"Top-down without history", base, ReconciliationParameters{Method: TopDown}},
+		{"MinT without residuals", base, ReconciliationParameters{Method: MinTReconciliation}},
+		{"Unknown method", base, ReconciliationParameters{Method: ReconciliationMethod(9)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Reconcile(h, tt.forecasts, tt.params); err == nil {
+				t.Error("Reconcile() returned no error")
+			}
+		})
+	}
+
+	twoRoots, err := NewHierarchy([]HierarchyNode{{"A", ""}, {"B", ""}})
+	if err != nil {
+		t.Fatalf("NewHierarchy() error = %v", err)
+	}
+	if _, err := Reconcile(twoRoots, map[string][]float64{"A": {1}, "B": {1}}, ReconciliationParameters{Method: TopDown}); err == nil {
+		t.Error("top-down Reconcile() with two roots returned no error")
+	}
+}
@@ -0,0 +1,747 @@
+package ar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// ModelSchemaVersion is the version of the JSON and binary encodings of the fitted models.
+// Encodings with a different version are rejected when loading.
+const ModelSchemaVersion = 2
+
+// Model kinds, as stored in the encodings of the fitted models.
+const (
+	lsModelKind    = "ls"
+	lsarxModelKind = "lsarx"
+)
+
+// binaryModelMagic starts the binary encoding of every fitted model.
+var binaryModelMagic = [4]byte{'G', 'O', 'A', 'R'}
+
+// ParsePredictionStrategy returns the prediction strategy with the given name, as returned by String.
+func ParsePredictionStrategy(name string) (PredictionStrategy, error) {
+	for s := RecursiveStrategy; s <= DirRecStrategy; s++ {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown prediction strategy: %q", name)
+}
+
+// modelHeaderJSON holds the fields shared by the JSON encodings of the fitted models.
+type modelHeaderJSON struct {
+	SchemaVersion int    `json:"schema_version"`
+	Kind          string `json:"kind"`
+}
+
+// trainingMetadataJSON is the JSON encoding of TrainingMetadata.
+type trainingMetadataJSON struct {
+	NumObservations int       `json:"num_observations"`
+	TrainedAt       time.Time `json:"trained_at"`
+	Sigma2          float64   `json:"sigma2"`
+	NumResiduals    int       `json:"num_residuals"`
+	SSE             float64   `json:"sse"`
+	TSS             float64   `json:"tss"`
+	StdErrors       []float64 `json:"std_errors,omitempty"`
+	ChangePoints    []int     `json:"change_points,omitempty"`
+}
+
+// changePointJSON is the JSON encoding of ChangePointParameters.
+type changePointJSON struct {
+	Method           string  `json:"method"`
+	Cost             string  `json:"cost"`
+	Penalty          float64 `json:"penalty"`
+	MinSegmentLength int     `json:"min_segment_length"`
+	MaxChangePoints  int     `json:"max_change_points"`
+}
+
+// Regressor kinds, as stored in the encodings of the fitted models.
+const (
+	calendarRegressorKind = "calendar"
+	eventRegressorKind    = "events"
+	fourierRegressorKind  = "fourier"
+)
+
+// regressorJSON is the JSON encoding of a Regressor of the package: its kind and its own encoding.
+type regressorJSON struct {
+	Kind string          `json:"kind"`
+	Spec json.RawMessage `json:"spec"`
+}
+
+// newRegressorsJSON encodes the regressors. Only the regressor types of the package can be encoded.
+func newRegressorsJSON(regressors []Regressor) ([]regressorJSON, error) {
+	var encoded []regressorJSON
+	for _, r := range regressors {
+		var kind string
+		switch r.(type) {
+		case *CalendarRegressor:
+			kind = calendarRegressorKind
+		case *EventRegressor:
+			kind = eventRegressorKind
+		case *FourierRegressor:
+			kind = fourierRegressorKind
+		default:
+			return nil, fmt.Errorf("cannot encode regressor of type %T", r)
+		}
+		spec, err := json.Marshal(r)
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, regressorJSON{Kind: kind, Spec: spec})
+	}
+	return encoded, nil
+}
+
+// parseRegressors decodes the regressors encoded by newRegressorsJSON.
+func parseRegressors(encoded []regressorJSON) ([]Regressor, error) {
+	var regressors []Regressor
+	for _, v := range encoded {
+		var r Regressor
+		switch v.Kind {
+		case calendarRegressorKind:
+			r = &CalendarRegressor{}
+		case eventRegressorKind:
+			r = &EventRegressor{}
+		case fourierRegressorKind:
+			r = &FourierRegressor{}
+		default:
+			return nil, fmt.Errorf("unknown regressor kind: %q", v.Kind)
+		}
+		if err := json.Unmarshal(v.Spec, r); err != nil {
+			return nil, fmt.Errorf("failed to decode %s regressor: %w", v.Kind, err)
+		}
+		regressors = append(regressors, r)
+	}
+	return regressors, nil
+}
+
+// lsModelJSON is the JSON encoding of LSModel.
+type lsModelJSON struct {
+	modelHeaderJSON
+	StepSize   float64              `json:"step_size"`
+	Regressors []regressorJSON      `json:"regressors,omitempty"`
+	Basis      []string             `json:"basis"`
+	Theta      []float64            `json:"theta"`
+	LastTime   float64              `json:"last_time"`
+	Metadata   trainingMetadataJSON `json:"metadata"`
+}
+
+// lsarxModelJSON is the JSON encoding of LSARXModel.
+type lsarxModelJSON struct {
+	modelHeaderJSON
+	AutoregressiveLags int                  `json:"autoregressive_lags"`
+	ExternalInputLags  int                  `json:"external_input_lags"`
+	InputDelay         int                  `json:"input_delay"`
+	Intercept          bool                 `json:"intercept"`
+	StepSize           float64              `json:"step_size"`
+	Strategy           string               `json:"strategy"`
+	LatestRegime       *changePointJSON     `json:"latest_regime,omitempty"`
+	Regressors         []regressorJSON      `json:"regressors,omitempty"`
+	Theta              []float64            `json:"theta"`
+	HorizonThetas      [][]float64          `json:"horizon_thetas,omitempty"`
+	History            [][]float64          `json:"history"`
+	Metadata           trainingMetadataJSON `json:"metadata"`
+}
+
+// MarshalJSON encodes the fitted model as versioned JSON.
+func (m *LSModel) MarshalJSON() ([]byte, error) {
+	regressors, err := newRegressorsJSON(m.Params.Regressors)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(lsModelJSON{
+		modelHeaderJSON: modelHeaderJSON{SchemaVersion: ModelSchemaVersion, Kind: lsModelKind},
+		StepSize:        m.Params.StepSize,
+		Regressors:      regressors,
+		Basis:           m.Basis,
+		Theta:           m.Theta,
+		LastTime:        m.LastTime,
+		Metadata:        trainingMetadataJSON(m.Metadata),
+	})
+}
+
+// UnmarshalJSON decodes a fitted model encoded by MarshalJSON.
+func (m *LSModel) UnmarshalJSON(data []byte) error {
+	var v lsModelJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check(lsModelKind); err != nil {
+		return err
+	}
+	regressors, err := parseRegressors(v.Regressors)
+	if err != nil {
+		return err
+	}
+
+	model := LSModel{
+		Params:   LSModelParameters{StepSize: v.StepSize, Regressors: regressors},
+		Basis:    v.Basis,
+		Theta:    v.Theta,
+		LastTime: v.LastTime,
+		Metadata: TrainingMetadata(v.Metadata),
+	}
+	if err := model.validate(); err != nil {
+		return err
+	}
+	*m = model
+	return nil
+}
+
+// MarshalJSON encodes the fitted model as versioned JSON.
+func (m *LSARXModel) MarshalJSON() ([]byte, error) {
+	regressors, err := newRegressorsJSON(m.Params.Regressors)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(lsarxModelJSON{
+		modelHeaderJSON:    modelHeaderJSON{SchemaVersion: ModelSchemaVersion, Kind: lsarxModelKind},
+		AutoregressiveLags: m.Params.AutoregressiveLags,
+		ExternalInputLags:  m.Params.ExternalInputLags,
+		InputDelay:         m.Params.InputDelay,
+		Intercept:          m.Params.Intercept,
+		StepSize:           m.Params.StepSize,
+		Strategy:           m.Params.Strategy.String(),
+		LatestRegime:       newChangePointJSON(m.Params.LatestRegime),
+		Regressors:         regressors,
+		Theta:              m.Theta,
+		HorizonThetas:      m.HorizonThetas,
+		History:            m.History,
+		Metadata:           trainingMetadataJSON(m.Metadata),
+	})
+}
+
+// UnmarshalJSON decodes a fitted model encoded by MarshalJSON.
+func (m *LSARXModel) UnmarshalJSON(data []byte) error {
+	var v lsarxModelJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check(lsarxModelKind); err != nil {
+		return err
+	}
+	strategy, err := ParsePredictionStrategy(v.Strategy)
+	if err != nil {
+		return err
+	}
+	latestRegime, err := v.LatestRegime.parameters()
+	if err != nil {
+		return err
+	}
+	regressors, err := parseRegressors(v.Regressors)
+	if err != nil {
+		return err
+	}
+
+	model := LSARXModel{
+		Params: LSARXModelParameters{
+			AutoregressiveLags: v.AutoregressiveLags,
+			ExternalInputLags:  v.ExternalInputLags,
+			InputDelay:         v.InputDelay,
+			Intercept:          v.Intercept,
+			StepSize:           v.StepSize,
+			Strategy:           strategy,
+			LatestRegime:       latestRegime,
+			Regressors:         regressors,
+		},
+		Theta:         v.Theta,
+		HorizonThetas: v.HorizonThetas,
+		History:       v.History,
+		Metadata:      TrainingMetadata(v.Metadata),
+	}
+	if err := model.validate(); err != nil {
+		return err
+	}
+	*m = model
+	return nil
+}
+
+// newChangePointJSON encodes the change-point parameters, nil when unset.
+func newChangePointJSON(p *ChangePointParameters) *changePointJSON {
+	if p == nil {
+		return nil
+	}
+	return &changePointJSON{
+		Method:           p.Method.String(),
+		Cost:             p.Cost.String(),
+		Penalty:          p.Penalty,
+		MinSegmentLength: p.MinSegmentLength,
+		MaxChangePoints:  p.MaxChangePoints,
+	}
+}
+
+// parameters decodes the change-point parameters, nil when unset.
+func (v *changePointJSON) parameters() (*ChangePointParameters, error) {
+	if v == nil {
+		return nil, nil
+	}
+	method, err := ParseChangePointMethod(v.Method)
+	if err != nil {
+		return nil, err
+	}
+	cost, err := ParseChangePointCost(v.Cost)
+	if err != nil {
+		return nil, err
+	}
+	return &ChangePointParameters{Method: method, Cost: cost, Penalty: v.Penalty, MinSegmentLength: v.MinSegmentLength, MaxChangePoints: v.MaxChangePoints}, nil
+}
+
+// check rejects encodings of another model kind or schema version.
+func (h modelHeaderJSON) check(kind string) error {
+	if h.SchemaVersion != ModelSchemaVersion {
+		return fmt.Errorf("unsupported model schema version %d, supported version: %d", h.SchemaVersion, ModelSchemaVersion)
+	}
+	if h.Kind != kind {
+		return fmt.Errorf("model kind is %q, expected %q", h.Kind, kind)
+	}
+	return nil
+}
+
+// LoadModelJSON decodes a fitted model of any kind encoded by MarshalJSON.
+// It returns either an *LSModel or an *LSARXModel.
+func LoadModelJSON(data []byte) (Forecaster, error) {
+	var h modelHeaderJSON
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, err
+	}
+	if h.SchemaVersion != ModelSchemaVersion {
+		return nil, fmt.Errorf("unsupported model schema version %d, supported version: %d", h.SchemaVersion, ModelSchemaVersion)
+	}
+
+	switch h.Kind {
+	case lsModelKind:
+		m := &LSModel{}
+		if err := m.UnmarshalJSON(data); err != nil {
+			return nil, err
+		}
+		return m, nil
+	case lsarxModelKind:
+		m := &LSARXModel{}
+		if err := m.UnmarshalJSON(data); err != nil {
+			return nil, err
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unknown model kind: %q", h.Kind)
+	}
+}
+
+// validate checks that the decoded model is consistent with its parameters.
+func (m *LSModel) validate() error {
+	if m.Params.StepSize <= 0 {
+		return fmt.Errorf("step size must be a positive number, step size: %f", m.Params.StepSize)
+	}
+	basis := m.Params.basisNames()
+	if len(m.Basis) != len(basis) {
+		return fmt.Errorf("model has %d basis functions, expected %d", len(m.Basis), len(basis))
+	}
+	for i, name := range m.Basis {
+		if name != basis[i] {
+			return fmt.Errorf("unknown basis function %q at position %d, expected %q", name, i, basis[i])
+		}
+	}
+	if len(m.Theta) != len(m.Basis) {
+		return fmt.Errorf("model has %d coefficients, expected %d", len(m.Theta), len(m.Basis))
+	}
+	return m.Metadata.validate(len(m.Theta))
+}
+
+// validate checks that the decoded model is consistent with its parameters.
+func (m *LSARXModel) validate() error {
+	if _, err := NewLSARXPredictor(nil, m.Params); err != nil {
+		return err
+	}
+	s := m.Params.structure()
+	if len(m.Theta) != s.numParams() {
+		return fmt.Errorf("model has %d coefficients, expected %d", len(m.Theta), s.numParams())
+	}
+	if len(m.History) != s.maxLag() {
+		return fmt.Errorf("model has %d history rows, expected %d", len(m.History), s.maxLag())
+	}
+	for i, row := range m.History {
+		if len(row) != 2 {
+			return fmt.Errorf("history row %d has %d values, expected 2", i, len(row))
+		}
+	}
+	for h, th := range m.HorizonThetas {
+		if _, lags := directLags(s.na, h+1, m.Params.Strategy == DirRecStrategy); len(th) != s.withLags(lags).numParams() {
+			return fmt.Errorf("model has %d coefficients for horizon %d, expected %d", len(th), h+1, s.withLags(lags).numParams())
+		}
+	}
+	return m.Metadata.validate(len(m.Theta))
+}
+
+// validate checks that the decoded metadata is consistent with a model of numParams coefficients.
+func (md TrainingMetadata) validate(numParams int) error {
+	if md.StdErrors != nil && len(md.StdErrors) != numParams {
+		return fmt.Errorf("model has %d standard errors, expected %d", len(md.StdErrors), numParams)
+	}
+	for i, cp := range md.ChangePoints {
+		if cp <= 0 || i > 0 && cp <= md.ChangePoints[i-1] {
+			return fmt.Errorf("change points must be positive and increasing, change points: %v", md.ChangePoints)
+		}
+	}
+	return nil
+}
+
+// Binary encoding: the magic "GOAR", the schema version as uint16, the model kind as a
+// length-prefixed string and the model fields, all little-endian. Slices are prefixed by their
+// length as uint32, booleans are stored as one byte and times as Unix nanoseconds.
+
+// MarshalBinary encodes the fitted model in the compact binary format.
+func (m *LSModel) MarshalBinary() ([]byte, error) {
+	regressors, err := newRegressorsJSON(m.Params.Regressors)
+	if err != nil {
+		return nil, err
+	}
+	w := newBinaryModelWriter(lsModelKind)
+	w.float(m.Params.StepSize)
+	w.regressors(regressors)
+	w.uint(len(m.Basis))
+	for _, name := range m.Basis {
+		w.string(name)
+	}
+	w.floats(m.Theta)
+	w.float(m.LastTime)
+	w.metadata(m.Metadata)
+	return w.buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a fitted model encoded by MarshalBinary.
+func (m *LSModel) UnmarshalBinary(data []byte) error {
+	r, err := newBinaryModelReader(data, lsModelKind)
+	if err != nil {
+		return err
+	}
+
+	var model LSModel
+	model.Params.StepSize = r.float()
+	regressors := r.regressors()
+	model.Basis = make([]string, r.length())
+	for i := range model.Basis {
+		model.Basis[i] = r.string()
+	}
+	model.Theta = r.floats()
+	model.LastTime = r.float()
+	model.Metadata = r.metadata()
+	if err := r.finish(); err != nil {
+		return err
+	}
+	if model.Params.Regressors, err = parseRegressors(regressors); err != nil {
+		return err
+	}
+	if err := model.validate(); err != nil {
+		return err
+	}
+	*m = model
+	return nil
+}
+
+// MarshalBinary encodes the fitted model in the compact binary format.
+func (m *LSARXModel) MarshalBinary() ([]byte, error) {
+	regressors, err := newRegressorsJSON(m.Params.Regressors)
+	if err != nil {
+		return nil, err
+	}
+	w := newBinaryModelWriter(lsarxModelKind)
+	w.uint(m.Params.AutoregressiveLags)
+	w.uint(m.Params.ExternalInputLags)
+	w.uint(m.Params.InputDelay)
+	w.bool(m.Params.Intercept)
+	w.float(m.Params.StepSize)
+	w.string(m.Params.Strategy.String())
+	w.bool(m.Params.LatestRegime != nil)
+	if p := m.Params.LatestRegime; p != nil {
+		w.string(p.Method.String())
+		w.string(p.Cost.String())
+		w.float(p.Penalty)
+		w.uint(p.MinSegmentLength)
+		w.uint(p.MaxChangePoints)
+	}
+	w.regressors(regressors)
+	w.floats(m.Theta)
+	w.uint(len(m.HorizonThetas))
+	for _, th := range m.HorizonThetas {
+		w.floats(th)
+	}
+	w.uint(len(m.History))
+	for _, row := range m.History {
+		w.floats(row)
+	}
+	w.metadata(m.Metadata)
+	return w.buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a fitted model encoded by MarshalBinary.
+func (m *LSARXModel) UnmarshalBinary(data []byte) error {
+	r, err := newBinaryModelReader(data, lsarxModelKind)
+	if err != nil {
+		return err
+	}
+
+	var model LSARXModel
+	model.Params.AutoregressiveLags = r.uint()
+	model.Params.ExternalInputLags = r.uint()
+	model.Params.InputDelay = r.uint()
+	model.Params.Intercept = r.bool()
+	model.Params.StepSize = r.float()
+	strategy := r.string()
+	var latestRegime *changePointJSON
+	if r.bool() {
+		latestRegime = &changePointJSON{Method: r.string(), Cost: r.string(), Penalty: r.float(), MinSegmentLength: r.uint(), MaxChangePoints: r.uint()}
+	}
+	regressors := r.regressors()
+	model.Theta = r.floats()
+	if n := r.length(); n > 0 {
+		model.HorizonThetas = make([][]float64, n)
+		for i := range model.HorizonThetas {
+			model.HorizonThetas[i] = r.floats()
+		}
+	}
+	model.History = make([][]float64, r.length())
+	for i := range model.History {
+		model.History[i] = r.floats()
+	}
+	model.Metadata = r.metadata()
+	if err := r.finish(); err != nil {
+		return err
+	}
+	if model.Params.Strategy, err = ParsePredictionStrategy(strategy); err != nil {
+		return err
+	}
+	if model.Params.LatestRegime, err = latestRegime.parameters(); err != nil {
+		return err
+	}
+	if model.Params.Regressors, err = parseRegressors(regressors); err != nil {
+		return err
+	}
+	if err := model.validate(); err != nil {
+		return err
+	}
+	*m = model
+	return nil
+}
+
+// LoadModelBinary decodes a fitted model of any kind encoded by MarshalBinary.
+// It returns either an *LSModel or an *LSARXModel.
+func LoadModelBinary(data []byte) (Forecaster, error) {
+	r, err := newBinaryModelReader(data, "")
+	if err != nil {
+		return nil, err
+	}
+
+	switch r.kind {
+	case lsModelKind:
+		m := &LSModel{}
+		if err := m.UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+		return m, nil
+	case lsarxModelKind:
+		m := &LSARXModel{}
+		if err := m.UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unknown model kind: %q", r.kind)
+	}
+}
+
+// binaryModelWriter writes the binary encoding of a fitted model.
+type binaryModelWriter struct {
+	buf bytes.Buffer
+}
+
+func newBinaryModelWriter(kind string) *binaryModelWriter {
+	w := &binaryModelWriter{}
+	w.buf.Write(binaryModelMagic[:])
+	_ = binary.Write(&w.buf, binary.LittleEndian, uint16(ModelSchemaVersion))
+	w.string(kind)
+	return w
+}
+
+func (w *binaryModelWriter) uint(v int) {
+	_ = binary.Write(&w.buf, binary.LittleEndian, uint32(v))
+}
+
+func (w *binaryModelWriter) bool(v bool) {
+	b := byte(0)
+	if v {
+		b = 1
+	}
+	w.buf.WriteByte(b)
+}
+
+func (w *binaryModelWriter) float(v float64) {
+	_ = binary.Write(&w.buf, binary.LittleEndian, math.Float64bits(v))
+}
+
+func (w *binaryModelWriter) floats(v []float64) {
+	w.uint(len(v))
+	for _, f := range v {
+		w.float(f)
+	}
+}
+
+func (w *binaryModelWriter) string(s string) {
+	w.uint(len(s))
+	w.buf.WriteString(s)
+}
+
+// regressors writes the regressors as their kinds and JSON encodings.
+func (w *binaryModelWriter) regressors(regressors []regressorJSON) {
+	w.uint(len(regressors))
+	for _, r := range regressors {
+		w.string(r.Kind)
+		w.string(string(r.Spec))
+	}
+}
+
+func (w *binaryModelWriter) metadata(md TrainingMetadata) {
+	w.uint(md.NumObservations)
+	_ = binary.Write(&w.buf, binary.LittleEndian, md.TrainedAt.UnixNano())
+	w.float(md.Sigma2)
+	w.uint(md.NumResiduals)
+	w.float(md.SSE)
+	w.float(md.TSS)
+	w.floats(md.StdErrors)
+	w.uint(len(md.ChangePoints))
+	for _, cp := range md.ChangePoints {
+		w.uint(cp)
+	}
+}
+
+// binaryModelReader reads the binary encoding of a fitted model. The first error is kept and
+// reported by finish, later reads return zero values.
+type binaryModelReader struct {
+	r    *bytes.Reader
+	kind string
+	err  error
+}
+
+// newBinaryModelReader checks the magic, the schema version and, unless kind is empty, the model kind.
+func newBinaryModelReader(data []byte, kind string) (*binaryModelReader, error) {
+	r := &binaryModelReader{r: bytes.NewReader(data)}
+
+	var magic [4]byte
+	var version uint16
+	r.read(&magic)
+	r.read(&version)
+	if r.err != nil || magic != binaryModelMagic {
+		return nil, errors.New("data is not a binary encoded model")
+	}
+	if version != ModelSchemaVersion {
+		return nil, fmt.Errorf("unsupported model schema version %d, supported version: %d", version, ModelSchemaVersion)
+	}
+	r.kind = r.string()
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode model: %w", r.err)
+	}
+	if kind != "" && r.kind != kind {
+		return nil, fmt.Errorf("model kind is %q, expected %q", r.kind, kind)
+	}
+	return r, nil
+}
+
+func (r *binaryModelReader) read(v any) {
+	if r.err == nil {
+		r.err = binary.Read(r.r, binary.LittleEndian, v)
+	}
+}
+
+func (r *binaryModelReader) uint() int {
+	var v uint32
+	r.read(&v)
+	return int(v)
+}
+
+// length reads a slice length and rejects values larger than the remaining data could hold.
+func (r *binaryModelReader) length() int {
+	v := r.uint()
+	if r.err == nil && v > r.r.Len() {
+		r.err = io.ErrUnexpectedEOF
+	}
+	if r.err != nil {
+		return 0
+	}
+	return v
+}
+
+func (r *binaryModelReader) bool() bool {
+	var v uint8
+	r.read(&v)
+	if r.err == nil && v > 1 {
+		r.err = fmt.Errorf("invalid boolean value %d", v)
+	}
+	return v == 1
+}
+
+func (r *binaryModelReader) float() float64 {
+	var v uint64
+	r.read(&v)
+	return math.Float64frombits(v)
+}
+
+func (r *binaryModelReader) floats() []float64 {
+	v := make([]float64, r.length())
+	for i := range v {
+		v[i] = r.float()
+	}
+	return v
+}
+
+func (r *binaryModelReader) string() string {
+	b := make([]byte, r.length())
+	r.read(b)
+	return string(b)
+}
+
+func (r *binaryModelReader) regressors() []regressorJSON {
+	var regressors []regressorJSON
+	if n := r.length(); n > 0 {
+		regressors = make([]regressorJSON, n)
+		for i := range regressors {
+			regressors[i] = regressorJSON{Kind: r.string(), Spec: json.RawMessage(r.string())}
+		}
+	}
+	return regressors
+}
+
+func (r *binaryModelReader) metadata() TrainingMetadata {
+	md := TrainingMetadata{NumObservations: r.uint()}
+	var nanos int64
+	r.read(&nanos)
+	md.TrainedAt = time.Unix(0, nanos).UTC()
+	md.Sigma2 = r.float()
+	md.NumResiduals = r.uint()
+	md.SSE = r.float()
+	md.TSS = r.float()
+	if stdErrors := r.floats(); len(stdErrors) > 0 {
+		md.StdErrors = stdErrors
+	}
+	if n := r.length(); n > 0 {
+		md.ChangePoints = make([]int, n)
+		for i := range md.ChangePoints {
+			md.ChangePoints[i] = r.uint()
+		}
+	}
+	return md
+}
+
+// finish reports the first decoding error, or trailing data after the model.
+func (r *binaryModelReader) finish() error {
+	if r.err != nil {
+		return fmt.Errorf("failed to decode model: %w", r.err)
+	}
+	if r.r.Len() != 0 {
+		return fmt.Errorf("failed to decode model: %d unexpected trailing bytes", r.r.Len())
+	}
+	return nil
+}
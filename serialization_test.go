@@ -0,0 +1,211 @@
+package ar
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func fittedTestModels(t *testing.T) map[string]Forecaster {
+	t.Helper()
+
+	ls, err := NewLSPredictor(sampleData, LSModelParameters{StepSize: 25})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	lsModel, err := ls.Fit()
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	models := map[string]Forecaster{"ls": lsModel}
+	for _, strategy := range []PredictionStrategy{RecursiveStrategy, DirectStrategy, DirRecStrategy} {
+		arx, err := NewLSARXPredictor(sampleData, LSARXModelParameters{
+			AutoregressiveLags: 3,
+			ExternalInputLags:  3,
+			StepSize:           25,
+			Strategy:           strategy,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create predictor: %v", err)
+		}
+		arxModel, err := arx.Fit(4)
+		if err != nil {
+			t.Fatalf("Fit() error = %v", err)
+		}
+		models["lsarx/"+strategy.String()] = arxModel
+	}
+
+	delayed, err := NewLSARXPredictor(sampleData, LSARXModelParameters{
+		AutoregressiveLags: 2,
+		ExternalInputLags:  2,
+		InputDelay:         2,
+		Intercept:          true,
+		StepSize:           25,
+		Strategy:           DirRecStrategy,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	if models["lsarx/delay-intercept"], err = delayed.Fit(4); err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	regime, err := NewLSARXPredictor(sampleData, LSARXModelParameters{
+		AutoregressiveLags: 1,
+		ExternalInputLags:  1,
+		Intercept:          true,
+		StepSize:           25,
+		LatestRegime:       &ChangePointParameters{Method: BinarySegmentation, Cost: RegressionCost, MaxChangePoints: 2},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	if models["lsarx/latest-regime"], err = regime.Fit(0); err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	calendarData, weekend := weekendCalendarData(t, 60)
+	calendar, err := NewLSARXPredictor(calendarData, LSARXModelParameters{
+		AutoregressiveLags: 1,
+		ExternalInputLags:  1,
+		Intercept:          true,
+		StepSize:           1,
+		Regressors:         []Regressor{weekend},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	if models["lsarx/calendar"], err = calendar.Fit(0); err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	eventData, events := promotionData(t, 100, false)
+	lsEvents, err := NewLSPredictor(eventData, LSModelParameters{StepSize: 1, Regressors: []Regressor{events, weekend}})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	if models["ls/events"], err = lsEvents.Fit(); err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	seasonalData, fourier := fourierData(t, 100)
+	seasonal, err := NewLSARXPredictor(seasonalData, LSARXModelParameters{
+		AutoregressiveLags: 2,
+		ExternalInputLags:  2,
+		StepSize:           1,
+		Strategy:           DirectStrategy,
+		Regressors:         []Regressor{fourier},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	if models["lsarx/fourier"], err = seasonal.Fit(4); err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+	return models
+}
+
+func TestModelSerializationRoundTrip(t *testing.T) {
+	encodings := map[string]struct {
+		marshal func(Forecaster) ([]byte, error)
+		load    func([]byte) (Forecaster, error)
+	}{
+		"json": {
+			marshal: func(m Forecaster) ([]byte, error) { return json.Marshal(m) },
+			load:    LoadModelJSON,
+		},
+		"binary": {
+			marshal: func(m Forecaster) ([]byte, error) {
+				return m.(interface{ MarshalBinary() ([]byte, error) }).MarshalBinary()
+			},
+			load: LoadModelBinary,
+		},
+	}
+
+	for name, model := range fittedTestModels(t) {
+		for encName, enc := range encodings {
+			t.Run(name+"/"+encName, func(t *testing.T) {
+				data, err := enc.marshal(model)
+				if err != nil {
+					t.Fatalf("marshal error = %v", err)
+				}
+				loaded, err := enc.load(data)
+				if err != nil {
+					t.Fatalf("load error = %v", err)
+				}
+				if !reflect.DeepEqual(loaded, model) {
+					t.Errorf("loaded model = %+v, want %+v", loaded, model)
+				}
+
+				want, err := model.Predict(4)
+				if err != nil {
+					t.Fatalf("Predict() error = %v", err)
+				}
+				got, err := loaded.Predict(4)
+				if err != nil {
+					t.Fatalf("Predict() error = %v", err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("loaded Predict() = %v, want %v", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestLoadModelJSONRejectsSchemaVersion(t *testing.T) {
+	model := fittedTestModels(t)["lsarx/recursive"]
+	data, err := json.Marshal(model)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	data = []byte(strings.Replace(string(data), fmt.Sprintf(`"schema_version":%d`, ModelSchemaVersion), `"schema_version":99`, 1))
+
+	_, err = LoadModelJSON(data)
+	if err == nil || !strings.Contains(err.Error(), "unsupported model schema version 99") {
+		t.Errorf("LoadModelJSON() error = %v, want unsupported schema version", err)
+	}
+	if err := (&LSARXModel{}).UnmarshalJSON(data); err == nil {
+		t.Errorf("UnmarshalJSON() expected an error for an unsupported schema version")
+	}
+}
+
+func TestLoadModelBinaryRejectsInvalidData(t *testing.T) {
+	model := fittedTestModels(t)["lsarx/direct"].(*LSARXModel)
+	data, err := model.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+
+	badVersion := append([]byte(nil), data...)
+	badVersion[4] = 99
+	if _, err := LoadModelBinary(badVersion); err == nil || !strings.Contains(err.Error(), "unsupported model schema version 99") {
+		t.Errorf("LoadModelBinary() error = %v, want unsupported schema version", err)
+	}
+
+	if _, err := LoadModelBinary(data[:len(data)-3]); err == nil {
+		t.Errorf("LoadModelBinary() expected an error for truncated data")
+	}
+	if _, err := LoadModelBinary([]byte("not a model")); err == nil {
+		t.Errorf("LoadModelBinary() expected an error for data that is not a model")
+	}
+	if err := (&LSModel{}).UnmarshalBinary(data); err == nil {
+		t.Errorf("UnmarshalBinary() expected an error for a model of another kind")
+	}
+}
+
+func TestUnmarshalJSONRejectsInconsistentModel(t *testing.T) {
+	model := fittedTestModels(t)["ls"].(*LSModel)
+	model.Basis = []string{"t^3", "t", "1", "cos(t)"}
+	data, err := json.Marshal(model)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	if _, err := LoadModelJSON(data); err == nil {
+		t.Errorf("LoadModelJSON() expected an error for an unknown basis function")
+	}
+}
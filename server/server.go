@@ -0,0 +1,460 @@
+// Package server exposes the predictors of package ar over HTTP with JSON requests and responses.
+//
+// The handler returned by NewHandler serves:
+//
+//	GET    /model-types           list the available model types and their parameters
+//	POST   /forecast              fit a model on a series and return its forecast
+//	POST   /models                fit a model on a series and store it
+//	GET    /models                list the IDs of the stored models
+//	GET    /models/{id}           return a stored model
+//	DELETE /models/{id}           delete a stored model
+//	POST   /models/{id}/forecast  forecast from a stored model
+//
+// Errors are returned with a 4xx or 5xx status and a body of the form {"error": "..."}. Horizons
+// are limited to DefaultMaxHorizon unless configured with WithMaxHorizon.
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+
+	ar "github.com/MagdielCAS/go-autoregression"
+	"gonum.org/v1/gonum/mat"
+)
+
+// maxRequestBytes limits the size of request bodies.
+const maxRequestBytes = 32 << 20
+
+// DefaultMaxHorizon is the largest horizon and max_horizon a handler accepts unless configured
+// otherwise with WithMaxHorizon. The forecasts allocate memory in proportion to the horizon.
+const DefaultMaxHorizon = 10000
+
+// ModelType describes a model type that can be fitted.
+type ModelType struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Parameters  []string `json:"parameters"`
+}
+
+// modelTypes lists the model types served by the handler.
+var modelTypes = []ModelType{
+	{
+		Name:        "ls",
+		Description: "least-squares fit of the basis functions [t^2, t, 1, cos(t)]",
+		Parameters:  []string{"step_size"},
+	},
+	{
+		Name:        "lsarx",
+		Description: "least-squares autoregressive model with an external input",
+		Parameters:  []string{"autoregressive_lags", "external_input_lags", "input_delay", "intercept", "step_size", "strategy", "latest_regime", "max_horizon"},
+	},
+}
+
+// ModelParameters holds the parameters of every model type; each type uses the ones listed in its ModelType.
+type ModelParameters struct {
+	AutoregressiveLags int     `json:"autoregressive_lags"`
+	ExternalInputLags  int     `json:"external_input_lags"`
+	InputDelay         int     `json:"input_delay,omitempty"`
+	Intercept          bool    `json:"intercept,omitempty"`
+	StepSize           float64 `json:"step_size"`
+	Strategy           string  `json:"strategy,omitempty"`
+	LatestRegime       string  `json:"latest_regime,omitempty"` // Change-point cost of the PELT detection of the latest regime to fit on, none by default.
+	MaxHorizon         int     `json:"max_horizon,omitempty"`
+}
+
+// FitRequest is the body of POST /forecast and POST /models.
+type FitRequest struct {
+	Model   string          `json:"model"`   // Model type, see GET /model-types.
+	Data    [][]float64     `json:"data"`    // Series: each row is [data_value, time_value].
+	Params  ModelParameters `json:"params"`  // Model parameters.
+	Horizon int             `json:"horizon"` // Number of values to forecast, POST /forecast only.
+}
+
+// ForecastRequest is the body of POST /models/{id}/forecast.
+type ForecastRequest struct {
+	Horizon int `json:"horizon"`
+}
+
+// ForecastResponse is returned by the forecast endpoints. Fitted and Residuals are only set
+// when the model was fitted by the same request.
+type ForecastResponse struct {
+	Fitted    [][]float64 `json:"fitted,omitempty"`
+	Residuals [][]float64 `json:"residuals,omitempty"`
+	Forecast  [][]float64 `json:"forecast"`
+}
+
+// ModelResponse is returned by POST /models and GET /models/{id}.
+type ModelResponse struct {
+	ID    string          `json:"id"`
+	Model json.RawMessage `json:"model"`
+}
+
+// handler serves the HTTP API.
+type handler struct {
+	store      ModelStore
+	maxHorizon int // Largest horizon and max_horizon of the requests.
+}
+
+// Option configures the handler returned by NewHandler.
+type Option func(*handler)
+
+// WithMaxHorizon sets the largest horizon and max_horizon the handler accepts, DefaultMaxHorizon
+// by default. Requests above it are rejected with 400 Bad Request.
+func WithMaxHorizon(n int) Option {
+	return func(h *handler) {
+		h.maxHorizon = n
+	}
+}
+
+// NewHandler returns the HTTP handler of the API, storing fitted models in store.
+func NewHandler(store ModelStore, opts ...Option) http.Handler {
+	h := &handler{store: store, maxHorizon: DefaultMaxHorizon}
+	for _, opt := range opts {
+		opt(h)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /model-types", h.listModelTypes)
+	mux.HandleFunc("POST /forecast", h.forecast)
+	mux.HandleFunc("POST /models", h.createModel)
+	mux.HandleFunc("GET /models", h.listModels)
+	mux.HandleFunc("GET /models/{id}", h.getModel)
+	mux.HandleFunc("DELETE /models/{id}", h.deleteModel)
+	mux.HandleFunc("POST /models/{id}/forecast", h.forecastModel)
+	return mux
+}
+
+func (h *handler) listModelTypes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, modelTypes)
+}
+
+func (h *handler) forecast(w http.ResponseWriter, r *http.Request) {
+	var req FitRequest
+	if err := decodeRequest(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.checkHorizon(req.Horizon); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	p, err := newPredictor(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := checkCollinear(req); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	result, err := p.Forecast(req.Horizon)
+	if err == nil {
+		err = checkFinite(result.Fitted, result.Residuals, result.Forecast)
+	}
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ForecastResponse{Fitted: result.Fitted, Residuals: result.Residuals, Forecast: result.Forecast})
+}
+
+func (h *handler) createModel(w http.ResponseWriter, r *http.Request) {
+	var req FitRequest
+	if err := decodeRequest(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Params.MaxHorizon > h.maxHorizon {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("max horizon must be at most %d, max horizon: %d", h.maxHorizon, req.Params.MaxHorizon))
+		return
+	}
+	p, err := newPredictor(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := checkCollinear(req); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var model any
+	switch p := p.(type) {
+	case *ar.LSPredictor:
+		model, err = p.Fit()
+	case *ar.LSARXPredictor:
+		model, err = p.Fit(req.Params.MaxHorizon)
+	}
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	encoded, err := json.Marshal(model)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	id, err := newModelID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := h.store.Save(id, encoded); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Location", "/models/"+id)
+	writeJSON(w, http.StatusCreated, ModelResponse{ID: id, Model: encoded})
+}
+
+func (h *handler) listModels(w http.ResponseWriter, r *http.Request) {
+	ids, err := h.store.List()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		IDs []string `json:"ids"`
+	}{ids})
+}
+
+func (h *handler) getModel(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	encoded, err := h.store.Load(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ModelResponse{ID: id, Model: encoded})
+}
+
+func (h *handler) deleteModel(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.Delete(r.PathValue("id")); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) forecastModel(w http.ResponseWriter, r *http.Request) {
+	var req ForecastRequest
+	if err := decodeRequest(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.checkHorizon(req.Horizon); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	encoded, err := h.store.Load(r.PathValue("id"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	model, err := ar.LoadModelJSON(encoded)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	forecast, err := model.Predict(req.Horizon)
+	if err == nil {
+		err = checkFinite(forecast)
+	}
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ForecastResponse{Forecast: forecast})
+}
+
+// checkHorizon checks that the horizon of a forecast request is positive and within the limit of the handler.
+func (h *handler) checkHorizon(horizon int) error {
+	if horizon <= 0 {
+		return fmt.Errorf("horizon must be a positive integer, horizon: %d", horizon)
+	}
+	if horizon > h.maxHorizon {
+		return fmt.Errorf("horizon must be at most %d, horizon: %d", h.maxHorizon, horizon)
+	}
+	return nil
+}
+
+// forecaster is implemented by both predictors of package ar.
+type forecaster interface {
+	Forecast(numToPredict int) (*ar.ForecastResult, error)
+}
+
+// newPredictor validates the request and creates its predictor. The parameters are validated
+// by the predictor constructors, so the errors are theirs.
+func newPredictor(req FitRequest) (forecaster, error) {
+	if len(req.Data) == 0 {
+		return nil, errors.New("data must not be empty")
+	}
+	for i, row := range req.Data {
+		if len(row) != 2 {
+			return nil, fmt.Errorf("data row %d has %d values, expected 2: [data_value, time_value]", i, len(row))
+		}
+	}
+
+	switch req.Model {
+	case "ls":
+		return ar.NewLSPredictor(req.Data, ar.LSModelParameters{StepSize: req.Params.StepSize})
+	case "lsarx":
+		strategy := ar.RecursiveStrategy
+		if req.Params.Strategy != "" {
+			var err error
+			if strategy, err = ar.ParsePredictionStrategy(req.Params.Strategy); err != nil {
+				return nil, err
+			}
+		}
+		var latestRegime *ar.ChangePointParameters
+		if req.Params.LatestRegime != "" {
+			cost, err := ar.ParseChangePointCost(req.Params.LatestRegime)
+			if err != nil {
+				return nil, err
+			}
+			latestRegime = &ar.ChangePointParameters{Method: ar.PELT, Cost: cost}
+		}
+		return ar.NewLSARXPredictor(req.Data, ar.LSARXModelParameters{
+			AutoregressiveLags: req.Params.AutoregressiveLags,
+			ExternalInputLags:  req.Params.ExternalInputLags,
+			InputDelay:         req.Params.InputDelay,
+			Intercept:          req.Params.Intercept,
+			StepSize:           req.Params.StepSize,
+			Strategy:           strategy,
+			LatestRegime:       latestRegime,
+		})
+	default:
+		return nil, fmt.Errorf("unknown model type: %q", req.Model)
+	}
+}
+
+// newModelID returns a random ID for a stored model.
+func newModelID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate model ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// decodeRequest decodes the JSON request body into v, rejecting unknown fields.
+func decodeRequest(w http.ResponseWriter, r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("request body must not be empty")
+		}
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	if dec.More() {
+		return errors.New("invalid request body: unexpected data after the JSON object")
+	}
+	return nil
+}
+
+// checkCollinear returns an error when the regressors of the model of a validated request are
+// collinear over the whole series, so that the least-squares fit has no unique solution. Package ar
+// fits such data with the pseudo-solution of the ill-conditioned normal equations, whose
+// coefficients and forecasts are meaningless. With an external input, this is the case of a
+// regularly sampled time column and more than one input lag.
+func checkCollinear(req FitRequest) error {
+	n := len(req.Data)
+	var phi *mat.Dense
+	switch req.Model {
+	case "ls":
+		phi = mat.NewDense(n, 4, nil)
+		for i, row := range req.Data {
+			t := row[1]
+			phi.SetRow(i, []float64{t * t, t, 1, math.Cos(t)})
+		}
+	case "lsarx":
+		na, nb, nk := req.Params.AutoregressiveLags, req.Params.ExternalInputLags, req.Params.InputDelay
+		m := na
+		if nb > 0 {
+			m = max(na, nk+nb-1)
+		}
+		cols := na + nb
+		if req.Params.Intercept {
+			cols++
+		}
+		if n-m < cols {
+			return nil
+		}
+		phi = mat.NewDense(n-m, cols, nil)
+		for i := m; i < n; i++ {
+			row := phi.RawRowView(i - m)
+			for j := 0; j < na; j++ {
+				row[j] = req.Data[i-1-j][0]
+			}
+			for j := 0; j < nb; j++ {
+				row[na+j] = req.Data[i-nk-j][1]
+			}
+			if req.Params.Intercept {
+				row[na+nb] = 1
+			}
+		}
+	default:
+		return nil
+	}
+	var normal, inverse mat.Dense
+	normal.Mul(phi.T(), phi)
+	if err := inverse.Inverse(&normal); err != nil {
+		return fmt.Errorf("regressors are collinear for this data: %v", err)
+	}
+	return nil
+}
+
+// checkFinite returns an error when a value of the rows is NaN or infinite, as the forecasts of an
+// unstable or ill-conditioned model become, which JSON cannot represent.
+func checkFinite(rows ...[][]float64) error {
+	for _, r := range rows {
+		for _, row := range r {
+			for _, v := range row {
+				if math.IsNaN(v) || math.IsInf(v, 0) {
+					return errors.New("model produced non-finite values, it is unstable or ill-conditioned for this data")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// writeJSON encodes v before writing the status, so that a value that cannot be encoded is
+// reported as an internal error instead of an empty success.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to encode response: %w", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(append(b, '\n'))
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrModelNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err)
+}
@@ -0,0 +1,306 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testSeries returns a series with a known input response: each row is [data_value, time_value].
+// A small disturbance keeps the lags of the series from being exactly collinear.
+func testSeries() [][]float64 {
+	data := make([][]float64, 60)
+	y := 10.0
+	for i := range data {
+		u := float64(i) + float64(i%5)
+		y = 0.5*y + 2*u + 0.1*math.Sin(1.7*float64(i))
+		data[i] = []float64{y, u}
+	}
+	return data
+}
+
+func doRequest(t *testing.T, h http.Handler, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var b bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&b).Encode(body); err != nil {
+			t.Fatalf("failed to encode body: %v", err)
+		}
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, &b))
+	return rec
+}
+
+func decodeResponse(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("failed to decode response %q: %v", rec.Body.String(), err)
+	}
+}
+
+func TestListModelTypes(t *testing.T) {
+	h := NewHandler(NewMemoryStore())
+
+	rec := doRequest(t, h, http.MethodGet, "/model-types", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /model-types status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var types []ModelType
+	decodeResponse(t, rec, &types)
+	if len(types) != 2 || types[0].Name != "ls" || types[1].Name != "lsarx" {
+		t.Errorf("GET /model-types = %+v", types)
+	}
+}
+
+func TestForecast(t *testing.T) {
+	h := NewHandler(NewMemoryStore())
+
+	rec := doRequest(t, h, http.MethodPost, "/forecast", FitRequest{
+		Model:   "lsarx",
+		Data:    testSeries(),
+		Params:  ModelParameters{AutoregressiveLags: 2, ExternalInputLags: 2, StepSize: 1},
+		Horizon: 4,
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /forecast status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var resp ForecastResponse
+	decodeResponse(t, rec, &resp)
+	if len(resp.Forecast) != 4 || len(resp.Fitted) != 58 || len(resp.Residuals) != 58 {
+		t.Errorf("POST /forecast returned %d forecast, %d fitted and %d residual values", len(resp.Forecast), len(resp.Fitted), len(resp.Residuals))
+	}
+}
+
+func TestForecastValidation(t *testing.T) {
+	h := NewHandler(NewMemoryStore())
+
+	testCases := []struct {
+		name    string
+		body    string
+		message string
+	}{
+		{
+			name:    "Invalid lags",
+			body:    `{"model":"lsarx","data":[[1,1],[2,2]],"params":{"autoregressive_lags":0,"step_size":1},"horizon":1}`,
+			message: "lags must be positive integers, autoregressive lags: 0, external input lags: 0",
+		},
+		{
+			name:    "Invalid step size",
+			body:    `{"model":"ls","data":[[1,1],[2,2]],"params":{"step_size":0},"horizon":1}`,
+			message: "step size must be a positive number, step size: 0.000000",
+		},
+		{
+			name:    "Unknown strategy",
+			body:    `{"model":"lsarx","data":[[1,1],[2,2]],"params":{"autoregressive_lags":1,"step_size":1,"strategy":"sideways"},"horizon":1}`,
+			message: `unknown prediction strategy: "sideways"`,
+		},
+		{
+			name:    "Unknown model",
+			body:    `{"model":"arima","data":[[1,1]],"horizon":1}`,
+			message: `unknown model type: "arima"`,
+		},
+		{
+			name:    "Missing horizon",
+			body:    `{"model":"ls","data":[[1,1]],"params":{"step_size":1}}`,
+			message: "horizon must be a positive integer, horizon: 0",
+		},
+		{
+			name:    "Malformed row",
+			body:    `{"model":"ls","data":[[1,1],[2]],"params":{"step_size":1},"horizon":1}`,
+			message: "data row 1 has 1 values, expected 2: [data_value, time_value]",
+		},
+		{
+			name:    "Unknown field",
+			body:    `{"model":"ls","lags":3}`,
+			message: `invalid request body: json: unknown field "lags"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/forecast", strings.NewReader(tc.body)))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("POST /forecast status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+			var resp struct {
+				Error string `json:"error"`
+			}
+			decodeResponse(t, rec, &resp)
+			if resp.Error != tc.message {
+				t.Errorf("POST /forecast error = %q, want %q", resp.Error, tc.message)
+			}
+		})
+	}
+}
+
+func TestStoredModels(t *testing.T) {
+	h := NewHandler(NewMemoryStore())
+
+	rec := doRequest(t, h, http.MethodPost, "/models", FitRequest{
+		Model:  "lsarx",
+		Data:   testSeries(),
+		Params: ModelParameters{AutoregressiveLags: 2, ExternalInputLags: 2, StepSize: 1, Strategy: "direct", MaxHorizon: 3},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /models status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var created ModelResponse
+	decodeResponse(t, rec, &created)
+	if created.ID == "" || rec.Header().Get("Location") != "/models/"+created.ID {
+		t.Fatalf("POST /models returned ID %q and location %q", created.ID, rec.Header().Get("Location"))
+	}
+
+	rec = doRequest(t, h, http.MethodGet, "/models", nil)
+	var list struct {
+		IDs []string `json:"ids"`
+	}
+	decodeResponse(t, rec, &list)
+	if len(list.IDs) != 1 || list.IDs[0] != created.ID {
+		t.Errorf("GET /models = %v, want [%s]", list.IDs, created.ID)
+	}
+
+	rec = doRequest(t, h, http.MethodGet, "/models/"+created.ID, nil)
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /models/{id} status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	rec = doRequest(t, h, http.MethodPost, "/models/"+created.ID+"/forecast", ForecastRequest{Horizon: 3})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /models/{id}/forecast status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var forecast ForecastResponse
+	decodeResponse(t, rec, &forecast)
+	if len(forecast.Forecast) != 3 {
+		t.Errorf("POST /models/{id}/forecast returned %d values, want 3", len(forecast.Forecast))
+	}
+
+	rec = doRequest(t, h, http.MethodPost, "/models/"+created.ID+"/forecast", ForecastRequest{Horizon: 4})
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("POST /models/{id}/forecast past the fitted horizon status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+
+	rec = doRequest(t, h, http.MethodDelete, "/models/"+created.ID, nil)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE /models/{id} status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	rec = doRequest(t, h, http.MethodGet, "/models/"+created.ID, nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /models/{id} after delete status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestForecastIllConditioned(t *testing.T) {
+	h := NewHandler(NewMemoryStore())
+
+	// The lags of a constant series and of regularly sampled time values are collinear, and the
+	// forecast of an explosive series overflows.
+	constant := make([][]float64, 30)
+	regular := make([][]float64, 60)
+	explosive := make([][]float64, 60)
+	for i := range constant {
+		constant[i] = []float64{5, float64(i)}
+	}
+	for i := range regular {
+		regular[i] = []float64{math.Sin(float64(i)), float64(i)}
+	}
+	y := 1.0
+	for i := range explosive {
+		y = 1.5*y + math.Sin(float64(i))
+		explosive[i] = []float64{y, float64(i)}
+	}
+	for name, req := range map[string]FitRequest{
+		"Constant":     {Model: "lsarx", Data: constant, Params: ModelParameters{AutoregressiveLags: 2, ExternalInputLags: 2, StepSize: 1}, Horizon: 200},
+		"Regular time": {Model: "lsarx", Data: regular, Params: ModelParameters{AutoregressiveLags: 1, ExternalInputLags: 3, StepSize: 1}, Horizon: 5},
+		"Explosive":    {Model: "lsarx", Data: explosive, Params: ModelParameters{AutoregressiveLags: 1, ExternalInputLags: 1, StepSize: 1}, Horizon: 2000},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := doRequest(t, h, http.MethodPost, "/forecast", req)
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("POST /forecast status = %d, want %d, body %s", rec.Code, http.StatusUnprocessableEntity, rec.Body.String())
+			}
+			var resp struct {
+				Error string `json:"error"`
+			}
+			decodeResponse(t, rec, &resp)
+			if resp.Error == "" {
+				t.Error("POST /forecast returned no error message")
+			}
+
+			// Collinear regressors are rejected before a model is stored as well.
+			if name != "Explosive" {
+				if rec := doRequest(t, h, http.MethodPost, "/models", req); rec.Code != http.StatusUnprocessableEntity {
+					t.Errorf("POST /models status = %d, want %d, body %s", rec.Code, http.StatusUnprocessableEntity, rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestWriteJSONEncodingError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeJSON(rec, http.StatusOK, ForecastResponse{Forecast: [][]float64{{1, math.Inf(1)}}})
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("writeJSON() of an infinite value status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	var resp struct {
+		Error string `json:"error"`
+	}
+	decodeResponse(t, rec, &resp)
+	if resp.Error == "" {
+		t.Error("writeJSON() of an infinite value returned no error message")
+	}
+}
+
+func TestHorizonLimit(t *testing.T) {
+	h := NewHandler(NewMemoryStore())
+	rec := doRequest(t, h, http.MethodPost, "/models", FitRequest{
+		Model:  "lsarx",
+		Data:   testSeries(),
+		Params: ModelParameters{AutoregressiveLags: 2, ExternalInputLags: 2, StepSize: 1},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /models status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var created ModelResponse
+	decodeResponse(t, rec, &created)
+
+	testCases := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"Forecast", "/forecast", `{"model":"lsarx","data":[[1,1],[2,2],[3,4]],"params":{"autoregressive_lags":1,"step_size":1},"horizon":2000000000}`},
+		{"Max horizon", "/models", `{"model":"lsarx","data":[[1,1],[2,2],[3,4]],"params":{"autoregressive_lags":1,"step_size":1,"strategy":"direct","max_horizon":2000000000}}`},
+		{"Stored model", "/models/" + created.ID + "/forecast", `{"horizon":2000000000}`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body)))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("POST %s status = %d, want %d, body %s", tc.path, rec.Code, http.StatusBadRequest, rec.Body.String())
+			}
+		})
+	}
+
+	// The limit is configurable.
+	limited := NewHandler(NewMemoryStore(), WithMaxHorizon(5))
+	for horizon, want := range map[int]int{5: http.StatusOK, 6: http.StatusBadRequest} {
+		rec := doRequest(t, limited, http.MethodPost, "/forecast", FitRequest{
+			Model:   "lsarx",
+			Data:    testSeries(),
+			Params:  ModelParameters{AutoregressiveLags: 2, ExternalInputLags: 2, StepSize: 1},
+			Horizon: horizon,
+		})
+		if rec.Code != want {
+			t.Errorf("POST /forecast with horizon %d status = %d, want %d", horizon, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrModelNotFound is returned by a ModelStore when no model is stored under the given ID.
+var ErrModelNotFound = errors.New("model not found")
+
+// ModelStore persists fitted models, encoded as the JSON written by their MarshalJSON methods.
+type ModelStore interface {
+	Save(id string, model []byte) error
+	Load(id string) ([]byte, error)
+	Delete(id string) error
+	List() ([]string, error)
+}
+
+// MemoryStore is a ModelStore that keeps the models in memory. It is safe for concurrent use.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	models map[string][]byte
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{models: map[string][]byte{}}
+}
+
+// Save stores the model under the given ID, replacing any previous model.
+func (s *MemoryStore) Save(id string, model []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.models[id] = append([]byte(nil), model...)
+	return nil
+}
+
+// Load returns the model stored under the given ID.
+func (s *MemoryStore) Load(id string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	model, ok := s.models[id]
+	if !ok {
+		return nil, ErrModelNotFound
+	}
+	return model, nil
+}
+
+// Delete removes the model stored under the given ID.
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.models[id]; !ok {
+		return ErrModelNotFound
+	}
+	delete(s.models, id)
+	return nil
+}
+
+// List returns the IDs of the stored models, sorted.
+func (s *MemoryStore) List() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := make([]string, 0, len(s.models))
+	for id := range s.models {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
@@ -0,0 +1,215 @@
+package ar
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+
+	"gonum.org/v1/gonum/dsp/fourier"
+)
+
+// Spectrum is a power spectral density at a grid of frequencies. Frequencies are in cycles per
+// sample, from 0 to the Nyquist frequency 0.5; divide them by the StepSize of a model for cycles
+// per time unit. The densities are two-sided, so their integral over [-0.5, 0.5] is the variance
+// of the process, and the densities of the models compare directly with the Periodogram and
+// Welch estimates of the data they were fitted on.
+type Spectrum struct {
+	Frequencies []float64 // Frequencies in cycles per sample.
+	Values      []float64 // Power spectral density at every frequency.
+}
+
+// FrequencyResponse is the response of a transfer function at a grid of frequencies, as shown
+// in a Bode plot.
+type FrequencyResponse struct {
+	Frequencies []float64 // Frequencies in cycles per sample.
+	Magnitude   []float64 // Gain |G| at every frequency.
+	Phase       []float64 // Unwrapped phase of G in degrees at every frequency.
+}
+
+// frequencyGrid returns n evenly spaced frequencies from 0 to 0.5.
+func frequencyGrid(n int) ([]float64, error) {
+	if n < 2 {
+		return nil, fmt.Errorf("number of frequencies must be at least 2, got: %d", n)
+	}
+	f := make([]float64, n)
+	for i := range f {
+		f[i] = 0.5 * float64(i) / float64(n-1)
+	}
+	return f, nil
+}
+
+// lagPolynomial evaluates sum_j coeffs[j] z^-(first+j) at z = exp(i 2 pi f).
+func lagPolynomial(coeffs []float64, first int, f float64) complex128 {
+	var sum complex128
+	for j, c := range coeffs {
+		sum += complex(c, 0) * cmplx.Exp(complex(0, -2*math.Pi*f*float64(first+j)))
+	}
+	return sum
+}
+
+// monicPolynomial evaluates 1 + sum_j coeffs[j] z^-(j+1) at z = exp(i 2 pi f), the form of the
+// A, C, D and F polynomials.
+func monicPolynomial(coeffs []float64, f float64) complex128 {
+	return 1 + lagPolynomial(coeffs, 1, f)
+}
+
+// rationalSpectrum returns sigma2 |num(f)|^2 / |den(f)|^2 over a grid of n frequencies.
+func rationalSpectrum(n int, sigma2 float64, num func(f float64) complex128, den func(f float64) complex128) (*Spectrum, error) {
+	freqs, err := frequencyGrid(n)
+	if err != nil {
+		return nil, err
+	}
+	s := &Spectrum{Frequencies: freqs, Values: make([]float64, n)}
+	for i, f := range freqs {
+		s.Values[i] = sigma2 * math.Pow(cmplx.Abs(num(f)), 2) / math.Pow(cmplx.Abs(den(f)), 2)
+	}
+	return s, nil
+}
+
+// transferResponse returns the response of num(f) / den(f) over a grid of n frequencies.
+func transferResponse(n int, num func(f float64) complex128, den func(f float64) complex128) (*FrequencyResponse, error) {
+	freqs, err := frequencyGrid(n)
+	if err != nil {
+		return nil, err
+	}
+	r := &FrequencyResponse{Frequencies: freqs, Magnitude: make([]float64, n), Phase: make([]float64, n)}
+	prev := 0.0
+	for i, f := range freqs {
+		g := num(f) / den(f)
+		r.Magnitude[i] = cmplx.Abs(g)
+		phase := cmplx.Phase(g)
+		if i > 0 {
+			// Unwrap: keep every step within half a turn of the previous phase.
+			phase -= 2 * math.Pi * math.Round((phase-prev)/(2*math.Pi))
+		}
+		r.Phase[i] = phase * 180 / math.Pi
+		prev = phase
+	}
+	return r, nil
+}
+
+// PowerSpectralDensity returns the spectral density of the noise part of the model,
+// sigma2 / |A(f)|^2, at n frequencies from 0 to 0.5. For na > 0 and nb = 0 it is the
+// spectrum of the fitted AR process.
+func (m *LSARXModel) PowerSpectralDensity(n int) (*Spectrum, error) {
+	s := m.Params.structure()
+	if len(m.Theta) != s.numParams() {
+		return nil, fmt.Errorf("model has %d coefficients, expected %d", len(m.Theta), s.numParams())
+	}
+	a := m.Theta[:s.na]
+	return rationalSpectrum(n, m.Metadata.Sigma2, func(float64) complex128 { return 1 }, func(f float64) complex128 { return monicPolynomial(a, f) })
+}
+
+// FrequencyResponse returns the response of the transfer function from the external input to
+// the output, B(q) q^-nk / A(q), at n frequencies from 0 to 0.5.
+func (m *LSARXModel) FrequencyResponse(n int) (*FrequencyResponse, error) {
+	s := m.Params.structure()
+	if len(m.Theta) != s.numParams() {
+		return nil, fmt.Errorf("model has %d coefficients, expected %d", len(m.Theta), s.numParams())
+	}
+	a, b := m.Theta[:s.na], m.Theta[s.na:s.na+s.nb]
+	return transferResponse(n, func(f float64) complex128 { return lagPolynomial(b, s.nk, f) }, func(f float64) complex128 { return monicPolynomial(a, f) })
+}
+
+// PowerSpectralDensity returns the spectral density of the model, sigma2 |C(f)|^2 / |A(f)|^2,
+// at n frequencies from 0 to 0.5.
+func (m *ARMAModel) PowerSpectralDensity(n int) (*Spectrum, error) {
+	return rationalSpectrum(n, m.Sigma2, func(f float64) complex128 { return monicPolynomial(m.MA, f) }, func(f float64) complex128 { return monicPolynomial(m.AR, f) })
+}
+
+// PowerSpectralDensity returns the spectral density of the noise part of the model,
+// sigma2 |C(f)|^2 / |A(f) D(f)|^2, at n frequencies from 0 to 0.5.
+func (m *PolynomialModel) PowerSpectralDensity(n int) (*Spectrum, error) {
+	return rationalSpectrum(n, m.Sigma2, func(f float64) complex128 { return monicPolynomial(m.C, f) }, func(f float64) complex128 {
+		return monicPolynomial(m.A, f) * monicPolynomial(m.D, f)
+	})
+}
+
+// FrequencyResponse returns the response of the transfer function from the input to the output,
+// B(q) q^-nk / (A(q) F(q)), at n frequencies from 0 to 0.5.
+func (m *PolynomialModel) FrequencyResponse(n int) (*FrequencyResponse, error) {
+	return transferResponse(n, func(f float64) complex128 { return lagPolynomial(m.B, m.Params.InputDelay, f) }, func(f float64) complex128 {
+		return monicPolynomial(m.A, f) * monicPolynomial(m.F, f)
+	})
+}
+
+// windowedSpectrum returns |FFT(w x)|^2 / sum(w^2) of the values with their mean removed, at the
+// frequencies k/len(values) for k = 0 .. len(values)/2. A nil window is rectangular.
+func windowedSpectrum(values []float64, window []float64) *Spectrum {
+	n := len(values)
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(n)
+
+	x := make([]float64, n)
+	norm := 0.0
+	for i, v := range values {
+		w := 1.0
+		if window != nil {
+			w = window[i]
+		}
+		x[i] = w * (v - mean)
+		norm += w * w
+	}
+
+	fft := fourier.NewFFT(n)
+	coeffs := fft.Coefficients(nil, x)
+	s := &Spectrum{Frequencies: make([]float64, len(coeffs)), Values: make([]float64, len(coeffs))}
+	for k, c := range coeffs {
+		s.Frequencies[k] = fft.Freq(k)
+		s.Values[k] = math.Pow(cmplx.Abs(c), 2) / norm
+	}
+	return s
+}
+
+// Periodogram returns the periodogram of the values with their mean removed,
+// |sum_t x[t] exp(-i 2 pi f t)|^2 / N, at the Fourier frequencies k/N for k = 0 .. N/2.
+func Periodogram(values []float64) (*Spectrum, error) {
+	if len(values) < 2 {
+		return nil, fmt.Errorf("not enough data points for a periodogram, need at least 2 points")
+	}
+	return windowedSpectrum(values, nil), nil
+}
+
+// Welch returns the Welch estimate of the power spectral density of the values: the average of
+// the periodic Hann-windowed periodograms of segments of segmentLength values, each starting
+// segmentLength - overlap values after the previous one. The mean is removed from every segment.
+// The frequencies are k/segmentLength for k = 0 .. segmentLength/2.
+func Welch(values []float64, segmentLength int, overlap int) (*Spectrum, error) {
+	if segmentLength < 2 {
+		return nil, fmt.Errorf("segment length must be at least 2, got: %d", segmentLength)
+	}
+	if overlap < 0 || overlap >= segmentLength {
+		return nil, fmt.Errorf("overlap must be between 0 and the segment length minus 1, overlap: %d, segment length: %d", overlap, segmentLength)
+	}
+	if len(values) < segmentLength {
+		return nil, fmt.Errorf("not enough data points for a segment, need at least %d points", segmentLength)
+	}
+
+	// The periodic Hann window, unlike the symmetric one of denominator segmentLength-1, is not zero
+	// everywhere for segments of 2 values.
+	window := make([]float64, segmentLength)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(segmentLength))
+	}
+
+	var result *Spectrum
+	segments := 0
+	for start := 0; start+segmentLength <= len(values); start += segmentLength - overlap {
+		s := windowedSpectrum(values[start:start+segmentLength], window)
+		if result == nil {
+			result = s
+		} else {
+			for k, v := range s.Values {
+				result.Values[k] += v
+			}
+		}
+		segments++
+	}
+	for k := range result.Values {
+		result.Values[k] /= float64(segments)
+	}
+	return result, nil
+}
@@ -0,0 +1,173 @@
+package ar
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestLSARXPowerSpectralDensity(t *testing.T) {
+	// y[t] = 0.5 y[t-1] + 2 u[t] + e[t] with unit noise variance.
+	model := &LSARXModel{
+		Params:   LSARXModelParameters{AutoregressiveLags: 1, ExternalInputLags: 1},
+		Theta:    []float64{-0.5, 2},
+		Metadata: TrainingMetadata{Sigma2: 1},
+	}
+	psd, err := model.PowerSpectralDensity(11)
+	if err != nil {
+		t.Fatalf("PowerSpectralDensity() error = %v", err)
+	}
+	for i, f := range psd.Frequencies {
+		want := 1 / (1.25 - math.Cos(2*math.Pi*f))
+		if !approxEqual(psd.Values[i], want, 1e-12) {
+			t.Errorf("PSD at %v = %v, want %v", f, psd.Values[i], want)
+		}
+	}
+	if psd.Frequencies[0] != 0 || psd.Frequencies[10] != 0.5 {
+		t.Errorf("frequencies = %v, want 0 .. 0.5", psd.Frequencies)
+	}
+
+	if _, err := model.PowerSpectralDensity(1); err == nil {
+		t.Error("PowerSpectralDensity(1) returned no error")
+	}
+}
+
+func TestLSARXFrequencyResponse(t *testing.T) {
+	tests := []struct {
+		name      string
+		params    LSARXModelParameters
+		theta     []float64
+		magnitude [2]float64 // At 0 and 0.5 cycles per sample.
+		phase     [2]float64
+	}{
+		{"First order", LSARXModelParameters{AutoregressiveLags: 1, ExternalInputLags: 1}, []float64{-0.5, 2}, [2]float64{4, 2 / 1.5}, [2]float64{0, 0}},
+		{"Pure delay", LSARXModelParameters{ExternalInputLags: 1, InputDelay: 3}, []float64{1}, [2]float64{1, 1}, [2]float64{0, -540}},
+		{"Delay and lag", LSARXModelParameters{AutoregressiveLags: 1, ExternalInputLags: 1, InputDelay: 1}, []float64{-0.5, 2}, [2]float64{4, 2 / 1.5}, [2]float64{0, -180}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := &LSARXModel{Params: tt.params, Theta: tt.theta}
+			r, err := model.FrequencyResponse(101)
+			if err != nil {
+				t.Fatalf("FrequencyResponse() error = %v", err)
+			}
+			if !approxEqual(r.Magnitude[0], tt.magnitude[0], 1e-12) || !approxEqual(r.Magnitude[100], tt.magnitude[1], 1e-12) {
+				t.Errorf("magnitude = %v .. %v, want %v", r.Magnitude[0], r.Magnitude[100], tt.magnitude)
+			}
+			if !approxEqual(r.Phase[0], tt.phase[0], 1e-9) || !approxEqual(r.Phase[100], tt.phase[1], 1e-9) {
+				t.Errorf("phase = %v .. %v, want %v", r.Phase[0], r.Phase[100], tt.phase)
+			}
+		})
+	}
+}
+
+func TestPolynomialAndARMASpectra(t *testing.T) {
+	// An ARMAX model has the noise spectrum of the ARMA model with the same A and C.
+	poly := newPolynomialModel(PolynomialModelParameters{Structure: ARMAXStructure, AutoregressiveLags: 1, NoiseLags: 1}, []float64{-0.7, 1, 0.4})
+	poly.Sigma2 = 2
+	arma := &ARMAModel{AR: []float64{-0.7}, MA: []float64{0.4}, Sigma2: 2}
+
+	got, err := poly.PowerSpectralDensity(5)
+	if err != nil {
+		t.Fatalf("PowerSpectralDensity() error = %v", err)
+	}
+	want, err := arma.PowerSpectralDensity(5)
+	if err != nil {
+		t.Fatalf("PowerSpectralDensity() error = %v", err)
+	}
+	for i := range want.Values {
+		if !approxEqual(got.Values[i], want.Values[i], 1e-12) {
+			t.Errorf("PSD = %v, want %v", got.Values, want.Values)
+			break
+		}
+	}
+	if w := 2 * 1.4 * 1.4 / (0.3 * 0.3); !approxEqual(want.Values[0], w, 1e-9) {
+		t.Errorf("PSD at 0 = %v, want %v", want.Values[0], w)
+	}
+
+	r, err := poly.FrequencyResponse(3)
+	if err != nil {
+		t.Fatalf("FrequencyResponse() error = %v", err)
+	}
+	if !approxEqual(r.Magnitude[0], 1/0.3, 1e-12) {
+		t.Errorf("static gain = %v, want %v", r.Magnitude[0], 1/0.3)
+	}
+}
+
+func TestPeriodogram(t *testing.T) {
+	values := make([]float64, 64)
+	for i := range values {
+		values[i] = 5 + 3*math.Cos(2*math.Pi*8*float64(i)/64)
+	}
+	p, err := Periodogram(values)
+	if err != nil {
+		t.Fatalf("Periodogram() error = %v", err)
+	}
+	if len(p.Frequencies) != 33 || p.Frequencies[8] != 0.125 {
+		t.Fatalf("frequencies = %v, want k/64 for k = 0 .. 32", p.Frequencies)
+	}
+	// The power of the cosine, 9/2, is split between +-1/8: N 9/4 at the positive frequency.
+	if !approxEqual(p.Values[8], 64*9.0/4, 1e-9) || !approxEqual(p.Values[0], 0, 1e-9) {
+		t.Errorf("periodogram at 0 and 1/8 = %v and %v, want 0 and %v", p.Values[0], p.Values[8], 64*9.0/4)
+	}
+
+	if _, err := Periodogram([]float64{1}); err == nil {
+		t.Error("Periodogram() of one value returned no error")
+	}
+}
+
+func TestWelch(t *testing.T) {
+	rnd := rand.New(rand.NewSource(3))
+	values := make([]float64, 4096)
+	for i := range values {
+		values[i] = 2 * rnd.NormFloat64()
+	}
+	w, err := Welch(values, 128, 64)
+	if err != nil {
+		t.Fatalf("Welch() error = %v", err)
+	}
+	if len(w.Values) != 65 {
+		t.Fatalf("len(Values) = %d, want 65", len(w.Values))
+	}
+	// White noise has a flat density equal to its variance.
+	mean := 0.0
+	for _, v := range w.Values[1:] {
+		mean += v
+	}
+	mean /= float64(len(w.Values) - 1)
+	if !approxEqual(mean, 4, 0.3) {
+		t.Errorf("mean Welch density = %v, want 4", mean)
+	}
+
+	// The shortest segments have a nonzero window and finite densities.
+	for _, segment := range []int{2, 3} {
+		short, err := Welch(values, segment, 0)
+		if err != nil {
+			t.Fatalf("Welch() with segments of %d error = %v", segment, err)
+		}
+		for k, v := range short.Values {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				t.Errorf("Welch() with segments of %d value %d = %v, want a finite density", segment, k, v)
+			}
+		}
+	}
+
+	tests := []struct {
+		name    string
+		values  []float64
+		segment int
+		overlap int
+	}{
+		{"Short segment", values, 1, 0},
+		{"Negative overlap", values, 128, -1},
+		{"Overlap of a full segment", values, 128, 128},
+		{"Not enough data", values[:100], 128, 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Welch(tt.values, tt.segment, tt.overlap); err == nil {
+				t.Error("Welch() returned no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,473 @@
+package ar
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// StateSpaceModel is a linear Gaussian state-space model with optional inputs u[t]:
+//
+//	x[t] = T x[t-1] + c + B u[t] + w[t],  w[t] ~ N(0, Q)
+//	y[t] = Z x[t] + d + D u[t] + v[t],    v[t] ~ N(0, H)
+//
+// where x[0] ~ N(InitialState, InitialCovariance). The intercepts and input matrices are optional.
+type StateSpaceModel struct {
+	T                 *mat.Dense    // Transition matrix (m x m).
+	Q                 *mat.SymDense // State noise covariance (m x m).
+	Z                 *mat.Dense    // Observation matrix (p x m).
+	H                 *mat.SymDense // Observation noise covariance (p x p).
+	StateIntercept    []float64     // c (m), nil for none.
+	ObsIntercept      []float64     // d (p), nil for none.
+	B                 *mat.Dense    // Input matrix of the state (m x r), nil without inputs.
+	D                 *mat.Dense    // Input matrix of the observations (p x r), nil for none.
+	InitialState      []float64     // Prior mean of x[0] (m).
+	InitialCovariance *mat.SymDense // Prior covariance of x[0] (m x m).
+}
+
+// KalmanResult holds the output of the Kalman filter, one entry per time step.
+type KalmanResult struct {
+	PredictedStates       [][]float64     // a[t|t-1]: state mean given the observations before t.
+	PredictedCovariances  []*mat.SymDense // P[t|t-1]: state covariance given the observations before t.
+	FilteredStates        [][]float64     // a[t|t]: state mean given the observations up to t.
+	FilteredCovariances   []*mat.SymDense // P[t|t]: state covariance given the observations up to t.
+	Innovations           [][]float64     // y[t] - E[y[t] | observations before t], NaN where y[t] is missing.
+	InnovationCovariances []*mat.SymDense // Covariance of the innovations, over all observation components.
+	LogLikelihood         float64         // Exact Gaussian log-likelihood of the observed values.
+}
+
+// SmootherResult holds the output of the Rauch-Tung-Striebel smoother, one entry per time step.
+type SmootherResult struct {
+	States      [][]float64     // a[t|n]: state mean given all the observations.
+	Covariances []*mat.SymDense // P[t|n]: state covariance given all the observations.
+}
+
+// StateSpaceForecast holds the forecast of a state-space model, one entry per forecast step.
+type StateSpaceForecast struct {
+	States                 [][]float64     // Forecast state means.
+	StateCovariances       []*mat.SymDense // Forecast state covariances.
+	Observations           [][]float64     // Forecast observation means.
+	ObservationCovariances []*mat.SymDense // Forecast observation covariances.
+}
+
+// validate checks the dimensions of the model matrices.
+func (s *StateSpaceModel) validate() (m int, p int, r int, err error) {
+	if s.T == nil || s.Q == nil || s.Z == nil || s.H == nil || s.InitialCovariance == nil {
+		return 0, 0, 0, fmt.Errorf("state-space model must define T, Q, Z, H and the initial covariance")
+	}
+	m, mc := s.T.Dims()
+	p, zc := s.Z.Dims()
+	switch {
+	case m != mc:
+		return 0, 0, 0, fmt.Errorf("transition matrix must be square, got %d x %d", m, mc)
+	case zc != m:
+		return 0, 0, 0, fmt.Errorf("observation matrix has %d columns, expected %d", zc, m)
+	case s.Q.SymmetricDim() != m:
+		return 0, 0, 0, fmt.Errorf("state noise covariance has dimension %d, expected %d", s.Q.SymmetricDim(), m)
+	case s.H.SymmetricDim() != p:
+		return 0, 0, 0, fmt.Errorf("observation noise covariance has dimension %d, expected %d", s.H.SymmetricDim(), p)
+	case len(s.InitialState) != m:
+		return 0, 0, 0, fmt.Errorf("initial state has %d values, expected %d", len(s.InitialState), m)
+	case s.InitialCovariance.SymmetricDim() != m:
+		return 0, 0, 0, fmt.Errorf("initial covariance has dimension %d, expected %d", s.InitialCovariance.SymmetricDim(), m)
+	case s.StateIntercept != nil && len(s.StateIntercept) != m:
+		return 0, 0, 0, fmt.Errorf("state intercept has %d values, expected %d", len(s.StateIntercept), m)
+	case s.ObsIntercept != nil && len(s.ObsIntercept) != p:
+		return 0, 0, 0, fmt.Errorf("observation intercept has %d values, expected %d", len(s.ObsIntercept), p)
+	}
+	if s.B != nil {
+		var br int
+		br, r = s.B.Dims()
+		if br != m {
+			return 0, 0, 0, fmt.Errorf("state input matrix has %d rows, expected %d", br, m)
+		}
+	}
+	if s.D != nil {
+		dr, dc := s.D.Dims()
+		if dr != p || (s.B != nil && dc != r) {
+			return 0, 0, 0, fmt.Errorf("observation input matrix is %d x %d, expected %d x %d", dr, dc, p, r)
+		}
+		r = dc
+	}
+	return m, p, r, nil
+}
+
+// checkInputs checks that there is one input row of r values for each of the n time steps.
+func checkInputs(inputs [][]float64, n int, r int) error {
+	if r == 0 {
+		return nil
+	}
+	if len(inputs) < n {
+		return fmt.Errorf("inputs have %d rows, need %d", len(inputs), n)
+	}
+	return checkColumns(inputs[:n], r, "inputs")
+}
+
+// predictState returns the mean and covariance of x[t] given x[t-1] ~ N(a, P) and the input u.
+func (s *StateSpaceModel) predictState(a []float64, P mat.Symmetric, u []float64) ([]float64, *mat.SymDense) {
+	var next mat.VecDense
+	next.MulVec(s.T, mat.NewVecDense(len(a), a))
+	if s.StateIntercept != nil {
+		next.AddVec(&next, mat.NewVecDense(len(s.StateIntercept), s.StateIntercept))
+	}
+	if s.B != nil {
+		var bu mat.VecDense
+		bu.MulVec(s.B, mat.NewVecDense(len(u), u))
+		next.AddVec(&next, &bu)
+	}
+
+	var TP, TPT mat.Dense
+	TP.Mul(s.T, P)
+	TPT.Mul(&TP, s.T.T())
+	TPT.Add(&TPT, s.Q)
+	return next.RawVector().Data, symmetrize(&TPT)
+}
+
+// observe returns the mean and covariance of y[t] given x[t] ~ N(a, P) and the input u.
+func (s *StateSpaceModel) observe(a []float64, P mat.Symmetric, u []float64) ([]float64, *mat.SymDense) {
+	var y mat.VecDense
+	y.MulVec(s.Z, mat.NewVecDense(len(a), a))
+	if s.ObsIntercept != nil {
+		y.AddVec(&y, mat.NewVecDense(len(s.ObsIntercept), s.ObsIntercept))
+	}
+	if s.D != nil {
+		var du mat.VecDense
+		du.MulVec(s.D, mat.NewVecDense(len(u), u))
+		y.AddVec(&y, &du)
+	}
+
+	var ZP, ZPZ mat.Dense
+	ZP.Mul(s.Z, P)
+	ZPZ.Mul(&ZP, s.Z.T())
+	ZPZ.Add(&ZPZ, s.H)
+	return y.RawVector().Data, symmetrize(&ZPZ)
+}
+
+// Filter runs the Kalman filter over the observations, one row of p values per time step.
+// Missing values are given as NaN and are left out of the update. Models with inputs need one
+// input row per time step; models without inputs take nil.
+func (s *StateSpaceModel) Filter(observations [][]float64, inputs [][]float64) (*KalmanResult, error) {
+	m, p, r, err := s.validate()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkColumns(observations, p, "observations"); err != nil {
+		return nil, err
+	}
+	if err := checkInputs(inputs, len(observations), r); err != nil {
+		return nil, err
+	}
+
+	n := len(observations)
+	result := &KalmanResult{
+		PredictedStates:       make([][]float64, n),
+		PredictedCovariances:  make([]*mat.SymDense, n),
+		FilteredStates:        make([][]float64, n),
+		FilteredCovariances:   make([]*mat.SymDense, n),
+		Innovations:           make([][]float64, n),
+		InnovationCovariances: make([]*mat.SymDense, n),
+	}
+
+	a := append([]float64(nil), s.InitialState...)
+	P := mat.NewSymDense(m, nil)
+	P.CopySym(s.InitialCovariance)
+	for t, y := range observations {
+		var u []float64
+		if r > 0 {
+			u = inputs[t]
+		}
+		if t > 0 {
+			a, P = s.predictState(result.FilteredStates[t-1], result.FilteredCovariances[t-1], u)
+		}
+		result.PredictedStates[t], result.PredictedCovariances[t] = a, P
+
+		yHat, F := s.observe(a, P, u)
+		innovation := make([]float64, p)
+		var observed []int
+		for i := range y {
+			innovation[i] = y[i] - yHat[i]
+			if !math.IsNaN(y[i]) {
+				observed = append(observed, i)
+			}
+		}
+		result.Innovations[t], result.InnovationCovariances[t] = innovation, F
+
+		if len(observed) == 0 {
+			result.FilteredStates[t], result.FilteredCovariances[t] = a, P
+			continue
+		}
+
+		// Restrict the update to the observed components.
+		k := len(observed)
+		Zo := mat.NewDense(k, m, nil)
+		Fo := mat.NewSymDense(k, nil)
+		v := mat.NewVecDense(k, nil)
+		for i, oi := range observed {
+			Zo.SetRow(i, s.Z.RawRowView(oi))
+			v.SetVec(i, innovation[oi])
+			for j, oj := range observed[:i+1] {
+				Fo.SetSym(i, j, F.At(oi, oj))
+			}
+		}
+
+		var chol mat.Cholesky
+		if ok := chol.Factorize(Fo); !ok {
+			return nil, fmt.Errorf("innovation covariance at time %d is not positive definite", t)
+		}
+		var FinvV mat.VecDense
+		if err := chol.SolveVecTo(&FinvV, v); err != nil {
+			return nil, fmt.Errorf("failed to solve innovation system at time %d: %w", t, err)
+		}
+		result.LogLikelihood -= 0.5 * (float64(k)*math.Log(2*math.Pi) + chol.LogDet() + mat.Dot(v, &FinvV))
+
+		// Gain K = P Zo' Fo^-1, a[t|t] = a + K v, P[t|t] = P - K Zo P.
+		var PZt, K mat.Dense
+		PZt.Mul(P, Zo.T())
+		if err := chol.SolveTo(&K, PZt.T()); err != nil {
+			return nil, fmt.Errorf("failed to compute Kalman gain at time %d: %w", t, err)
+		}
+		var Kv mat.VecDense
+		Kv.MulVec(K.T(), v)
+		filtered := make([]float64, m)
+		for i := range filtered {
+			filtered[i] = a[i] + Kv.AtVec(i)
+		}
+		var KZP mat.Dense
+		KZP.Mul(K.T(), PZt.T())
+		var Pf mat.Dense
+		Pf.Sub(P, &KZP)
+		result.FilteredStates[t], result.FilteredCovariances[t] = filtered, symmetrize(&Pf)
+	}
+
+	return result, nil
+}
+
+// LogLikelihood returns the exact Gaussian log-likelihood of the observations, see Filter.
+func (s *StateSpaceModel) LogLikelihood(observations [][]float64, inputs [][]float64) (float64, error) {
+	result, err := s.Filter(observations, inputs)
+	if err != nil {
+		return 0, err
+	}
+	return result.LogLikelihood, nil
+}
+
+// Smooth runs the Kalman filter followed by the Rauch-Tung-Striebel smoother, which estimates
+// every state given all the observations. The arguments are the ones of Filter.
+func (s *StateSpaceModel) Smooth(observations [][]float64, inputs [][]float64) (*SmootherResult, error) {
+	filtered, err := s.Filter(observations, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(observations)
+	result := &SmootherResult{States: make([][]float64, n), Covariances: make([]*mat.SymDense, n)}
+	if n == 0 {
+		return result, nil
+	}
+	result.States[n-1], result.Covariances[n-1] = filtered.FilteredStates[n-1], filtered.FilteredCovariances[n-1]
+
+	for t := n - 2; t >= 0; t-- {
+		// J = P[t|t] T' P[t+1|t]^+, using the pseudo-inverse as P[t+1|t] may be singular.
+		var J mat.Dense
+		J.Mul(filtered.FilteredCovariances[t], s.T.T())
+		J.Mul(&J, pseudoInverse(filtered.PredictedCovariances[t+1]))
+
+		m := len(filtered.FilteredStates[t])
+		diff := mat.NewVecDense(m, nil)
+		for i := 0; i < m; i++ {
+			diff.SetVec(i, result.States[t+1][i]-filtered.PredictedStates[t+1][i])
+		}
+		var Jd mat.VecDense
+		Jd.MulVec(&J, diff)
+		state := make([]float64, m)
+		for i := range state {
+			state[i] = filtered.FilteredStates[t][i] + Jd.AtVec(i)
+		}
+
+		var dP, JdP mat.Dense
+		dP.Sub(result.Covariances[t+1], filtered.PredictedCovariances[t+1])
+		JdP.Mul(&J, &dP)
+		JdP.Mul(&JdP, J.T())
+		JdP.Add(filtered.FilteredCovariances[t], &JdP)
+
+		result.States[t], result.Covariances[t] = state, symmetrize(&JdP)
+	}
+	return result, nil
+}
+
+// Forecast forecasts numToPredict steps ahead from a state x[t] ~ N(state, covariance), typically the
+// last filtered state. Models with inputs need the inputs of the steps t+1 .. t+numToPredict.
+func (s *StateSpaceModel) Forecast(state []float64, covariance *mat.SymDense, futureInputs [][]float64, numToPredict int) (*StateSpaceForecast, error) {
+	m, _, r, err := s.validate()
+	if err != nil {
+		return nil, err
+	}
+	if len(state) != m || covariance == nil || covariance.SymmetricDim() != m {
+		return nil, fmt.Errorf("forecast must start from a state of dimension %d", m)
+	}
+	if numToPredict < 0 {
+		return nil, fmt.Errorf("number of values to predict must not be negative, got: %d", numToPredict)
+	}
+	if err := checkInputs(futureInputs, numToPredict, r); err != nil {
+		return nil, err
+	}
+
+	result := &StateSpaceForecast{}
+	a, P := state, mat.Symmetric(covariance)
+	for h := 0; h < numToPredict; h++ {
+		var u []float64
+		if r > 0 {
+			u = futureInputs[h]
+		}
+		nextA, nextP := s.predictState(a, P, u)
+		y, F := s.observe(nextA, nextP, u)
+		result.States = append(result.States, nextA)
+		result.StateCovariances = append(result.StateCovariances, nextP)
+		result.Observations = append(result.Observations, y)
+		result.ObservationCovariances = append(result.ObservationCovariances, F)
+		a, P = nextA, nextP
+	}
+	return result, nil
+}
+
+// symmetrize returns (A + A') / 2 as a symmetric matrix, removing rounding asymmetries.
+func symmetrize(a mat.Matrix) *mat.SymDense {
+	n, _ := a.Dims()
+	s := mat.NewSymDense(n, nil)
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			s.SetSym(i, j, (a.At(i, j)+a.At(j, i))/2)
+		}
+	}
+	return s
+}
+
+// pseudoInverse returns the Moore-Penrose pseudo-inverse of a square matrix.
+func pseudoInverse(a mat.Matrix) *mat.Dense {
+	n, _ := a.Dims()
+	var svd mat.SVD
+	if ok := svd.Factorize(a, mat.SVDFull); !ok {
+		return mat.NewDense(n, n, nil)
+	}
+	values := svd.Values(nil)
+	var U, V mat.Dense
+	svd.UTo(&U)
+	svd.VTo(&V)
+
+	tol := float64(n) * values[0] * 1e-12
+	inv := mat.NewDense(n, n, nil)
+	for k, sv := range values {
+		if sv <= tol {
+			continue
+		}
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				inv.Set(i, j, inv.At(i, j)+V.At(i, k)*U.At(j, k)/sv)
+			}
+		}
+	}
+	return inv
+}
+
+// StateSpace expresses the fitted one-step-ahead ARX model in state-space form, with the state
+// x[t] = [y[t], y[t-1], .., y[t-na+1]] in companion form, the noise variance of the fit, the
+// intercept as state intercept and the lagged external inputs [u[t-nk], .., u[t-nk-nb+1]] as inputs,
+// see StateSpaceInputs, or no inputs when nb is 0. The initial state is the end of the training data, known exactly.
+func (m *LSARXModel) StateSpace() (*StateSpaceModel, error) {
+	s := m.Params.structure()
+	na, nb := s.na, s.nb
+	if s.nx > 0 {
+		return nil, fmt.Errorf("state-space form does not support deterministic regressors")
+	}
+	if len(m.Theta) != s.numParams() || len(m.History) < na {
+		return nil, fmt.Errorf("model has %d coefficients and %d history rows, expected %d and at least %d", len(m.Theta), len(m.History), s.numParams(), na)
+	}
+
+	T := mat.NewDense(na, na, nil)
+	for j := 0; j < na; j++ {
+		T.Set(0, j, -m.Theta[j])
+	}
+	for i := 1; i < na; i++ {
+		T.Set(i, i-1, 1)
+	}
+	var B *mat.Dense
+	if nb > 0 {
+		B = mat.NewDense(na, nb, nil)
+		B.SetRow(0, m.Theta[na:na+nb])
+	}
+	var c []float64
+	if s.intercept {
+		c = make([]float64, na)
+		c[0] = m.Theta[na+nb]
+	}
+
+	Q := mat.NewSymDense(na, nil)
+	Q.SetSym(0, 0, m.Metadata.Sigma2)
+	Z := mat.NewDense(1, na, nil)
+	Z.Set(0, 0, 1)
+
+	state := make([]float64, na)
+	for j := range state {
+		state[j] = m.History[len(m.History)-1-j][0]
+	}
+
+	return &StateSpaceModel{
+		T:                 T,
+		Q:                 Q,
+		Z:                 Z,
+		H:                 mat.NewSymDense(1, nil),
+		StateIntercept:    c,
+		B:                 B,
+		InitialState:      state,
+		InitialCovariance: mat.NewSymDense(na, nil),
+	}, nil
+}
+
+// StateSpaceInputs returns the inputs of the state-space form of the model for a series of
+// external input values: row t holds [u[t-nk], .., u[t-nk-nb+1]], with zeros before the series
+// start. Without input terms, the rows are empty.
+func (m *LSARXModel) StateSpaceInputs(inputs []float64) [][]float64 {
+	nb, nk := m.Params.ExternalInputLags, m.Params.InputDelay
+	rows := make([][]float64, len(inputs))
+	for t := range inputs {
+		rows[t] = make([]float64, nb)
+		for j := 0; j < nb && t-nk-j >= 0; j++ {
+			rows[t][j] = inputs[t-nk-j]
+		}
+	}
+	return rows
+}
+
+// PredictWithVariance forecasts the given number of steps past the end of the training data with
+// the Kalman filter of the state-space form of the model. It returns the forecast as a slice of
+// [time, value] pairs, equal to Predict for the recursive strategy, and the variance of every value.
+func (m *LSARXModel) PredictWithVariance(numToPredict int) ([][]float64, []float64, error) {
+	ss, err := m.StateSpace()
+	if err != nil {
+		return nil, nil, err
+	}
+	if numToPredict < 0 {
+		return nil, nil, fmt.Errorf("number of values to predict must not be negative, got: %d", numToPredict)
+	}
+
+	n := len(m.History)
+	timeValues := make([]float64, n)
+	for i, row := range m.History {
+		timeValues[i] = row[1]
+	}
+	pl := extendTimeValues(timeValues, numToPredict, m.Params.StepSize)
+
+	forecast, err := ss.Forecast(ss.InitialState, ss.InitialCovariance, m.StateSpaceInputs(pl)[n:], numToPredict)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := make([][]float64, numToPredict)
+	variances := make([]float64, numToPredict)
+	for h := range result {
+		result[h] = []float64{pl[n+h], forecast.Observations[h][0]}
+		variances[h] = forecast.ObservationCovariances[h].At(0, 0)
+	}
+	return result, variances, nil
+}
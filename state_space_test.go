@@ -0,0 +1,173 @@
+package ar
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// localLevelModel returns the random walk plus noise model x[t] = x[t-1] + w[t], y[t] = x[t] + v[t].
+func localLevelModel(q, h, p0 float64) *StateSpaceModel {
+	return &StateSpaceModel{
+		T:                 mat.NewDense(1, 1, []float64{1}),
+		Q:                 mat.NewSymDense(1, []float64{q}),
+		Z:                 mat.NewDense(1, 1, []float64{1}),
+		H:                 mat.NewSymDense(1, []float64{h}),
+		InitialState:      []float64{0},
+		InitialCovariance: mat.NewSymDense(1, []float64{p0}),
+	}
+}
+
+func TestStateSpaceFilter(t *testing.T) {
+	q, h, p0 := 0.5, 2.0, 10.0
+	observations := [][]float64{{1.2}, {0.7}, {math.NaN()}, {2.4}, {1.9}}
+
+	result, err := localLevelModel(q, h, p0).Filter(observations, nil)
+	if err != nil {
+		t.Fatalf("Filter() error = %v", err)
+	}
+
+	// Scalar Kalman recursion of the local level model.
+	a, p, loglik := 0.0, p0, 0.0
+	for i, row := range observations {
+		if i > 0 {
+			p += q
+		}
+		if !approxEqual(result.PredictedStates[i][0], a, 1e-12) || !approxEqual(result.PredictedCovariances[i].At(0, 0), p, 1e-12) {
+			t.Errorf("predicted[%d] = (%v, %v), want (%v, %v)", i, result.PredictedStates[i][0], result.PredictedCovariances[i].At(0, 0), a, p)
+		}
+		if y := row[0]; !math.IsNaN(y) {
+			f := p + h
+			loglik -= 0.5 * (math.Log(2*math.Pi) + math.Log(f) + (y-a)*(y-a)/f)
+			a += p / f * (y - a)
+			p -= p * p / f
+		}
+		if !approxEqual(result.FilteredStates[i][0], a, 1e-12) || !approxEqual(result.FilteredCovariances[i].At(0, 0), p, 1e-12) {
+			t.Errorf("filtered[%d] = (%v, %v), want (%v, %v)", i, result.FilteredStates[i][0], result.FilteredCovariances[i].At(0, 0), a, p)
+		}
+	}
+	if !approxEqual(result.LogLikelihood, loglik, 1e-12) {
+		t.Errorf("LogLikelihood = %v, want %v", result.LogLikelihood, loglik)
+	}
+	if !math.IsNaN(result.Innovations[2][0]) {
+		t.Errorf("Innovations[2] = %v, want NaN for a missing value", result.Innovations[2])
+	}
+}
+
+func TestStateSpaceLogLikelihoodWhiteNoise(t *testing.T) {
+	// With T = 0 and no observation noise, the observations are independent N(0, sigma2).
+	sigma2 := 1.5
+	model := &StateSpaceModel{
+		T:                 mat.NewDense(1, 1, []float64{0}),
+		Q:                 mat.NewSymDense(1, []float64{sigma2}),
+		Z:                 mat.NewDense(1, 1, []float64{1}),
+		H:                 mat.NewSymDense(1, []float64{0}),
+		InitialState:      []float64{0},
+		InitialCovariance: mat.NewSymDense(1, []float64{sigma2}),
+	}
+	observations := [][]float64{{0.3}, {-1.1}, {2.0}, {0.4}}
+
+	got, err := model.LogLikelihood(observations, nil)
+	if err != nil {
+		t.Fatalf("LogLikelihood() error = %v", err)
+	}
+	want := 0.0
+	for _, row := range observations {
+		want -= 0.5 * (math.Log(2*math.Pi*sigma2) + row[0]*row[0]/sigma2)
+	}
+	if !approxEqual(got, want, 1e-12) {
+		t.Errorf("LogLikelihood() = %v, want %v", got, want)
+	}
+}
+
+func TestStateSpaceSmooth(t *testing.T) {
+	model := localLevelModel(0.5, 2.0, 10.0)
+	observations := [][]float64{{1.2}, {0.7}, {math.NaN()}, {2.4}, {1.9}}
+
+	filtered, err := model.Filter(observations, nil)
+	if err != nil {
+		t.Fatalf("Filter() error = %v", err)
+	}
+	smoothed, err := model.Smooth(observations, nil)
+	if err != nil {
+		t.Fatalf("Smooth() error = %v", err)
+	}
+
+	n := len(observations)
+	if smoothed.States[n-1][0] != filtered.FilteredStates[n-1][0] {
+		t.Errorf("last smoothed state = %v, want the filtered state %v", smoothed.States[n-1][0], filtered.FilteredStates[n-1][0])
+	}
+	for i := 0; i < n-1; i++ {
+		if smoothed.Covariances[i].At(0, 0) > filtered.FilteredCovariances[i].At(0, 0) {
+			t.Errorf("smoothed variance[%d] = %v, larger than the filtered one %v", i, smoothed.Covariances[i].At(0, 0), filtered.FilteredCovariances[i].At(0, 0))
+		}
+	}
+	// The missing value is smoothed between its neighbours.
+	lo, hi := math.Min(smoothed.States[1][0], smoothed.States[3][0]), math.Max(smoothed.States[1][0], smoothed.States[3][0])
+	if s := smoothed.States[2][0]; s < lo || s > hi {
+		t.Errorf("smoothed state of the missing value = %v, want within [%v, %v]", s, lo, hi)
+	}
+}
+
+func TestStateSpaceValidation(t *testing.T) {
+	model := localLevelModel(0.5, 2.0, 10.0)
+	model.Z = mat.NewDense(1, 2, nil)
+	if _, err := model.Filter([][]float64{{1}}, nil); err == nil {
+		t.Error("Filter() with mismatched dimensions returned no error")
+	}
+
+	model = localLevelModel(0.5, 2.0, 10.0)
+	if _, err := model.Filter([][]float64{{1, 2}}, nil); err == nil {
+		t.Error("Filter() with too many observation values returned no error")
+	}
+}
+
+func TestLSARXPredictWithVariance(t *testing.T) {
+	numToPredict := 5
+	predictor, err := NewLSARXPredictor(sampleData, LSARXModelParameters{
+		AutoregressiveLags: 3,
+		ExternalInputLags:  3,
+		StepSize:           25,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	model, err := predictor.Fit(0)
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	want, err := model.Predict(numToPredict)
+	if err != nil {
+		t.Fatalf("Predict() error = %v", err)
+	}
+	got, variances, err := model.PredictWithVariance(numToPredict)
+	if err != nil {
+		t.Fatalf("PredictWithVariance() error = %v", err)
+	}
+
+	for i := range want {
+		if got[i][0] != want[i][0] || !approxEqual(got[i][1], want[i][1], 1e-6*math.Abs(want[i][1])+1e-9) {
+			t.Errorf("PredictWithVariance()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	// The variances follow sigma2 * sum psi_j^2 of the AR polynomial, psi_0 = 1.
+	a := model.Theta[:3]
+	psi := []float64{1}
+	sum := 0.0
+	for h := 0; h < numToPredict; h++ {
+		if h > 0 {
+			next := 0.0
+			for j := 1; j <= 3 && j <= h; j++ {
+				next -= a[j-1] * psi[h-j]
+			}
+			psi = append(psi, next)
+		}
+		sum += psi[h] * psi[h]
+		if wantVar := model.Metadata.Sigma2 * sum; !approxEqual(variances[h], wantVar, 1e-9*wantVar) {
+			t.Errorf("variance[%d] = %v, want %v", h, variances[h], wantVar)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package ar
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// summaryConfidenceLevel is the confidence level of the coefficient intervals of a FitSummary.
+const summaryConfidenceLevel = 0.95
+
+// CoefficientSummary holds the inference on one estimated coefficient.
+type CoefficientSummary struct {
+	Label      string  // Regressor of the coefficient, e.g. "y[t-2]" or "u[t-1]".
+	Estimate   float64 // Estimated coefficient.
+	StdError   float64 // Standard error of the estimate.
+	TStatistic float64 // Estimate divided by its standard error.
+	PValue     float64 // Two-sided p-value of the t-test of a zero coefficient.
+	Lower      float64 // Lower limit of the confidence interval.
+	Upper      float64 // Upper limit of the confidence interval.
+}
+
+// FitSummary describes a least-squares fit in the manner of a regression table.
+type FitSummary struct {
+	Model            string               // Description of the model.
+	Coefficients     []CoefficientSummary // Inference on every coefficient.
+	NumObservations  int                  // Number of observations in the regression.
+	DegreesOfFreedom int                  // Residual degrees of freedom.
+	ConfidenceLevel  float64              // Confidence level of the coefficient intervals.
+	RSquared         float64              // Coefficient of determination.
+	AdjustedRSquared float64              // R-squared adjusted for the number of coefficients.
+	Sigma2           float64              // Residual variance, SSE divided by the degrees of freedom.
+	LogLikelihood    float64              // Gaussian log-likelihood at the maximum likelihood noise variance.
+	AIC              float64              // Akaike information criterion, -2 logL + 2k.
+	BIC              float64              // Bayesian information criterion, -2 logL + k ln(N).
+	AICc             float64              // AIC corrected for small samples.
+	ChangePoints     []int                // Training data rows that start a new regime; the fit uses the rows from the last one.
+}
+
+// newFitSummary builds the summary of a least-squares fit from the estimates, their labels and the
+// training metadata of the fit.
+func newFitSummary(model string, labels []string, estimates []float64, md TrainingMetadata) *FitSummary {
+	n, k := md.NumResiduals, len(estimates)
+	N := float64(n)
+	dof := n - k
+	s := &FitSummary{
+		Model:            model,
+		NumObservations:  n,
+		DegreesOfFreedom: dof,
+		ConfidenceLevel:  summaryConfidenceLevel,
+		RSquared:         1 - md.SSE/md.TSS,
+		LogLikelihood:    -N / 2 * (math.Log(2*math.Pi*md.SSE/N) + 1),
+		Sigma2:           math.NaN(),
+		AdjustedRSquared: math.NaN(),
+		AICc:             math.Inf(1),
+		ChangePoints:     md.ChangePoints,
+	}
+	s.AIC = -2*s.LogLikelihood + 2*float64(k)
+	s.BIC = -2*s.LogLikelihood + float64(k)*math.Log(N)
+	if dof > 0 {
+		s.Sigma2 = md.Sigma2
+		s.AdjustedRSquared = 1 - (1-s.RSquared)*(N-1)/float64(dof)
+	}
+	if dof > 1 {
+		s.AICc = s.AIC + 2*float64(k)*float64(k+1)/float64(dof-1)
+	}
+
+	var tDist distuv.StudentsT
+	tCrit := math.NaN()
+	if dof > 0 {
+		tDist = distuv.StudentsT{Mu: 0, Sigma: 1, Nu: float64(dof)}
+		tCrit = tDist.Quantile(1 - (1-summaryConfidenceLevel)/2)
+	}
+	for j, est := range estimates {
+		c := CoefficientSummary{Label: labels[j], Estimate: est, StdError: math.NaN(), TStatistic: math.NaN(), PValue: math.NaN(), Lower: math.NaN(), Upper: math.NaN()}
+		if md.StdErrors != nil && dof > 0 {
+			c.StdError = md.StdErrors[j]
+			c.TStatistic = est / c.StdError
+			c.PValue = 2 * tDist.Survival(math.Abs(c.TStatistic))
+			c.Lower, c.Upper = est-tCrit*c.StdError, est+tCrit*c.StdError
+		}
+		s.Coefficients = append(s.Coefficients, c)
+	}
+	return s
+}
+
+// String formats the summary as a regression table.
+func (s *FitSummary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", s.Model)
+	fmt.Fprintf(&b, "Observations: %-14d Degrees of freedom: %d\n", s.NumObservations, s.DegreesOfFreedom)
+	fmt.Fprintf(&b, "R-squared:    %-14.4f Adj. R-squared:     %.4f\n", s.RSquared, s.AdjustedRSquared)
+	fmt.Fprintf(&b, "Sigma^2:      %-14.6g Log-likelihood:     %.4f\n", s.Sigma2, s.LogLikelihood)
+	fmt.Fprintf(&b, "AIC:          %-14.4f BIC:                %.4f\n", s.AIC, s.BIC)
+	fmt.Fprintf(&b, "AICc:         %.4f\n", s.AICc)
+	if len(s.ChangePoints) > 0 {
+		points := make([]string, len(s.ChangePoints))
+		for i, cp := range s.ChangePoints {
+			points[i] = strconv.Itoa(cp)
+		}
+		fmt.Fprintf(&b, "Change points: %s (fitted from row %d)\n", strings.Join(points, ", "), s.ChangePoints[len(s.ChangePoints)-1])
+	}
+	b.WriteString("\n")
+
+	alpha := (1 - s.ConfidenceLevel) / 2
+	lower, upper := fmt.Sprintf("[%.3g", alpha), fmt.Sprintf("%.3g]", 1-alpha)
+	fmt.Fprintf(&b, "%-10s %12s %12s %9s %8s %12s %12s\n", "", "Estimate", "Std. Error", "t", "P>|t|", lower, upper)
+	for _, c := range s.Coefficients {
+		fmt.Fprintf(&b, "%-10s %12.6g %12.6g %9.3f %8.4f %12.6g %12.6g\n", c.Label, c.Estimate, c.StdError, c.TStatistic, c.PValue, c.Lower, c.Upper)
+	}
+	return b.String()
+}
+
+// lsarxLabels returns the coefficient labels of an LSARX model, in Theta order.
+func lsarxLabels(s arxStructure) []string {
+	labels := make([]string, 0, s.numParams())
+	for j := 1; j <= s.na; j++ {
+		labels = append(labels, fmt.Sprintf("y[t-%d]", j))
+	}
+	for j := s.nk; j < s.nk+s.nb; j++ {
+		if j == 0 {
+			labels = append(labels, "u[t]")
+		} else {
+			labels = append(labels, fmt.Sprintf("u[t-%d]", j))
+		}
+	}
+	if s.intercept {
+		labels = append(labels, "const")
+	}
+	return append(labels, s.regressorNames()...)
+}
+
+// Summary returns the regression summary of the least-squares fit of the model.
+func (m *LSModel) Summary() *FitSummary {
+	return newFitSummary("LS model: basis "+strings.Join(m.Basis, ", "), m.Basis, m.Theta, m.Metadata)
+}
+
+// Summary returns the regression summary of the one-step-ahead least-squares fit of the model.
+// The autoregressive estimates are the coefficients of y[t-j] in the prediction, that is -a_j of Theta.
+func (m *LSARXModel) Summary() *FitSummary {
+	s := m.Params.structure()
+	estimates := append([]float64(nil), m.Theta...)
+	for j := 0; j < s.na; j++ {
+		estimates[j] = -estimates[j]
+	}
+	model := fmt.Sprintf("LSARX model: na = %d, nb = %d, nk = %d", s.na, s.nb, s.nk)
+	if s.intercept {
+		model += ", intercept"
+	}
+	return newFitSummary(model, lsarxLabels(s), estimates, m.Metadata)
+}
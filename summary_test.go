@@ -0,0 +1,116 @@
+package ar
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLSARXSummary(t *testing.T) {
+	// y[t] = 0.5 y[t-1] + 2 u[t] + e[t], with a random input and no dependence on u[t-1].
+	rnd := rand.New(rand.NewSource(5))
+	data := make([][]float64, 300)
+	y := 0.0
+	for i := range data {
+		u := rnd.NormFloat64()
+		y = 0.5*y + 2*u + 0.1*rnd.NormFloat64()
+		data[i] = []float64{y, u}
+	}
+	predictor, err := NewLSARXPredictor(data, LSARXModelParameters{AutoregressiveLags: 1, ExternalInputLags: 2, StepSize: 1})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	model, err := predictor.Fit(0)
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	s := model.Summary()
+	wantLabels := []string{"y[t-1]", "u[t]", "u[t-1]"}
+	wantEstimates := []float64{0.5, 2, 0}
+	for i, c := range s.Coefficients {
+		if c.Label != wantLabels[i] {
+			t.Errorf("Coefficients[%d].Label = %q, want %q", i, c.Label, wantLabels[i])
+		}
+		if c.Lower > wantEstimates[i] || c.Upper < wantEstimates[i] {
+			t.Errorf("Coefficients[%d] interval [%v, %v] misses %v", i, c.Lower, c.Upper, wantEstimates[i])
+		}
+		if !approxEqual(c.TStatistic, c.Estimate/c.StdError, 1e-12) {
+			t.Errorf("Coefficients[%d].TStatistic = %v, want %v", i, c.TStatistic, c.Estimate/c.StdError)
+		}
+	}
+	if s.Coefficients[0].Estimate != -model.Theta[0] {
+		t.Errorf("y[t-1] estimate = %v, want -Theta[0] = %v", s.Coefficients[0].Estimate, -model.Theta[0])
+	}
+	if s.Coefficients[0].PValue > 1e-6 || s.Coefficients[1].PValue > 1e-6 || s.Coefficients[2].PValue < 1e-3 {
+		t.Errorf("p-values = %v, %v, %v, want the first two significant and the last not", s.Coefficients[0].PValue, s.Coefficients[1].PValue, s.Coefficients[2].PValue)
+	}
+	if s.NumObservations != 299 || s.DegreesOfFreedom != 296 {
+		t.Errorf("NumObservations, DegreesOfFreedom = %d, %d, want 299, 296", s.NumObservations, s.DegreesOfFreedom)
+	}
+	if s.RSquared < 0.99 || s.AdjustedRSquared > s.RSquared || !approxEqual(s.Sigma2, 0.01, 0.003) {
+		t.Errorf("RSquared, AdjustedRSquared, Sigma2 = %v, %v, %v", s.RSquared, s.AdjustedRSquared, s.Sigma2)
+	}
+	if s.BIC <= s.AIC || s.AICc <= s.AIC {
+		t.Errorf("AIC, BIC, AICc = %v, %v, %v, want BIC and AICc above AIC", s.AIC, s.BIC, s.AICc)
+	}
+
+	// The AIC differs from the order selection score by a constant.
+	sse := s.Sigma2 * float64(s.DegreesOfFreedom)
+	score := AIC.score(sse, s.NumObservations, 3)
+	if want := score + float64(s.NumObservations)*(math.Log(2*math.Pi)+1); !approxEqual(s.AIC, want, 1e-9) {
+		t.Errorf("AIC = %v, want %v", s.AIC, want)
+	}
+
+	table := s.String()
+	for _, want := range []string{"LSARX model: na = 1, nb = 2", "Std. Error", "u[t-1]", "[0.025"} {
+		if !strings.Contains(table, want) {
+			t.Errorf("String() = %q, want it to contain %q", table, want)
+		}
+	}
+}
+
+func TestSummaryAfterLoad(t *testing.T) {
+	for name, model := range fittedTestModels(t) {
+		t.Run(name, func(t *testing.T) {
+			data, err := json.Marshal(model)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			loaded, err := LoadModelJSON(data)
+			if err != nil {
+				t.Fatalf("LoadModelJSON() error = %v", err)
+			}
+
+			type summarizer interface{ Summary() *FitSummary }
+			want := model.(summarizer).Summary()
+			if got := loaded.(summarizer).Summary(); !reflect.DeepEqual(got, want) {
+				t.Errorf("loaded Summary() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestLSSummary(t *testing.T) {
+	predictor, err := NewLSPredictor(sampleData, LSModelParameters{StepSize: 25})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	model, err := predictor.Fit()
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	s := model.Summary()
+	if len(s.Coefficients) != len(lsBasisNames) || s.NumObservations != len(sampleData) {
+		t.Fatalf("Summary() has %d coefficients and %d observations, want %d and %d", len(s.Coefficients), s.NumObservations, len(lsBasisNames), len(sampleData))
+	}
+	for i, c := range s.Coefficients {
+		if c.Label != lsBasisNames[i] || c.Estimate != model.Theta[i] || !(c.StdError > 0) {
+			t.Errorf("Coefficients[%d] = %+v", i, c)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package ar
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// ValidationResult compares the output of a model run over data with the measured output.
+type ValidationResult struct {
+	Output []float64 // Simulated or predicted output, one value per data row.
+	Start  int       // First row of the comparison; the rows before it are initial conditions.
+	Fit    float64   // NRMSE fit of Output[Start:] to the measured output, in percent.
+}
+
+// NRMSEFit returns the normalized root-mean-square-error fit of output to measured in percent,
+// 100 (1 - ||measured - output|| / ||measured - mean(measured)||), as reported by system-identification
+// tools: 100 is a perfect fit, 0 fits no better than the mean and the fit is negative when it is worse.
+// It returns NaN when the measured output is constant.
+func NRMSEFit(measured []float64, output []float64) float64 {
+	mean := 0.0
+	for _, v := range measured {
+		mean += v
+	}
+	mean /= float64(len(measured))
+
+	sse, tss := 0.0, 0.0
+	for i, v := range measured {
+		sse += (v - output[i]) * (v - output[i])
+		tss += (v - mean) * (v - mean)
+	}
+	if tss == 0 {
+		return math.NaN()
+	}
+	return 100 * (1 - math.Sqrt(sse/tss))
+}
+
+// newValidationResult scores output against measured from row start.
+func newValidationResult(measured []float64, output []float64, start int) *ValidationResult {
+	return &ValidationResult{Output: output, Start: start, Fit: NRMSEFit(measured[start:], output[start:])}
+}
+
+// splitValidationData checks that the data rows are [data_value, time_value] and that there are
+// more than m of them, and returns the two columns.
+func splitValidationData(data [][]float64, m int) ([]float64, []float64, error) {
+	if err := checkColumns(data, 2, "data"); err != nil {
+		return nil, nil, err
+	}
+	if len(data) <= m {
+		return nil, nil, fmt.Errorf("not enough data points for validation, need at least %d points", m+1)
+	}
+	y := make([]float64, len(data))
+	u := make([]float64, len(data))
+	for i, row := range data {
+		y[i], u[i] = row[0], row[1]
+	}
+	return y, u, nil
+}
+
+// Simulate runs the one-step-ahead model in free run over data rows of [data_value, time_value]:
+// the first max(na, nk+nb-1) measured values are the initial conditions, and every later value is
+// computed from the inputs and the previously simulated values alone. The fit is scored on the
+// simulated rows.
+func (m *LSARXModel) Simulate(data [][]float64) (*ValidationResult, error) {
+	s := m.Params.structure()
+	if len(m.Theta) != s.numParams() {
+		return nil, fmt.Errorf("model has %d coefficients, expected %d", len(m.Theta), s.numParams())
+	}
+	lag := s.maxLag()
+	y, u, err := splitValidationData(data, lag)
+	if err != nil {
+		return nil, err
+	}
+
+	th := mat.NewDense(len(m.Theta), 1, m.Theta)
+	yAp := make([]float64, len(y))
+	copy(yAp, y[:lag])
+	x := make([]float64, s.nx)
+	for t := lag; t < len(yAp); t++ {
+		yAp[t] = s.predictAt(yAp, u, th, t, 1, x)
+	}
+	return newValidationResult(y, yAp, lag), nil
+}
+
+// PredictK returns the k-step-ahead predictions over data rows of [data_value, time_value]: the
+// value at row t is predicted from the measured values up to row t-k and the inputs up to row t,
+// with the strategy of the model, so the direct strategies need k at most the horizon they were
+// fitted for. k = 1 gives the one-step-ahead fit and large k approach Simulate. The first
+// max(na, nk+nb-1) measured values are the initial conditions, and the fit is scored on the later rows.
+func (m *LSARXModel) PredictK(data [][]float64, k int) (*ValidationResult, error) {
+	s := m.Params.structure()
+	if k <= 0 {
+		return nil, fmt.Errorf("prediction horizon must be a positive integer, k: %d", k)
+	}
+	if len(m.Theta) != s.numParams() {
+		return nil, fmt.Errorf("model has %d coefficients, expected %d", len(m.Theta), s.numParams())
+	}
+	if m.Params.Strategy != RecursiveStrategy && k > len(m.HorizonThetas) {
+		return nil, fmt.Errorf("model was fitted for %d horizon steps, cannot predict %d steps ahead", len(m.HorizonThetas), k)
+	}
+	lag := s.maxLag()
+	y, u, err := splitValidationData(data, lag)
+	if err != nil {
+		return nil, err
+	}
+
+	dirRec := m.Params.Strategy == DirRecStrategy
+	x := make([]float64, s.nx)
+	step := func(yAp []float64, t int, h int) float64 {
+		if m.Params.Strategy == RecursiveStrategy {
+			return s.predictAt(yAp, u, mat.NewDense(len(m.Theta), 1, m.Theta), t, 1, x)
+		}
+		thh := m.HorizonThetas[h-1]
+		return predictDirectStep(yAp, u, mat.NewDense(len(thh), 1, thh), t, s, h, dirRec, x)
+	}
+
+	output := make([]float64, len(y))
+	copy(output, y[:lag])
+	yAp := append([]float64(nil), y...)
+	for t := lag; t < len(y); t++ {
+		// Predict forward from the origin t-k, then restore the measured values for the next row.
+		origin := t - k
+		first := max(origin+1, lag)
+		for i := first; i <= t; i++ {
+			yAp[i] = step(yAp, i, i-origin)
+		}
+		output[t] = yAp[t]
+		copy(yAp[first:t+1], y[first:t+1])
+	}
+	return newValidationResult(y, output, lag), nil
+}
@@ -0,0 +1,151 @@
+package ar
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestNRMSEFit(t *testing.T) {
+	measured := []float64{1, 2, 3, 4}
+	tests := []struct {
+		name     string
+		measured []float64
+		output   []float64
+		want     float64
+	}{
+		{"Perfect fit", measured, []float64{1, 2, 3, 4}, 100},
+		{"Mean", measured, []float64{2.5, 2.5, 2.5, 2.5}, 0},
+		{"Worse than the mean", measured, []float64{4, 3, 2, 1}, 100 * (1 - math.Sqrt(20.0/5))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NRMSEFit(tt.measured, tt.output); !approxEqual(got, tt.want, 1e-12) {
+				t.Errorf("NRMSEFit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := NRMSEFit([]float64{2, 2}, []float64{1, 2}); !math.IsNaN(got) {
+		t.Errorf("NRMSEFit() of a constant = %v, want NaN", got)
+	}
+}
+
+func TestLSARXSimulateAndPredictK(t *testing.T) {
+	data := delayedARXData(200)
+	params := LSARXModelParameters{AutoregressiveLags: 1, ExternalInputLags: 1, InputDelay: 2, Intercept: true, StepSize: 1}
+	predictor, err := NewLSARXPredictor(data, params)
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	model, err := predictor.Fit(0)
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	sim, err := model.Simulate(data)
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+	if sim.Start != 2 || sim.Output[0] != data[0][0] || sim.Output[1] != data[1][0] {
+		t.Errorf("Simulate() starts at %d with %v, want 2 with the measured initial conditions", sim.Start, sim.Output[:2])
+	}
+	if sim.Fit < 95 || sim.Fit > 100 {
+		t.Errorf("Simulate() fit = %v, want in [95, 100]", sim.Fit)
+	}
+
+	// The one-step predictions use the measured lags.
+	oneStep, err := model.PredictK(data, 1)
+	if err != nil {
+		t.Fatalf("PredictK() error = %v", err)
+	}
+	y := make([]float64, len(data))
+	u := make([]float64, len(data))
+	for i, row := range data {
+		y[i], u[i] = row[0], row[1]
+	}
+	th := mat.NewDense(len(model.Theta), 1, model.Theta)
+	for i := 2; i < len(data); i++ {
+		if want := params.structure().predictAt(y, u, th, i, 1, nil); !approxEqual(oneStep.Output[i], want, 1e-12) {
+			t.Fatalf("PredictK(1) = %v at %d, want %v", oneStep.Output[i], i, want)
+		}
+	}
+	if oneStep.Fit < sim.Fit {
+		t.Errorf("PredictK(1) fit = %v, want at least the simulation fit %v", oneStep.Fit, sim.Fit)
+	}
+
+	// Predicting from the initial conditions alone is the simulation.
+	longRange, err := model.PredictK(data, len(data))
+	if err != nil {
+		t.Fatalf("PredictK() error = %v", err)
+	}
+	for i := range sim.Output {
+		if !approxEqual(longRange.Output[i], sim.Output[i], 1e-9) {
+			t.Fatalf("PredictK(%d) = %v at %d, want simulation %v", len(data), longRange.Output[i], i, sim.Output[i])
+		}
+	}
+
+	if _, err := model.PredictK(data, 0); err == nil {
+		t.Error("PredictK(0) returned no error")
+	}
+	if _, err := model.Simulate(data[:2]); err == nil {
+		t.Error("Simulate() with only the initial conditions returned no error")
+	}
+	if _, err := model.Simulate([][]float64{{1}, {2}, {3}}); err == nil {
+		t.Error("Simulate() with rows of one value returned no error")
+	}
+}
+
+func TestLSARXPredictKDirect(t *testing.T) {
+	data := delayedARXData(200)
+	params := LSARXModelParameters{AutoregressiveLags: 2, ExternalInputLags: 1, InputDelay: 2, StepSize: 1}
+	recursive, err := NewLSARXPredictor(data, params)
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	recursiveModel, err := recursive.Fit(0)
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	for _, strategy := range []PredictionStrategy{DirectStrategy, DirRecStrategy} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			params := params
+			params.Strategy = strategy
+			predictor, err := NewLSARXPredictor(data, params)
+			if err != nil {
+				t.Fatalf("Failed to create predictor: %v", err)
+			}
+			model, err := predictor.Fit(3)
+			if err != nil {
+				t.Fatalf("Fit() error = %v", err)
+			}
+
+			// The model for the first horizon is the one-step-ahead model.
+			got, err := model.PredictK(data, 1)
+			if err != nil {
+				t.Fatalf("PredictK() error = %v", err)
+			}
+			want, err := recursiveModel.PredictK(data, 1)
+			if err != nil {
+				t.Fatalf("PredictK() error = %v", err)
+			}
+			for i := range want.Output {
+				if !approxEqual(got.Output[i], want.Output[i], 1e-9) {
+					t.Fatalf("PredictK(1) = %v at %d, want %v", got.Output[i], i, want.Output[i])
+				}
+			}
+
+			three, err := model.PredictK(data, 3)
+			if err != nil {
+				t.Fatalf("PredictK() error = %v", err)
+			}
+			if three.Fit > got.Fit {
+				t.Errorf("PredictK(3) fit = %v, want at most the one-step fit %v", three.Fit, got.Fit)
+			}
+			if _, err := model.PredictK(data, 4); err == nil {
+				t.Error("PredictK() past the fitted horizon returned no error")
+			}
+		})
+	}
+}
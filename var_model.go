@@ -0,0 +1,347 @@
+package ar
+
+import (
+	"fmt"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// VARModelParameters holds the configuration for the vector autoregressive model.
+type VARModelParameters struct {
+	Lags          int  // p: Number of past values of every series in each equation.
+	ExogenousLags int  // s: Number of past exogenous input values, besides the current one; used only with exogenous inputs.
+	Constant      bool // Constant: whether every equation has an intercept.
+}
+
+// VARPredictor encapsulates the VAR(p) / VARX(p, s) model: every series is explained by the past
+// values of all series and, optionally, by the current and past values of exogenous inputs.
+type VARPredictor struct {
+	Data      [][]float64        // Historical data: each row holds the value of every series.
+	Exogenous [][]float64        // Historical exogenous inputs: each row holds the value of every input, nil for a VAR model.
+	Params    VARModelParameters // Model parameters.
+}
+
+// NewVARPredictor creates a new VAR model predictor with the given data, exogenous inputs and parameters.
+// It performs basic validation of the parameters and of the shape of the data.
+func NewVARPredictor(data [][]float64, exogenous [][]float64, params VARModelParameters) (*VARPredictor, error) {
+	if params.Lags <= 0 || params.ExogenousLags < 0 {
+		return nil, fmt.Errorf("lags must be positive integers, lags: %d, exogenous lags: %d", params.Lags, params.ExogenousLags)
+	}
+	if len(data) == 0 || len(data[0]) == 0 {
+		return nil, fmt.Errorf("data must hold at least one series")
+	}
+	if err := checkColumns(data, len(data[0]), "data"); err != nil {
+		return nil, err
+	}
+	if exogenous != nil {
+		if len(exogenous) != len(data) {
+			return nil, fmt.Errorf("exogenous inputs have %d rows, expected %d", len(exogenous), len(data))
+		}
+		if len(exogenous[0]) == 0 {
+			return nil, fmt.Errorf("exogenous inputs must hold at least one input")
+		}
+		if err := checkColumns(exogenous, len(exogenous[0]), "exogenous inputs"); err != nil {
+			return nil, err
+		}
+	}
+
+	return &VARPredictor{Data: data, Exogenous: exogenous, Params: params}, nil
+}
+
+// checkColumns checks that every row has the given number of columns.
+func checkColumns(rows [][]float64, cols int, name string) error {
+	for i, row := range rows {
+		if len(row) != cols {
+			return fmt.Errorf("%s row %d has %d values, expected %d", name, i, len(row), cols)
+		}
+	}
+	return nil
+}
+
+// VARModel is a fitted VARPredictor: y[t] = c + A1 y[t-1] + ... + Ap y[t-p] + B0 x[t] + ... + Bs x[t-s] + e[t].
+type VARModel struct {
+	Params           VARModelParameters // Model parameters.
+	Constant         []float64          // Intercept c of every equation, nil without a constant.
+	AR               []*mat.Dense       // AR[i] is the k x k coefficient matrix of lag i+1.
+	Exogenous        []*mat.Dense       // Exogenous[j] is the k x r coefficient matrix of the inputs at lag j, nil for a VAR model.
+	Sigma            *mat.SymDense      // Covariance of the residuals e[t].
+	History          [][]float64        // Last p rows of the data the forecast starts from.
+	ExogenousHistory [][]float64        // Last s rows of the exogenous inputs, nil for a VAR model.
+	NumObservations  int                // Number of data points every equation was fitted on.
+}
+
+// varRegressors builds the regressor matrix of the VAR model, one row for every t from m to the
+// end of the data: [1, y[t-1] .. y[t-p], x[t] .. x[t-s]], where the constant and inputs are optional.
+// When skip is a valid series index, the lags of that series are left out.
+func varRegressors(data [][]float64, exogenous [][]float64, params VARModelParameters, m int, skip int) *mat.Dense {
+	k := len(data[0])
+	r := 0
+	if exogenous != nil {
+		r = len(exogenous[0])
+	}
+	lagged := k
+	if skip >= 0 && skip < k {
+		lagged--
+	}
+	cols := lagged*params.Lags + r*(params.ExogenousLags+1)
+	if params.Constant {
+		cols++
+	}
+
+	rows := len(data) - m
+	X := mat.NewDense(rows, cols, nil)
+	for i := 0; i < rows; i++ {
+		t := i + m
+		c := 0
+		if params.Constant {
+			X.Set(i, c, 1)
+			c++
+		}
+		for lag := 1; lag <= params.Lags; lag++ {
+			for j := 0; j < k; j++ {
+				if j == skip {
+					continue
+				}
+				X.Set(i, c, data[t-lag][j])
+				c++
+			}
+		}
+		for lag := 0; r > 0 && lag <= params.ExogenousLags; lag++ {
+			for j := 0; j < r; j++ {
+				X.Set(i, c, exogenous[t-lag][j])
+				c++
+			}
+		}
+	}
+	return X
+}
+
+// varLead returns the number of leading rows used only as lags.
+func (p *VARPredictor) varLead() int {
+	if p.Exogenous == nil {
+		return p.Params.Lags
+	}
+	return max(p.Params.Lags, p.Params.ExogenousLags)
+}
+
+// solveLeastSquares solves X * B = Y in the least-squares sense and returns B and the residuals.
+func solveLeastSquares(X *mat.Dense, Y *mat.Dense) (*mat.Dense, *mat.Dense, error) {
+	var B mat.Dense
+	if err := B.Solve(X, Y); err != nil {
+		return nil, nil, fmt.Errorf("failed to solve least squares: %w", err)
+	}
+	var E mat.Dense
+	E.Mul(X, &B)
+	E.Sub(Y, &E)
+	return &B, &E, nil
+}
+
+// Fit estimates every equation of the model by least squares and returns the fitted model.
+func (p *VARPredictor) Fit() (*VARModel, error) {
+	k := len(p.Data[0])
+	m := p.varLead()
+	X := varRegressors(p.Data, p.Exogenous, p.Params, m, -1)
+	rows, cols := X.Dims()
+	if rows <= cols {
+		return nil, fmt.Errorf("not enough data points for prediction, need at least %d points", m+cols+1)
+	}
+
+	Y := mat.NewDense(rows, k, nil)
+	for i := 0; i < rows; i++ {
+		Y.SetRow(i, p.Data[m+i])
+	}
+	B, E, err := solveLeastSquares(X, Y)
+	if err != nil {
+		return nil, err
+	}
+
+	model := &VARModel{Params: p.Params, NumObservations: rows}
+	c := 0
+	if p.Params.Constant {
+		model.Constant = mat.Row(nil, 0, B)
+		c++
+	}
+	for lag := 0; lag < p.Params.Lags; lag++ {
+		A := mat.NewDense(k, k, nil)
+		A.Copy(B.Slice(c, c+k, 0, k).T())
+		model.AR = append(model.AR, A)
+		c += k
+	}
+	if p.Exogenous != nil {
+		r := len(p.Exogenous[0])
+		for lag := 0; lag <= p.Params.ExogenousLags; lag++ {
+			Bx := mat.NewDense(k, r, nil)
+			Bx.Copy(B.Slice(c, c+r, 0, k).T())
+			model.Exogenous = append(model.Exogenous, Bx)
+			c += r
+		}
+		model.ExogenousHistory = copyRows(p.Exogenous[len(p.Exogenous)-p.Params.ExogenousLags:])
+	}
+
+	var S mat.SymDense
+	S.SymOuterK(1/float64(rows-cols), E.T())
+	model.Sigma = &S
+	model.History = copyRows(p.Data[len(p.Data)-p.Params.Lags:])
+
+	return model, nil
+}
+
+// Predict fits the model and forecasts every series jointly, see VARModel.Predict.
+func (p *VARPredictor) Predict(numToPredict int, futureExogenous [][]float64) ([][]float64, error) {
+	model, err := p.Fit()
+	if err != nil {
+		return nil, err
+	}
+	return model.Predict(numToPredict, futureExogenous)
+}
+
+// copyRows returns a deep copy of rows.
+func copyRows(rows [][]float64) [][]float64 {
+	c := make([][]float64, len(rows))
+	for i, row := range rows {
+		c[i] = append([]float64(nil), row...)
+	}
+	return c
+}
+
+// Predict forecasts every series jointly for the given number of steps past the end of the data.
+// VARX models need the future exogenous inputs, one row per forecast step; VAR models take nil.
+// It returns one row per step holding the forecast of every series.
+func (m *VARModel) Predict(numToPredict int, futureExogenous [][]float64) ([][]float64, error) {
+	if numToPredict < 0 {
+		return nil, fmt.Errorf("number of values to predict must not be negative, got: %d", numToPredict)
+	}
+	if m.Exogenous != nil {
+		_, r := m.Exogenous[0].Dims()
+		if len(futureExogenous) < numToPredict {
+			return nil, fmt.Errorf("future exogenous inputs have %d rows, need %d", len(futureExogenous), numToPredict)
+		}
+		if err := checkColumns(futureExogenous[:numToPredict], r, "future exogenous inputs"); err != nil {
+			return nil, err
+		}
+	}
+
+	k := len(m.History[0])
+	y := copyRows(m.History)
+	x := append(copyRows(m.ExogenousHistory), futureExogenous...)
+	p := len(y)
+	s := len(m.ExogenousHistory)
+
+	for h := 0; h < numToPredict; h++ {
+		next := make([]float64, k)
+		if m.Constant != nil {
+			copy(next, m.Constant)
+		}
+		for lag, A := range m.AR {
+			prev := y[p+h-lag-1]
+			for i := 0; i < k; i++ {
+				for j := 0; j < k; j++ {
+					next[i] += A.At(i, j) * prev[j]
+				}
+			}
+		}
+		for lag, B := range m.Exogenous {
+			in := x[s+h-lag]
+			rows, cols := B.Dims()
+			for i := 0; i < rows; i++ {
+				for j := 0; j < cols; j++ {
+					next[i] += B.At(i, j) * in[j]
+				}
+			}
+		}
+		y = append(y, next)
+	}
+
+	return y[p:], nil
+}
+
+// ImpulseResponse returns the response of every series to a unit shock, for steps 0..horizon.
+// Element (i, j) of the returned matrix h is the response of series i, h steps after a shock to
+// series j. With orthogonalized set, the shocks are one standard deviation of the Cholesky-factored
+// residual covariance, so the order of the series matters.
+func (m *VARModel) ImpulseResponse(horizon int, orthogonalized bool) ([]*mat.Dense, error) {
+	if horizon < 0 {
+		return nil, fmt.Errorf("horizon must not be negative, horizon: %d", horizon)
+	}
+	k, _ := m.AR[0].Dims()
+
+	// Psi[0] = I, Psi[h] = sum_{i=1}^{min(h, p)} A_i Psi[h-i]
+	psi := make([]*mat.Dense, horizon+1)
+	psi[0] = mat.NewDense(k, k, nil)
+	for i := 0; i < k; i++ {
+		psi[0].Set(i, i, 1)
+	}
+	for h := 1; h <= horizon; h++ {
+		psi[h] = mat.NewDense(k, k, nil)
+		for i := 1; i <= min(h, len(m.AR)); i++ {
+			var term mat.Dense
+			term.Mul(m.AR[i-1], psi[h-i])
+			psi[h].Add(psi[h], &term)
+		}
+	}
+
+	if orthogonalized {
+		var chol mat.Cholesky
+		if ok := chol.Factorize(m.Sigma); !ok {
+			return nil, fmt.Errorf("residual covariance is not positive definite")
+		}
+		var L mat.TriDense
+		chol.LTo(&L)
+		for h := range psi {
+			psi[h].Mul(psi[h], &L)
+		}
+	}
+	return psi, nil
+}
+
+// GrangerTestResult holds the outcome of a Granger-causality F-test.
+type GrangerTestResult struct {
+	FStatistic float64 // F statistic of the restriction that the lags of the cause series are zero.
+	DF1        int     // Numerator degrees of freedom: the number of restricted coefficients.
+	DF2        int     // Denominator degrees of freedom of the unrestricted equation.
+	PValue     float64 // Probability of an F statistic at least as large under the null of no causality.
+}
+
+// GrangerCausality tests whether the past values of series cause help predict series effect, by
+// comparing the equation of effect with and without the lags of cause. A small p-value rejects
+// the null hypothesis that cause does not Granger-cause effect.
+func (p *VARPredictor) GrangerCausality(cause int, effect int) (*GrangerTestResult, error) {
+	k := len(p.Data[0])
+	if cause < 0 || cause >= k || effect < 0 || effect >= k || cause == effect {
+		return nil, fmt.Errorf("cause and effect must be different series in [0, %d), cause: %d, effect: %d", k, cause, effect)
+	}
+
+	m := p.varLead()
+	full := varRegressors(p.Data, p.Exogenous, p.Params, m, -1)
+	restricted := varRegressors(p.Data, p.Exogenous, p.Params, m, cause)
+	rows, cols := full.Dims()
+	if rows <= cols {
+		return nil, fmt.Errorf("not enough data points for prediction, need at least %d points", m+cols+1)
+	}
+
+	y := mat.NewDense(rows, 1, nil)
+	for i := 0; i < rows; i++ {
+		y.Set(i, 0, p.Data[m+i][effect])
+	}
+	_, eFull, err := solveLeastSquares(full, y)
+	if err != nil {
+		return nil, err
+	}
+	_, eRestricted, err := solveLeastSquares(restricted, y)
+	if err != nil {
+		return nil, err
+	}
+
+	rssFull := mat.Dot(eFull.ColView(0), eFull.ColView(0))
+	rssRestricted := mat.Dot(eRestricted.ColView(0), eRestricted.ColView(0))
+	df1, df2 := p.Params.Lags, rows-cols
+	f := ((rssRestricted - rssFull) / float64(df1)) / (rssFull / float64(df2))
+
+	return &GrangerTestResult{
+		FStatistic: f,
+		DF1:        df1,
+		DF2:        df2,
+		PValue:     distuv.F{D1: float64(df1), D2: float64(df2)}.Survival(f),
+	}, nil
+}
@@ -0,0 +1,190 @@
+package ar
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// simulateVARX simulates y[t] = c + A y[t-1] + B x[t] + e[t] for two series, where series 0
+// drives series 1 but not the other way around.
+func simulateVARX(n int, withInput bool) ([][]float64, [][]float64) {
+	rnd := rand.New(rand.NewSource(7))
+	c := []float64{1, -0.5}
+	A := [][]float64{{0.5, 0}, {0.4, 0.3}}
+	B := []float64{2, -1}
+
+	data := make([][]float64, n)
+	var exogenous [][]float64
+	if withInput {
+		exogenous = make([][]float64, n)
+	}
+	prev := []float64{0, 0}
+	for t := 0; t < n; t++ {
+		x := math.Sin(float64(t) / 4)
+		row := make([]float64, 2)
+		for i := range row {
+			row[i] = c[i] + A[i][0]*prev[0] + A[i][1]*prev[1] + 0.1*rnd.NormFloat64()
+			if withInput {
+				row[i] += B[i] * x
+			}
+		}
+		data[t] = row
+		if withInput {
+			exogenous[t] = []float64{x}
+		}
+		prev = row
+	}
+	return data, exogenous
+}
+
+func TestNewVARPredictor(t *testing.T) {
+	testCases := []struct {
+		name        string
+		data        [][]float64
+		exogenous   [][]float64
+		params      VARModelParameters
+		expectedErr bool
+	}{
+		{name: "Valid VAR", data: [][]float64{{1, 2}, {3, 4}}, params: VARModelParameters{Lags: 1}},
+		{name: "Valid VARX", data: [][]float64{{1, 2}, {3, 4}}, exogenous: [][]float64{{1}, {2}}, params: VARModelParameters{Lags: 1, ExogenousLags: 1}},
+		{name: "Invalid lags", data: [][]float64{{1, 2}}, params: VARModelParameters{Lags: 0}, expectedErr: true},
+		{name: "Invalid exogenous lags", data: [][]float64{{1, 2}}, params: VARModelParameters{Lags: 1, ExogenousLags: -1}, expectedErr: true},
+		{name: "Empty data", data: [][]float64{}, params: VARModelParameters{Lags: 1}, expectedErr: true},
+		{name: "Ragged data", data: [][]float64{{1, 2}, {3}}, params: VARModelParameters{Lags: 1}, expectedErr: true},
+		{name: "Exogenous row count", data: [][]float64{{1, 2}, {3, 4}}, exogenous: [][]float64{{1}}, params: VARModelParameters{Lags: 1}, expectedErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewVARPredictor(tc.data, tc.exogenous, tc.params)
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("NewVARPredictor() error = %v, expectedErr %v", err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestVARFit(t *testing.T) {
+	for _, withInput := range []bool{false, true} {
+		name := "VAR"
+		if withInput {
+			name = "VARX"
+		}
+		t.Run(name, func(t *testing.T) {
+			data, exogenous := simulateVARX(5000, withInput)
+			predictor, err := NewVARPredictor(data, exogenous, VARModelParameters{Lags: 1, Constant: true})
+			if err != nil {
+				t.Fatalf("Failed to create predictor: %v", err)
+			}
+
+			model, err := predictor.Fit()
+			if err != nil {
+				t.Fatalf("Fit() error = %v", err)
+			}
+			expectedA := mat.NewDense(2, 2, []float64{0.5, 0, 0.4, 0.3})
+			if !mat.EqualApprox(model.AR[0], expectedA, 0.05) {
+				t.Errorf("Fit() AR = %v, want %v", mat.Formatted(model.AR[0]), mat.Formatted(expectedA))
+			}
+			if math.Abs(model.Constant[0]-1) > 0.1 || math.Abs(model.Constant[1]+0.5) > 0.1 {
+				t.Errorf("Fit() constant = %v, want [1 -0.5]", model.Constant)
+			}
+			if withInput && (math.Abs(model.Exogenous[0].At(0, 0)-2) > 0.05 || math.Abs(model.Exogenous[0].At(1, 0)+1) > 0.05) {
+				t.Errorf("Fit() exogenous = %v, want [2 -1]", mat.Formatted(model.Exogenous[0]))
+			}
+			if math.Abs(model.Sigma.At(0, 0)-0.01) > 0.003 {
+				t.Errorf("Fit() residual variance = %f, want 0.01", model.Sigma.At(0, 0))
+			}
+
+			var future [][]float64
+			if withInput {
+				future = [][]float64{{0.5}, {0.5}, {0.5}}
+			}
+			forecast, err := model.Predict(3, future)
+			if err != nil {
+				t.Fatalf("Predict() error = %v", err)
+			}
+			last := data[len(data)-1]
+			for i := 0; i < 2; i++ {
+				expected := model.Constant[i] + model.AR[0].At(i, 0)*last[0] + model.AR[0].At(i, 1)*last[1]
+				if withInput {
+					expected += model.Exogenous[0].At(i, 0) * 0.5
+				}
+				if math.Abs(forecast[0][i]-expected) > 1e-9 {
+					t.Errorf("Predict()[0][%d] = %f, want %f", i, forecast[0][i], expected)
+				}
+			}
+			if len(forecast) != 3 || len(forecast[2]) != 2 {
+				t.Errorf("Predict() returned %d rows, want 3 rows of 2 values", len(forecast))
+			}
+			if withInput {
+				if _, err := model.Predict(3, future[:1]); err == nil {
+					t.Errorf("Predict() expected an error without enough future inputs")
+				}
+			}
+		})
+	}
+}
+
+func TestVARGrangerCausality(t *testing.T) {
+	data, _ := simulateVARX(500, false)
+	predictor, err := NewVARPredictor(data, nil, VARModelParameters{Lags: 2, Constant: true})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+
+	causes, err := predictor.GrangerCausality(0, 1)
+	if err != nil {
+		t.Fatalf("GrangerCausality() error = %v", err)
+	}
+	if causes.PValue > 0.001 || causes.DF1 != 2 {
+		t.Errorf("GrangerCausality(0, 1) = %+v, want a small p-value with 2 restrictions", causes)
+	}
+
+	notCauses, err := predictor.GrangerCausality(1, 0)
+	if err != nil {
+		t.Fatalf("GrangerCausality() error = %v", err)
+	}
+	if notCauses.PValue < 0.01 {
+		t.Errorf("GrangerCausality(1, 0) = %+v, want a large p-value", notCauses)
+	}
+
+	if _, err := predictor.GrangerCausality(1, 1); err == nil {
+		t.Errorf("GrangerCausality() expected an error for the same series")
+	}
+}
+
+func TestVARImpulseResponse(t *testing.T) {
+	data, _ := simulateVARX(500, false)
+	predictor, err := NewVARPredictor(data, nil, VARModelParameters{Lags: 1, Constant: true})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	model, err := predictor.Fit()
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+
+	irf, err := model.ImpulseResponse(3, false)
+	if err != nil {
+		t.Fatalf("ImpulseResponse() error = %v", err)
+	}
+	if len(irf) != 4 {
+		t.Fatalf("ImpulseResponse() returned %d steps, want 4", len(irf))
+	}
+	var A2 mat.Dense
+	A2.Mul(model.AR[0], model.AR[0])
+	if !mat.EqualApprox(irf[1], model.AR[0], 1e-12) || !mat.EqualApprox(irf[2], &A2, 1e-12) {
+		t.Errorf("ImpulseResponse() = %v, want the powers of A1", irf)
+	}
+
+	orth, err := model.ImpulseResponse(1, true)
+	if err != nil {
+		t.Fatalf("ImpulseResponse() error = %v", err)
+	}
+	if orth[0].At(0, 1) != 0 || math.Abs(orth[0].At(0, 0)-math.Sqrt(model.Sigma.At(0, 0))) > 1e-12 {
+		t.Errorf("ImpulseResponse() orthogonalized impact = %v, want the Cholesky factor", mat.Formatted(orth[0]))
+	}
+}
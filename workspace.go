@@ -0,0 +1,193 @@
+package ar
+
+import (
+	"fmt"
+	"reflect"
+
+	"gonum.org/v1/gonum/blas"
+	"gonum.org/v1/gonum/blas/blas64"
+	"gonum.org/v1/gonum/lapack/lapack64"
+	"gonum.org/v1/gonum/mat"
+)
+
+// LSARXWorkspace holds the buffers of the fits and forecasts of LSARXPredictor.PredictWith, so that
+// repeated forecasts of data of the same shape reuse them instead of allocating. The zero value is
+// ready to use, and the buffers grow to the largest shape seen. A workspace must not be used by
+// several goroutines at once.
+type LSARXWorkspace struct {
+	dataValues []float64   // Historical data values, followed by the direct forecasts.
+	pl         []float64   // Historical time values extended by the forecast steps.
+	yAp        []float64   // Predicted data values.
+	x          []float64   // Columns of the regressors at one time value.
+	phi        []float64   // Regressor matrix, row-major.
+	normal     []float64   // Normal matrix phi' phi, factorized in place.
+	th         mat.Dense   // Model coefficients.
+	values     []float64   // Backing array of the result rows.
+	result     [][]float64 // Result rows.
+	regressors []Regressor // Regressors of the widths.
+	widths     []int       // Number of columns of every regressor.
+	nx         int         // Total number of columns of the regressors.
+}
+
+// PredictWith performs the prediction of Predict with the buffers of the workspace: once the
+// workspace has grown to the shape of the data, it does not allocate. The returned rows share
+// memory with the workspace and are only valid until its next use.
+// The workspace keeps the number of columns of the regressors of its last use while the regressors
+// compare equal, and regressors that are pointers compare by address, so a regressor must not be
+// modified in place between uses of the workspace: pass a new regressor or a new workspace instead.
+// The coefficients are solved by a Cholesky factorization of the normal equations instead of
+// their inverse, so the predictions match those of Predict up to rounding.
+func (p *LSARXPredictor) PredictWith(ws *LSARXWorkspace, numToPredict int) ([][]float64, error) {
+	if p.Params.LatestRegime != nil {
+		regime, _, err := p.latestRegime()
+		if err != nil {
+			return nil, err
+		}
+		return regime.PredictWith(ws, numToPredict)
+	}
+	if numToPredict < 0 {
+		return nil, fmt.Errorf("number of values to predict must not be negative, number to predict: %d", numToPredict)
+	}
+	s := ws.structure(p.Params)
+	m := s.maxLag()
+	n := len(p.Data)
+	if n <= m {
+		return nil, fmt.Errorf("not enough data points for prediction, need at least %d points", m+1)
+	}
+
+	// Separate the data, extend the time values and fit the one-step-ahead model, as Predict does.
+	ws.dataValues = growFloats(ws.dataValues, n+numToPredict)
+	ws.pl = growFloats(ws.pl, n+numToPredict)
+	dataValues, pl := ws.dataValues[:n], ws.pl
+	for i, row := range p.Data {
+		dataValues[i] = row[0]
+		pl[i] = row[1]
+	}
+	projectTimeValues(pl, n, p.Params.StepSize)
+	if err := ws.fitTheta(dataValues, pl[:n], s, 1, m); err != nil {
+		return nil, err
+	}
+
+	// The historical predictions run free from the first data points, and the forecast starts
+	// from the observed data, as Forecast does. The forecast-only output skips the former.
+	forecastOnly := p.Params.OutputMode == ForecastOnlyOutput
+	start := m + 1
+	if forecastOnly {
+		start = n
+	}
+	ws.yAp = growFloats(ws.yAp, n+numToPredict)
+	ws.x = growFloats(ws.x, s.nx)
+	yAp := ws.yAp
+	copy(yAp, dataValues)
+	for i := start; i < n; i++ {
+		yAp[i] = s.predictAt(yAp, pl, &ws.th, i, 1, ws.x)
+	}
+	// The forecasts use the data values as lags, followed by the previous forecasts.
+	lagged := ws.dataValues
+	switch p.Params.Strategy {
+	case DirectStrategy, DirRecStrategy:
+		dirRec := p.Params.Strategy == DirRecStrategy
+		for h := 1; h <= numToPredict; h++ {
+			lagStart, lags := directLags(s.na, h, dirRec)
+			sh := s.withLags(lags)
+			mh := max(lagStart+lags-1, s.maxInputLag())
+			if n-mh < sh.numParams() {
+				return nil, fmt.Errorf("not enough data points for horizon %d, need at least %d points", h, mh+sh.numParams())
+			}
+			if err := ws.fitTheta(dataValues, pl[:n], sh, lagStart, mh); err != nil {
+				return nil, fmt.Errorf("failed to calculate theta for horizon %d: %v", h, err)
+			}
+			lagged[n+h-1] = predictDirectStep(lagged, pl, &ws.th, n+h-1, s, h, dirRec, ws.x)
+			yAp[n+h-1] = lagged[n+h-1]
+		}
+	default:
+		for i := n; i < len(pl); i++ {
+			lagged[i] = s.predictAt(lagged, pl, &ws.th, i, 1, ws.x)
+			yAp[i] = lagged[i]
+		}
+	}
+
+	first := 0
+	if forecastOnly {
+		first = n
+	}
+	count := len(pl) - first
+	ws.values = growFloats(ws.values, 2*count)
+	if cap(ws.result) < count {
+		ws.result = make([][]float64, count)
+	}
+	ws.result = ws.result[:count]
+	for i := range ws.result {
+		row := ws.values[2*i : 2*i+2 : 2*i+2]
+		row[0], row[1] = pl[first+i], yAp[first+i]
+		ws.result[i] = row
+	}
+	return ws.result, nil
+}
+
+// structure returns the structure of the parameters, reusing the widths of the regressors of the
+// previous call when the regressors are the same.
+func (ws *LSARXWorkspace) structure(params LSARXModelParameters) arxStructure {
+	if !sameRegressors(ws.regressors, params.Regressors) {
+		ws.regressors = append(ws.regressors[:0], params.Regressors...)
+		ws.widths, ws.nx = regressorWidths(params.Regressors)
+	}
+	return params.structureWith(ws.widths, ws.nx)
+}
+
+// sameRegressors reports whether the regressors are equal values, pointers being equal when they
+// have the same address. Regressors of types that are not comparable are never equal.
+func sameRegressors(a, b []Regressor) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if t := reflect.TypeOf(a[i]); t == nil || t != reflect.TypeOf(b[i]) || !t.Comparable() || a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// fitTheta estimates the coefficients of the structure s, with the autoregressive lags starting at
+// lagStart, on the data values from index m into ws.th. It solves the normal equations by a Cholesky
+// factorization, and falls back to calculateTheta when they are not positive definite.
+func (ws *LSARXWorkspace) fitTheta(dataValues []float64, timeValues []float64, s arxStructure, lagStart int, m int) error {
+	dim := s.numParams()
+	rows := len(dataValues) - m
+	if rows <= 0 {
+		return fmt.Errorf("failed to construct phi matrix")
+	}
+	ws.phi = growFloats(ws.phi, rows*dim)
+	fillPhiMatrix(ws.phi, dataValues, timeValues, s, lagStart, m)
+	phi := blas64.General{Rows: rows, Cols: dim, Stride: dim, Data: ws.phi}
+
+	ws.th.Reset()
+	ws.th.ReuseAs(dim, 1)
+	th := ws.th.RawMatrix()
+	ws.normal = growFloats(ws.normal, dim*dim)
+	normal := blas64.Symmetric{N: dim, Stride: dim, Uplo: blas.Upper, Data: ws.normal}
+	blas64.Syrk(blas.Trans, 1, phi, 0, normal)
+	blas64.Gemv(blas.Trans, 1, phi, blas64.Vector{N: rows, Inc: 1, Data: dataValues[m:]}, 0, blas64.Vector{N: dim, Inc: 1, Data: th.Data})
+
+	chol, ok := lapack64.Potrf(normal)
+	if !ok {
+		fallback, err := calculateTheta(mat.NewDense(rows, dim, ws.phi), dataValues)
+		if err != nil {
+			return err
+		}
+		ws.th.Copy(fallback)
+		return nil
+	}
+	lapack64.Potrs(chol, th)
+	return nil
+}
+
+// growFloats returns buf resized to n values, reallocating it only when its capacity is too small.
+// The values are left as they were.
+func growFloats(buf []float64, n int) []float64 {
+	if cap(buf) < n {
+		return make([]float64, n)
+	}
+	return buf[:n]
+}
@@ -0,0 +1,124 @@
+package ar
+
+import (
+	"testing"
+)
+
+// fourierRegressor returns a Fourier regressor of the seasonal periods, failing the test on error.
+func fourierRegressor(t testing.TB, periods ...SeasonalPeriod) *FourierRegressor {
+	t.Helper()
+	r, err := NewFourierRegressor(periods...)
+	if err != nil {
+		t.Fatalf("NewFourierRegressor() error = %v", err)
+	}
+	return r
+}
+
+func TestLSARXPredictWith(t *testing.T) {
+	data := delayedARXData(200)
+	weekly := fourierRegressor(t, SeasonalPeriod{Period: 7, Harmonics: 2})
+	short := fourierRegressor(t, SeasonalPeriod{Period: 3, Harmonics: 1})
+	tests := []struct {
+		name   string
+		params LSARXModelParameters
+	}{
+		{"Recursive", LSARXModelParameters{AutoregressiveLags: 2, ExternalInputLags: 2, StepSize: 1}},
+		{"Delay and intercept", LSARXModelParameters{AutoregressiveLags: 1, ExternalInputLags: 1, InputDelay: 2, Intercept: true, StepSize: 1}},
+		{"Forecast only", LSARXModelParameters{AutoregressiveLags: 2, ExternalInputLags: 2, StepSize: 1, OutputMode: ForecastOnlyOutput}},
+		{"Direct", LSARXModelParameters{AutoregressiveLags: 2, ExternalInputLags: 3, StepSize: 1, Strategy: DirectStrategy}},
+		{"DirRec", LSARXModelParameters{AutoregressiveLags: 1, ExternalInputLags: 3, Intercept: true, StepSize: 1, Strategy: DirRecStrategy}},
+		{"Regressors", LSARXModelParameters{AutoregressiveLags: 2, ExternalInputLags: 2, StepSize: 1, Regressors: []Regressor{weekly}}},
+		{"Other regressors", LSARXModelParameters{AutoregressiveLags: 2, ExternalInputLags: 2, StepSize: 1, Regressors: []Regressor{short, weekly}}},
+		{"Direct regressors", LSARXModelParameters{AutoregressiveLags: 2, ExternalInputLags: 2, StepSize: 1, Strategy: DirectStrategy, Regressors: []Regressor{short}}},
+	}
+	var ws LSARXWorkspace
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The workspace is shared by data of several shapes.
+			for _, n := range []int{200, 60} {
+				predictor, err := NewLSARXPredictor(data[:n], tt.params)
+				if err != nil {
+					t.Fatalf("Failed to create predictor: %v", err)
+				}
+				want, err := predictor.Predict(5)
+				if err != nil {
+					t.Fatalf("Predict() error = %v", err)
+				}
+				got, err := predictor.PredictWith(&ws, 5)
+				if err != nil {
+					t.Fatalf("PredictWith() error = %v", err)
+				}
+				if len(got) != len(want) {
+					t.Fatalf("PredictWith() returned %d rows, want %d", len(got), len(want))
+				}
+				for i := range want {
+					if got[i][0] != want[i][0] || !approxEqual(got[i][1], want[i][1], 1e-9) {
+						t.Errorf("PredictWith() row %d = %v, want %v", i, got[i], want[i])
+						break
+					}
+				}
+			}
+		})
+	}
+
+	predictor, err := NewLSARXPredictor(data[:2], LSARXModelParameters{AutoregressiveLags: 2, ExternalInputLags: 1, StepSize: 1})
+	if err != nil {
+		t.Fatalf("Failed to create predictor: %v", err)
+	}
+	if _, err := predictor.PredictWith(&ws, 5); err == nil {
+		t.Error("PredictWith() on too little data returned no error")
+	}
+}
+
+func TestLSARXPredictWithAllocations(t *testing.T) {
+	data := delayedARXData(200)
+	weekly := fourierRegressor(t, SeasonalPeriod{Period: 7, Harmonics: 2})
+	for _, strategy := range []PredictionStrategy{RecursiveStrategy, DirectStrategy, DirRecStrategy} {
+		for _, regressors := range [][]Regressor{nil, {weekly}} {
+			predictor, err := NewLSARXPredictor(data, LSARXModelParameters{AutoregressiveLags: 2, ExternalInputLags: 2, Intercept: true, StepSize: 1, Strategy: strategy, Regressors: regressors})
+			if err != nil {
+				t.Fatalf("Failed to create predictor: %v", err)
+			}
+			var ws LSARXWorkspace
+			allocs := testing.AllocsPerRun(20, func() {
+				if _, err := predictor.PredictWith(&ws, 10); err != nil {
+					t.Fatalf("PredictWith() error = %v", err)
+				}
+			})
+			if allocs != 0 {
+				t.Errorf("%v PredictWith() with %d regressors allocated %v times per call, want 0", strategy, len(regressors), allocs)
+			}
+		}
+	}
+}
+
+func benchmarkLSARXPredict(b *testing.B, withWorkspace bool) {
+	predictor, err := NewLSARXPredictor(delayedARXData(500), LSARXModelParameters{AutoregressiveLags: 2, ExternalInputLags: 2, Intercept: true, StepSize: 1})
+	if err != nil {
+		b.Fatalf("Failed to create predictor: %v", err)
+	}
+	var ws LSARXWorkspace
+	if _, err := predictor.PredictWith(&ws, 10); err != nil {
+		b.Fatalf("PredictWith() error = %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if withWorkspace {
+			_, err = predictor.PredictWith(&ws, 10)
+		} else {
+			_, err = predictor.Predict(10)
+		}
+		if err != nil {
+			b.Fatalf("prediction error = %v", err)
+		}
+	}
+}
+
+func BenchmarkLSARXPredict(b *testing.B) {
+	benchmarkLSARXPredict(b, false)
+}
+
+func BenchmarkLSARXPredictWith(b *testing.B) {
+	benchmarkLSARXPredict(b, true)
+}